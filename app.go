@@ -2,24 +2,52 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"myitools/device"
 	"myitools/dialog"
+	"myitools/filesystem"
+	"myitools/photos"
+	"myitools/secret"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App 应用结构体
 type App struct {
-	ctx    context.Context
-	dialog *dialog.DialogManager
+	ctx     context.Context
+	dialog  *dialog.DialogManager
+	secrets secret.Store
+
+	samplersMu sync.Mutex
+	samplers   map[string]*device.TelemetrySampler
+
+	syslogsMu sync.Mutex
+	syslogs   map[string]*device.SyslogStream
+
+	screenshotMu  sync.Mutex
+	screenshotDir string
+	burstsMu      sync.Mutex
+	bursts        map[string]context.CancelFunc
+
+	transfersMu sync.Mutex
+	transfers   map[string]context.CancelFunc
 }
 
 // NewApp 创建一个新的App应用实例
 func NewApp() *App {
 	return &App{
-		dialog: dialog.NewDialogManager(),
+		dialog:    dialog.NewDialogManager(),
+		samplers:  make(map[string]*device.TelemetrySampler),
+		syslogs:   make(map[string]*device.SyslogStream),
+		bursts:    make(map[string]context.CancelFunc),
+		transfers: make(map[string]context.CancelFunc),
 	}
 }
 
@@ -32,6 +60,13 @@ func (a *App) startup(ctx context.Context) {
 
 	// 确保备份目录存在
 	a.ensureBackupDirExists()
+
+	// 系统密钥库可用时直接使用，否则等待用户输入主密码解锁文件存储
+	if store, err := secret.NewSystemStore(); err == nil {
+		a.secrets = store
+	} else {
+		fmt.Printf("系统密钥库不可用: %v\n", err)
+	}
 }
 
 // ensureBackupDirExists 确保备份目录存在
@@ -55,28 +90,878 @@ func (a *App) ensureBackupDirExists() {
 // shutdown 在应用关闭时调用
 func (a *App) shutdown(ctx context.Context) {
 	// 执行清理操作
+	a.samplersMu.Lock()
+	for udid, sampler := range a.samplers {
+		sampler.Stop()
+		delete(a.samplers, udid)
+	}
+	a.samplersMu.Unlock()
+
+	a.syslogsMu.Lock()
+	for udid, stream := range a.syslogs {
+		stream.Stop()
+		delete(a.syslogs, udid)
+	}
+	a.syslogsMu.Unlock()
+
+	a.burstsMu.Lock()
+	for _, cancel := range a.bursts {
+		cancel()
+	}
+	a.burstsMu.Unlock()
+
+	a.transfersMu.Lock()
+	for _, cancel := range a.transfers {
+		cancel()
+	}
+	a.transfersMu.Unlock()
 }
 
 // GetDevices 获取已连接的iOS设备列表
-func (a *App) GetDevices() []device.Device {
+func (a *App) GetDevices() ([]device.Device, error) {
 	return device.ListDevicesWithMock()
 }
 
+// RunDiagnostics 检查外部工具和 usbmuxd 服务，用于启动时的健康检查页面
+func (a *App) RunDiagnostics() device.DoctorReport {
+	return device.RunDiagnostics()
+}
+
 // GetDeviceInfo 获取设备详细信息
 func (a *App) GetDeviceInfo(udid string) (map[string]string, error) {
 	return device.GetDeviceInfoWithMock(udid)
 }
 
+// LookupDeviceModel 根据 ProductType 查询机型信息
+func (a *App) LookupDeviceModel(productType string) (device.ModelInfo, error) {
+	model, ok := device.LookupModel(productType)
+	if !ok {
+		return model, fmt.Errorf("未知机型: %s", productType)
+	}
+	return model, nil
+}
+
+// DecodeSerial 解码序列号中的工厂和生产日期信息
+func (a *App) DecodeSerial(serial string) device.SerialInfo {
+	return device.DecodeSerial(serial)
+}
+
+// VerifyDevice 生成验机报告，校验序列号、IMEI、ECID 等标识是否一致
+func (a *App) VerifyDevice(udid string) (device.VerificationReport, error) {
+	return device.VerifyDeviceWithMock(udid)
+}
+
+// ExportVerificationReport 生成验机报告并导出为 json 或 html 文件，返回保存路径
+func (a *App) ExportVerificationReport(udid string, format string) (string, error) {
+	var filter wailsruntime.FileFilter
+	switch format {
+	case "json":
+		filter = wailsruntime.FileFilter{DisplayName: "JSON 文件 (*.json)", Pattern: "*.json"}
+	case "html":
+		filter = wailsruntime.FileFilter{DisplayName: "HTML 文件 (*.html)", Pattern: "*.html"}
+	default:
+		return "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	report, err := device.VerifyDeviceWithMock(udid)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := a.dialog.SaveFileDialog("导出验机报告", "", []wailsruntime.FileFilter{filter})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if format == "json" {
+		err = report.WriteJSON(f)
+	} else {
+		err = report.WriteHTML(f)
+	}
+	if err != nil {
+		return "", fmt.Errorf("写入验机报告失败: %v", err)
+	}
+	return path, nil
+}
+
+// exportFilters 各导出格式对应的文件过滤器
+var exportFilters = map[string]wailsruntime.FileFilter{
+	device.ExportFormatJSON:     {DisplayName: "JSON 文件 (*.json)", Pattern: "*.json"},
+	device.ExportFormatCSV:      {DisplayName: "CSV 文件 (*.csv)", Pattern: "*.csv"},
+	device.ExportFormatHTML:     {DisplayName: "HTML 文件 (*.html)", Pattern: "*.html"},
+	device.ExportFormatMarkdown: {DisplayName: "Markdown 文件 (*.md)", Pattern: "*.md"},
+}
+
+// ExportDeviceInfo 导出设备信息，format 为 json、csv、html 或 markdown，返回保存路径
+func (a *App) ExportDeviceInfo(udid string, format string, options device.ExportOptions) (string, error) {
+	filter, ok := exportFilters[format]
+	if !ok {
+		return "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	report, err := device.BuildDeviceReport(udid, options)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := a.dialog.SaveFileDialog("导出设备信息", "", []wailsruntime.FileFilter{filter})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if err := device.WriteDeviceReport(f, report, format); err != nil {
+		return "", fmt.Errorf("写入设备信息失败: %v", err)
+	}
+	return path, nil
+}
+
+// ExportAllDevicesCSV 将所有已连接设备的信息导出到一个CSV文件，每台设备一行
+func (a *App) ExportAllDevicesCSV(options device.ExportOptions) (string, error) {
+	devices, err := device.ListDevicesWithMock()
+	if err != nil {
+		return "", err
+	}
+
+	var reports []device.DeviceReport
+	for _, d := range devices {
+		if d.Status != device.DeviceStatusConnected {
+			continue
+		}
+		report, err := device.BuildDeviceReport(d.UDID, options)
+		if err != nil {
+			fmt.Printf("读取设备 %s 信息失败: %v\n", d.UDID, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) == 0 {
+		return "", fmt.Errorf("没有可导出的设备")
+	}
+
+	path, err := a.dialog.SaveFileDialog("导出全部设备信息", "", []wailsruntime.FileFilter{exportFilters[device.ExportFormatCSV]})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if err := device.WriteDevicesCSV(f, reports); err != nil {
+		return "", fmt.Errorf("写入设备信息失败: %v", err)
+	}
+	return path, nil
+}
+
+// DetectJailbreak 检测设备是否越狱，耗时较长，需单独调用
+func (a *App) DetectJailbreak(udid string) (device.JailbreakReport, error) {
+	return device.DetectJailbreak(udid)
+}
+
+// GetStorageInfo 获取设备存储空间使用情况
+func (a *App) GetStorageInfo(udid string) (device.StorageInfo, error) {
+	return device.GetStorageInfoWithMock(udid)
+}
+
+// GetBatteryInfo 获取设备电池信息
+func (a *App) GetBatteryInfo(udid string) (device.BatteryInfo, error) {
+	return device.GetBatteryInfoWithMock(udid)
+}
+
+// StartTelemetry 开始采集设备电池遥测数据，每次采样通过 telemetry:sample 事件推送
+func (a *App) StartTelemetry(udid string, intervalSeconds int, persist bool) error {
+	a.samplersMu.Lock()
+	defer a.samplersMu.Unlock()
+
+	if sampler, exists := a.samplers[udid]; exists && sampler.Running() {
+		return fmt.Errorf("设备 %s 的遥测采集已在运行", udid)
+	}
+
+	interval := device.DefaultTelemetryInterval
+	if intervalSeconds > 0 {
+		interval = time.Duration(intervalSeconds) * time.Second
+	}
+
+	logPath := ""
+	if persist {
+		telemetryDir := filepath.Join(filepath.Dir(a.GetDefaultBackupDir()), "telemetry")
+		logPath = filepath.Join(telemetryDir, fmt.Sprintf("%s_%s.jsonl", udid, time.Now().Format("20060102_150405")))
+	}
+
+	sampler := device.NewTelemetrySampler(udid, interval, device.DefaultTelemetryCapacity, logPath)
+	sampler.OnSample = func(sample device.TelemetrySample) {
+		if a.ctx != nil {
+			wailsruntime.EventsEmit(a.ctx, "telemetry:sample", sample)
+		}
+	}
+	if err := sampler.Start(); err != nil {
+		return err
+	}
+	a.samplers[udid] = sampler
+	return nil
+}
+
+// StopTelemetry 停止采集设备遥测数据，已采集的数据保留到下次开始采集
+func (a *App) StopTelemetry(udid string) {
+	a.samplersMu.Lock()
+	sampler := a.samplers[udid]
+	a.samplersMu.Unlock()
+
+	if sampler != nil {
+		sampler.Stop()
+	}
+}
+
+// GetTelemetrySamples 获取设备最近的遥测采样
+func (a *App) GetTelemetrySamples(udid string) []device.TelemetrySample {
+	a.samplersMu.Lock()
+	sampler := a.samplers[udid]
+	a.samplersMu.Unlock()
+
+	if sampler == nil {
+		return []device.TelemetrySample{}
+	}
+	return sampler.Samples()
+}
+
+// ExportTelemetryCSV 将设备的遥测采样导出为CSV文件，返回保存路径
+func (a *App) ExportTelemetryCSV(udid string) (string, error) {
+	samples := a.GetTelemetrySamples(udid)
+	if len(samples) == 0 {
+		return "", fmt.Errorf("没有可导出的遥测数据")
+	}
+
+	path, err := a.dialog.SaveFileDialog("导出遥测数据", "", []wailsruntime.FileFilter{
+		{DisplayName: "CSV 文件 (*.csv)", Pattern: "*.csv"},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := device.WriteTelemetryCSV(path, samples); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SyslogBatch 推送给前端的一批日志
+type SyslogBatch struct {
+	UDID    string               `json:"udid"`
+	Entries []device.SyslogEntry `json:"entries"`
+}
+
+// StartSyslog 开始读取设备日志，新日志通过 syslog:lines 事件分批推送，读取结束时发送 syslog:stopped 事件
+func (a *App) StartSyslog(udid string, filter device.SyslogFilter) error {
+	a.syslogsMu.Lock()
+	defer a.syslogsMu.Unlock()
+
+	stream, exists := a.syslogs[udid]
+	if exists && stream.Running() {
+		return fmt.Errorf("设备 %s 的日志已在读取", udid)
+	}
+	if !exists {
+		stream = device.NewSyslogStream(udid, device.DefaultSyslogCapacity)
+		stream.OnBatch = func(entries []device.SyslogEntry) {
+			if a.ctx != nil {
+				wailsruntime.EventsEmit(a.ctx, "syslog:lines", SyslogBatch{UDID: udid, Entries: entries})
+			}
+		}
+		stream.OnStop = func(err error) {
+			if a.ctx == nil {
+				return
+			}
+			payload := map[string]any{"udid": udid}
+			if err != nil {
+				payload["error"] = device.FormatError(err)
+			}
+			wailsruntime.EventsEmit(a.ctx, "syslog:stopped", payload)
+		}
+	}
+	if err := stream.SetFilter(filter); err != nil {
+		return err
+	}
+	stream.Resume()
+	if err := stream.Start(); err != nil {
+		return err
+	}
+	a.syslogs[udid] = stream
+	return nil
+}
+
+// StopSyslog 停止读取设备日志，已读取的日志保留到下次开始读取
+func (a *App) StopSyslog(udid string) {
+	if stream := a.syslogStream(udid); stream != nil {
+		stream.Stop()
+	}
+}
+
+// PauseSyslog 暂停推送日志，暂停期间的日志仍会保存在缓冲区中
+func (a *App) PauseSyslog(udid string) {
+	if stream := a.syslogStream(udid); stream != nil {
+		stream.Pause()
+	}
+}
+
+// ResumeSyslog 恢复推送日志
+func (a *App) ResumeSyslog(udid string) {
+	if stream := a.syslogStream(udid); stream != nil {
+		stream.Resume()
+	}
+}
+
+// SetSyslogFilter 修改日志过滤条件
+func (a *App) SetSyslogFilter(udid string, filter device.SyslogFilter) error {
+	stream := a.syslogStream(udid)
+	if stream == nil {
+		return fmt.Errorf("设备 %s 没有读取日志", udid)
+	}
+	return stream.SetFilter(filter)
+}
+
+// GetSyslogEntries 获取缓冲区中满足过滤条件的日志
+func (a *App) GetSyslogEntries(udid string) []device.SyslogEntry {
+	stream := a.syslogStream(udid)
+	if stream == nil {
+		return []device.SyslogEntry{}
+	}
+	return stream.Entries()
+}
+
+// ClearSyslog 清空日志缓冲区
+func (a *App) ClearSyslog(udid string) {
+	if stream := a.syslogStream(udid); stream != nil {
+		stream.Clear()
+	}
+}
+
+// SaveSyslog 将满足过滤条件的日志保存到文件，返回保存路径
+func (a *App) SaveSyslog(udid string) (string, error) {
+	stream := a.syslogStream(udid)
+	if stream == nil {
+		return "", fmt.Errorf("没有可保存的日志")
+	}
+
+	path, err := a.dialog.SaveFileDialog("保存设备日志", "", []wailsruntime.FileFilter{
+		{DisplayName: "日志文件 (*.log)", Pattern: "*.log"},
+		{DisplayName: "文本文件 (*.txt)", Pattern: "*.txt"},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := stream.Save(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// syslogStream 获取设备的日志流
+func (a *App) syslogStream(udid string) *device.SyslogStream {
+	a.syslogsMu.Lock()
+	defer a.syslogsMu.Unlock()
+	return a.syslogs[udid]
+}
+
+// ListDeviceCrashReports 列出设备上的崩溃报告，报告同时保存到本地，可直接用 GetCrashReport 打开
+func (a *App) ListDeviceCrashReports(udid string) ([]device.CrashReport, error) {
+	return device.ListDeviceCrashReports(udid, a.crashReportDir(udid))
+}
+
+// PullCrashReports 将设备上的崩溃报告复制到本地，removeFromDevice 为 true 时同时从设备上删除
+func (a *App) PullCrashReports(udid string, removeFromDevice bool) ([]device.CrashReport, error) {
+	return device.PullCrashReports(udid, a.crashReportDir(udid), removeFromDevice)
+}
+
+// ListCrashReports 列出本地已保存的设备崩溃报告
+func (a *App) ListCrashReports(udid string) ([]device.CrashReport, error) {
+	return device.ListCrashReports(a.crashReportDir(udid))
+}
+
+// GetCrashReport 解析本地崩溃报告
+func (a *App) GetCrashReport(udid string, relativePath string) (device.IPSReport, error) {
+	dir := a.crashReportDir(udid)
+	path := filepath.Join(dir, relativePath)
+	// 只允许读取该设备报告目录中的文件
+	if rel, err := filepath.Rel(dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return device.IPSReport{}, fmt.Errorf("无效的报告路径: %s", relativePath)
+	}
+	report, err := device.ParseIPSReport(path)
+	report.RelativePath = relativePath
+	return report, err
+}
+
+// crashReportDir 设备崩溃报告的本地保存目录
+func (a *App) crashReportDir(udid string) string {
+	return filepath.Join(filepath.Dir(a.GetDefaultBackupDir()), "crashreports", udid)
+}
+
+// GetScreenshotDir 获取截图保存目录
+func (a *App) GetScreenshotDir() string {
+	a.screenshotMu.Lock()
+	dir := a.screenshotDir
+	a.screenshotMu.Unlock()
+	if dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(a.GetDefaultBackupDir()), "screenshots")
+}
+
+// SetScreenshotDir 设置截图保存目录
+func (a *App) SetScreenshotDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建截图目录失败: %v", err)
+	}
+	a.screenshotMu.Lock()
+	a.screenshotDir = dir
+	a.screenshotMu.Unlock()
+	return nil
+}
+
+// TakeScreenshot 截取设备屏幕，save 为 true 时同时保存到截图目录
+func (a *App) TakeScreenshot(udid string, save bool) (device.Screenshot, error) {
+	shot, err := device.TakeScreenshot(udid)
+	if err != nil {
+		return shot, err
+	}
+	if save {
+		if _, err := device.SaveScreenshot(&shot, a.GetScreenshotDir()); err != nil {
+			return shot, err
+		}
+	}
+	return shot, nil
+}
+
+// StartBurstCapture 按间隔连续截图并保存到截图目录
+// 每张截图通过 screenshot:captured 事件推送，结束时发送 screenshot:burst_done 事件。
+func (a *App) StartBurstCapture(udid string, count int, intervalMs int) error {
+	a.burstsMu.Lock()
+	defer a.burstsMu.Unlock()
+
+	if _, exists := a.bursts[udid]; exists {
+		return fmt.Errorf("设备 %s 正在连拍", udid)
+	}
+	if count <= 0 || count > device.MaxBurstCount {
+		return fmt.Errorf("连拍张数必须在 1 到 %d 之间", device.MaxBurstCount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.bursts[udid] = cancel
+	dir := a.GetScreenshotDir()
+
+	go func() {
+		shots, err := device.CaptureBurst(ctx, udid, count, time.Duration(intervalMs)*time.Millisecond, dir, func(shot device.Screenshot) {
+			if a.ctx != nil {
+				wailsruntime.EventsEmit(a.ctx, "screenshot:captured", shot)
+			}
+		})
+
+		a.burstsMu.Lock()
+		delete(a.bursts, udid)
+		a.burstsMu.Unlock()
+		cancel()
+
+		if a.ctx == nil {
+			return
+		}
+		payload := map[string]any{"udid": udid, "count": len(shots)}
+		if err != nil {
+			payload["error"] = device.FormatError(err)
+		}
+		wailsruntime.EventsEmit(a.ctx, "screenshot:burst_done", payload)
+	}()
+	return nil
+}
+
+// StopBurstCapture 停止连拍
+func (a *App) StopBurstCapture(udid string) {
+	a.burstsMu.Lock()
+	cancel := a.bursts[udid]
+	a.burstsMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// GetImageMountStatus 查询开发者镜像挂载状态和开发者模式
+func (a *App) GetImageMountStatus(udid string) (device.ImageMountStatus, error) {
+	return device.GetImageMountStatus(udid)
+}
+
+// MountDeveloperImage 挂载开发者镜像，dir 为镜像所在目录
+// iOS 17 起使用个性化镜像，需要联网签名。
+func (a *App) MountDeveloperImage(udid string, dir string) error {
+	return device.MountImage(context.Background(), udid, dir, nil, nil)
+}
+
+// UnmountDeveloperImage 卸载开发者镜像
+func (a *App) UnmountDeveloperImage(udid string) error {
+	return device.UnmountDeveloperImage(udid)
+}
+
+// ListApps 获取设备上已安装的应用
+func (a *App) ListApps(udid string, filter device.AppFilter) ([]device.AppInfo, error) {
+	return device.ListAppsWithMock(udid, filter)
+}
+
+// RefreshApps 重新读取设备上已安装的应用
+func (a *App) RefreshApps(udid string, filter device.AppFilter) ([]device.AppInfo, error) {
+	device.InvalidateAppCache(udid)
+	return device.ListAppsWithMock(udid, filter)
+}
+
+// ipaFilter IPA 文件选择过滤器
+var ipaFilter = wailsruntime.FileFilter{DisplayName: "iOS 应用 (*.ipa)", Pattern: "*.ipa"}
+
+// SelectIPAFiles 选择要安装的 IPA 文件
+func (a *App) SelectIPAFiles() ([]string, error) {
+	return a.dialog.OpenMultipleFilesDialog("选择 IPA 文件", "", []wailsruntime.FileFilter{ipaFilter})
+}
+
+// SelectIPAFile 选择一个 IPA 文件
+func (a *App) SelectIPAFile() (string, error) {
+	return a.dialog.OpenFileDialog("选择 IPA 文件", "", []wailsruntime.FileFilter{ipaFilter})
+}
+
+// InspectIPA 离线查看 IPA 的信息、图标、描述文件、签名和架构
+func (a *App) InspectIPA(path string) (device.IPAInspection, error) {
+	return device.InspectIPA(path)
+}
+
+// GetIPAInfo 读取 IPA 的 Bundle ID、版本、最低系统版本等信息
+func (a *App) GetIPAInfo(path string) (device.IPAInfo, error) {
+	return device.ReadIPAInfo(path)
+}
+
+// InstallIPA 安装 IPA，通过 install:progress 事件报告进度
+func (a *App) InstallIPA(udid string, path string) (device.IPAInfo, error) {
+	return device.InstallIPA(context.Background(), udid, path, a.emitInstallProgress)
+}
+
+// InstallIPAs 将多个 IPA 安装到多台设备，concurrency 为同时进行的任务数
+func (a *App) InstallIPAs(udids []string, paths []string, concurrency int) []device.InstallResult {
+	return device.BatchInstall(context.Background(), udids, paths, concurrency, a.emitInstallProgress)
+}
+
+// emitInstallProgress 向前端发送安装进度
+func (a *App) emitInstallProgress(progress device.InstallProgress) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, "install:progress", progress)
+	}
+}
+
+// UninstallApp 卸载应用，通过 apps:progress 事件报告进度
+func (a *App) UninstallApp(udid string, bundleID string) error {
+	return device.UninstallApp(context.Background(), udid, bundleID, a.emitAppProgress)
+}
+
+// UninstallApps 批量卸载应用
+func (a *App) UninstallApps(udid string, bundleIDs []string) []device.InstallResult {
+	return device.UninstallApps(context.Background(), udid, bundleIDs, a.emitAppProgress)
+}
+
+// ArchiveApp 归档应用
+func (a *App) ArchiveApp(udid string, bundleID string, options device.ArchiveOptions) error {
+	return device.ArchiveApp(context.Background(), udid, bundleID, options, a.emitAppProgress)
+}
+
+// RestoreApp 从归档恢复应用
+func (a *App) RestoreApp(udid string, bundleID string) error {
+	return device.RestoreApp(context.Background(), udid, bundleID, a.emitAppProgress)
+}
+
+// RemoveAppArchive 删除应用归档
+func (a *App) RemoveAppArchive(udid string, bundleID string) error {
+	return device.RemoveAppArchive(context.Background(), udid, bundleID, a.emitAppProgress)
+}
+
+// ListAppArchives 列出设备上的应用归档
+func (a *App) ListAppArchives(udid string) ([]device.AppArchive, error) {
+	return device.ListAppArchives(udid)
+}
+
+// emitAppProgress 向前端发送卸载、归档等操作的进度
+func (a *App) emitAppProgress(progress device.InstallProgress) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, "apps:progress", progress)
+	}
+}
+
+// appBackupDir 应用数据备份目录
+func (a *App) appBackupDir() string {
+	return filepath.Join(filepath.Dir(a.GetDefaultBackupDir()), "appbackups")
+}
+
+// BackupAppData 备份应用的 Documents 或数据容器，通过 appdata:progress 事件报告进度
+func (a *App) BackupAppData(udid string, bundleID string, options device.AppBackupOptions) (device.AppBackupInfo, error) {
+	return device.BackupAppData(context.Background(), udid, bundleID, a.appBackupDir(), options, filesystem.OpenAppData, a.emitAppDataProgress)
+}
+
+// RestoreAppData 将应用数据备份恢复到设备
+func (a *App) RestoreAppData(udid string, backupID string) error {
+	return device.RestoreAppData(context.Background(), udid, a.appBackupDir(), backupID, filesystem.OpenAppData, a.emitAppDataProgress)
+}
+
+// ListAppBackups 列出应用数据备份
+func (a *App) ListAppBackups() ([]device.AppBackupInfo, error) {
+	return device.ListAppBackups(a.appBackupDir())
+}
+
+// DeleteAppBackup 删除应用数据备份
+func (a *App) DeleteAppBackup(backupID string) error {
+	return device.DeleteAppBackup(a.appBackupDir(), backupID)
+}
+
+// GetBackupCatalog 获取整机备份和应用数据备份列表
+func (a *App) GetBackupCatalog() (device.BackupCatalog, error) {
+	return device.GetBackupCatalog(a.GetDefaultBackupDir(), a.appBackupDir())
+}
+
+// emitAppDataProgress 向前端发送应用数据备份或恢复的进度
+func (a *App) emitAppDataProgress(progress device.AppDataProgress) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, "appdata:progress", progress)
+	}
+}
+
+// ListFiles 列出设备媒体分区中的目录内容
+func (a *App) ListFiles(udid string, dir string, showHidden bool) ([]filesystem.FileInfo, error) {
+	return filesystem.ListFiles(context.Background(), filesystem.NewClient(udid), dir, showHidden)
+}
+
+// GetFileInfo 获取设备上文件的属性
+func (a *App) GetFileInfo(udid string, path string) (filesystem.FileInfo, error) {
+	return filesystem.NewClient(udid).Stat(context.Background(), path)
+}
+
+// GetFilesystemInfo 获取设备文件系统容量
+func (a *App) GetFilesystemInfo(udid string) (filesystem.FSInfo, error) {
+	return filesystem.NewClient(udid).DeviceInfo(context.Background())
+}
+
+// CreateFolder 在设备上创建目录
+func (a *App) CreateFolder(udid string, path string) error {
+	return filesystem.NewClient(udid).Mkdir(context.Background(), path)
+}
+
+// DeleteFiles 删除设备上的文件或目录，目录会连同内容一起删除
+func (a *App) DeleteFiles(udid string, paths []string) error {
+	client := filesystem.NewClient(udid)
+	for _, path := range paths {
+		if err := client.Remove(context.Background(), path, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameFile 重命名或移动设备上的文件
+func (a *App) RenameFile(udid string, from string, to string) error {
+	return filesystem.NewClient(udid).Rename(context.Background(), from, to)
+}
+
+// TruncateFile 截断设备上的文件
+func (a *App) TruncateFile(udid string, path string, size int64) error {
+	return filesystem.NewClient(udid).Truncate(context.Background(), path, size)
+}
+
+// CreateSymlink 在设备上创建符号链接
+func (a *App) CreateSymlink(udid string, target string, link string) error {
+	return filesystem.NewClient(udid).Symlink(context.Background(), target, link)
+}
+
+// StartDownload 下载设备上的文件到本地目录，localDir 为空时弹出目录选择框
+// 返回传输 ID，进度通过 filesystem:progress 事件推送，结束时发送 filesystem:done 事件。
+func (a *App) StartDownload(udid string, remotePaths []string, localDir string) (string, error) {
+	if localDir == "" {
+		dir, err := a.dialog.OpenDirectoryDialog("选择保存位置", "")
+		if err != nil || dir == "" {
+			return "", err
+		}
+		localDir = dir
+	}
+	client := filesystem.NewClient(udid)
+	return a.startTransfer(udid, filesystem.DirectionDownload, func(ctx context.Context, onProgress func(filesystem.TransferProgress)) error {
+		return client.Download(ctx, remotePaths, localDir, onProgress)
+	}), nil
+}
+
+// StartUpload 上传本地文件到设备上的目录，localPaths 为空时弹出文件选择框
+func (a *App) StartUpload(udid string, localPaths []string, remoteDir string) (string, error) {
+	if len(localPaths) == 0 {
+		paths, err := a.dialog.OpenMultipleFilesDialog("选择要上传的文件", "", nil)
+		if err != nil || len(paths) == 0 {
+			return "", err
+		}
+		localPaths = paths
+	}
+	client := filesystem.NewClient(udid)
+	return a.startTransfer(udid, filesystem.DirectionUpload, func(ctx context.Context, onProgress func(filesystem.TransferProgress)) error {
+		return client.Upload(ctx, localPaths, remoteDir, onProgress)
+	}), nil
+}
+
+// CancelTransfer 取消上传、下载或照片导出
+func (a *App) CancelTransfer(id string) {
+	a.transfersMu.Lock()
+	cancel := a.transfers[id]
+	a.transfersMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// startTransfer 在后台运行传输任务并推送进度
+func (a *App) startTransfer(udid string, direction string, run func(context.Context, func(filesystem.TransferProgress)) error) string {
+	id := fmt.Sprintf("%s_%d", direction, time.Now().UnixNano())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a.transfersMu.Lock()
+	a.transfers[id] = cancel
+	a.transfersMu.Unlock()
+
+	go func() {
+		err := run(ctx, func(progress filesystem.TransferProgress) {
+			progress.ID = id
+			if a.ctx != nil {
+				wailsruntime.EventsEmit(a.ctx, "filesystem:progress", progress)
+			}
+		})
+
+		a.transfersMu.Lock()
+		delete(a.transfers, id)
+		a.transfersMu.Unlock()
+		cancel()
+
+		if a.ctx == nil {
+			return
+		}
+		payload := map[string]any{"id": id, "udid": udid, "direction": direction}
+		if err != nil {
+			payload["error"] = device.FormatError(err)
+		}
+		wailsruntime.EventsEmit(a.ctx, "filesystem:done", payload)
+	}()
+	return id
+}
+
+// ScanPhotos 扫描设备相机胶卷中的照片和视频
+func (a *App) ScanPhotos(udid string) ([]photos.Asset, error) {
+	return photos.Scan(context.Background(), filesystem.NewClient(udid))
+}
+
+// GetPhotoThumbnail 获取照片或视频的 JPEG 缩略图，path 为设备上的文件路径
+func (a *App) GetPhotoThumbnail(udid string, path string, size int) ([]byte, error) {
+	return photos.Thumbnail(context.Background(), filesystem.NewClient(udid), path, size)
+}
+
+// GetPhotoExportDir 获取照片默认导出目录
+func (a *App) GetPhotoExportDir() string {
+	return filepath.Join(filepath.Dir(a.GetDefaultBackupDir()), "photos")
+}
+
+// StartPhotoExport 导出照片，assetIDs 为空时导出全部，dest 为空时导出到默认目录
+// 返回任务 ID，可通过 CancelTransfer 取消；进度通过 photos:progress 事件推送，结束时发送 photos:done 事件。
+func (a *App) StartPhotoExport(udid string, assetIDs []string, dest string, options photos.ExportOptions) (string, error) {
+	if dest == "" {
+		dest = a.GetPhotoExportDir()
+	}
+	id := fmt.Sprintf("photos_%d", time.Now().UnixNano())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a.transfersMu.Lock()
+	a.transfers[id] = cancel
+	a.transfersMu.Unlock()
+
+	go func() {
+		client := filesystem.NewClient(udid)
+		var result photos.ExportResult
+		assets, err := photos.Scan(ctx, client)
+		if err == nil {
+			assets = selectAssets(assets, assetIDs)
+			result, err = photos.Export(ctx, client, assets, dest, options, func(progress photos.ExportProgress) {
+				progress.ID = id
+				if a.ctx != nil {
+					wailsruntime.EventsEmit(a.ctx, "photos:progress", progress)
+				}
+			})
+		}
+
+		a.transfersMu.Lock()
+		delete(a.transfers, id)
+		a.transfersMu.Unlock()
+		cancel()
+
+		if a.ctx == nil {
+			return
+		}
+		payload := map[string]any{"id": id, "udid": udid, "result": result}
+		if err != nil {
+			payload["error"] = device.FormatError(err)
+		}
+		wailsruntime.EventsEmit(a.ctx, "photos:done", payload)
+	}()
+	return id, nil
+}
+
+// selectAssets 按 ID 筛选资源，ids 为空时返回全部
+func selectAssets(assets []photos.Asset, ids []string) []photos.Asset {
+	if len(ids) == 0 {
+		return assets
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	selected := []photos.Asset{}
+	for _, asset := range assets {
+		if wanted[asset.ID] {
+			selected = append(selected, asset)
+		}
+	}
+	return selected
+}
+
 // BackupDevice 备份设备数据
 func (a *App) BackupDevice(udid string, backupDir string, encrypt bool, password string) (string, error) {
+	if encrypt && password == "" {
+		password = a.savedBackupPassword(udid)
+	}
 	return device.CreateBackup(udid, backupDir, encrypt, password)
 }
 
 // RestoreDevice 恢复设备数据
+// 未提供密码时使用为备份来源设备保存的密码，迁移到其他设备时来源与目标不同。
 func (a *App) RestoreDevice(udid string, backupDir string, password string) error {
+	if password == "" {
+		sourceUDID, err := device.BackupSourceUDID(backupDir)
+		if err != nil {
+			fmt.Printf("读取备份来源设备失败，使用目标设备保存的密码: %v\n", err)
+			sourceUDID = udid
+		}
+		password = a.savedBackupPassword(sourceUDID)
+	}
 	return device.RestoreBackup(udid, backupDir, password)
 }
 
+// CheckRestoreCompatibility 检查备份能否恢复到目标设备
+func (a *App) CheckRestoreCompatibility(udid string, backupDir string) (device.RestoreCompatibility, error) {
+	return device.CheckRestoreCompatibility(udid, backupDir)
+}
+
 // GetBackupProgress 获取备份进度
 func (a *App) GetBackupProgress(backupID string) *device.BackupProgress {
 	return device.GetBackupProgress(backupID)
@@ -93,6 +978,60 @@ func (a *App) DeleteBackup(backupPath string) error {
 	return nil
 }
 
+// GetPasswordStoreKind 获取当前密码存储类型，未解锁时返回空字符串
+func (a *App) GetPasswordStoreKind() string {
+	if a.secrets == nil {
+		return ""
+	}
+	return a.secrets.Kind()
+}
+
+// UnlockPasswordStore 使用主密码解锁密码存储（系统密钥库不可用时使用）
+func (a *App) UnlockPasswordStore(masterPassword string) error {
+	store, err := secret.Open(masterPassword)
+	if err != nil {
+		return err
+	}
+	a.secrets = store
+	return nil
+}
+
+// SaveBackupPassword 保存设备的备份密码
+func (a *App) SaveBackupPassword(udid string, password string) error {
+	if a.secrets == nil {
+		return fmt.Errorf("密码存储未解锁")
+	}
+	return a.secrets.Set(secret.BackupAccount(udid), password)
+}
+
+// DeleteBackupPassword 删除保存的设备备份密码
+func (a *App) DeleteBackupPassword(udid string) error {
+	if a.secrets == nil {
+		return fmt.Errorf("密码存储未解锁")
+	}
+	return a.secrets.Delete(secret.BackupAccount(udid))
+}
+
+// HasSavedBackupPassword 检查是否保存了设备的备份密码
+func (a *App) HasSavedBackupPassword(udid string) bool {
+	return a.savedBackupPassword(udid) != ""
+}
+
+// savedBackupPassword 读取保存的备份密码，没有时返回空字符串
+func (a *App) savedBackupPassword(udid string) string {
+	if a.secrets == nil {
+		return ""
+	}
+	password, err := a.secrets.Get(secret.BackupAccount(udid))
+	if err != nil {
+		if !errors.Is(err, secret.ErrNotFound) {
+			fmt.Printf("读取保存的备份密码失败: %v\n", err)
+		}
+		return ""
+	}
+	return password
+}
+
 // OpenDirectoryDialog 打开目录选择对话框
 func (a *App) OpenDirectoryDialog(title string, defaultPath string) (string, error) {
 	return a.dialog.OpenDirectoryDialog(title, defaultPath)
@@ -113,6 +1052,21 @@ func (a *App) SetBackupEncryption(udid string, enable bool, password string) err
 	return device.SetBackupEncryption(udid, enable, password)
 }
 
+// ChangeBackupPassword 修改备份加密密码
+func (a *App) ChangeBackupPassword(udid string, oldPassword string, newPassword string) error {
+	if err := device.ChangeBackupPassword(udid, oldPassword, newPassword); err != nil {
+		return err
+	}
+
+	// 已保存旧密码时同步更新
+	if a.savedBackupPassword(udid) != "" {
+		if err := a.secrets.Set(secret.BackupAccount(udid), newPassword); err != nil {
+			fmt.Printf("更新保存的备份密码失败: %v\n", err)
+		}
+	}
+	return nil
+}
+
 // OpenFileDialog 打开文件选择对话框
 func (a *App) OpenFileDialog(title string, defaultPath string) (string, error) {
 	return a.dialog.OpenFileDialog(title, defaultPath, nil)
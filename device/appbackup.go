@@ -0,0 +1,472 @@
+package device
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 应用数据范围
+const (
+	AppDataDocuments = "documents" // VendDocuments，仅 Documents 目录，需要应用开启文件共享
+	AppDataContainer = "container" // VendContainer，整个数据容器，仅开发签名的应用可用
+)
+
+// 应用数据备份的文件名
+const (
+	appBackupCatalogFile = "catalog.json"
+	appBackupInfoFile    = "MyiToolsAppBackup.json"
+	appBackupDataDir     = "data"
+	appBackupArchive     = "data.zip"
+)
+
+// AppBackupOptions 应用数据备份选项
+type AppBackupOptions struct {
+	Scope   string `json:"scope"`   // documents 或 container，为空时为 documents
+	Archive bool   `json:"archive"` // 打包为 zip，否则保存为目录
+}
+
+// AppBackupInfo 一份应用数据备份
+type AppBackupInfo struct {
+	ID         string    `json:"id"`
+	DeviceUDID string    `json:"device_udid"`
+	DeviceName string    `json:"device_name"`
+	BundleID   string    `json:"bundle_id"`
+	AppName    string    `json:"app_name"`
+	AppVersion string    `json:"app_version"`
+	Scope      string    `json:"scope"`
+	Path       string    `json:"path"`     // 备份目录
+	Archived   bool      `json:"archived"` // 数据是否打包为 data.zip
+	CreatedAt  time.Time `json:"created_at"`
+	Size       int64     `json:"size"`
+	FileCount  int       `json:"file_count"`
+}
+
+// AppDataProgress 应用数据备份或恢复的进度
+type AppDataProgress struct {
+	UDID        string `json:"udid"`
+	BundleID    string `json:"bundle_id"`
+	Operation   string `json:"operation"` // backup 或 restore
+	CurrentFile string `json:"current_file"`
+	Done        int    `json:"done"`
+	Total       int    `json:"total"`
+	Percent     int    `json:"percent"`
+}
+
+// BackupCatalog 整机备份和应用数据备份的汇总
+type BackupCatalog struct {
+	Full []BackupInfo    `json:"full"`
+	Apps []AppBackupInfo `json:"apps"`
+}
+
+// AppDataClient 通过 house_arrest 访问应用数据的客户端，由 filesystem.Client 实现
+// filesystem 包依赖本包，这里只声明用到的方法，由调用方传入。
+type AppDataClient interface {
+	GetTree(ctx context.Context, remote string, local string) error // 递归下载文件或目录
+	Mkdir(ctx context.Context, dir string) error
+	PutFile(ctx context.Context, local string, remote string) error
+}
+
+// AppDataOpener 创建访问应用数据的客户端，container 为 false 时只能访问 Documents 目录
+type AppDataOpener func(udid string, bundleID string, container bool) AppDataClient
+
+// appBackupCatalogMu 保护目录文件的读写
+var appBackupCatalogMu sync.Mutex
+
+// BackupAppData 通过 house_arrest 将应用数据复制到 baseDir 中，并记录到备份目录
+func BackupAppData(ctx context.Context, udid string, bundleID string, baseDir string, options AppBackupOptions, open AppDataOpener, onProgress func(AppDataProgress)) (AppBackupInfo, error) {
+	scope := options.Scope
+	if scope == "" {
+		scope = AppDataDocuments
+	}
+	app, err := findInstalledApp(udid, bundleID)
+	if err != nil {
+		return AppBackupInfo{}, err
+	}
+	if err := checkAppDataAccess(app, scope); err != nil {
+		return AppBackupInfo{}, err
+	}
+
+	created := time.Now()
+	backup := AppBackupInfo{
+		ID:         fmt.Sprintf("%s_%s_%s", bundleID, shortUDID(udid), created.Format("20060102_150405")),
+		DeviceUDID: udid,
+		DeviceName: getDeviceValue(udid, "DeviceName"),
+		BundleID:   bundleID,
+		AppName:    app.Name,
+		AppVersion: app.Version,
+		Scope:      scope,
+		Archived:   options.Archive,
+		CreatedAt:  created,
+	}
+	backup.Path = filepath.Join(baseDir, backup.ID)
+	dataDir := filepath.Join(backup.Path, appBackupDataDir)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return backup, fmt.Errorf("创建备份目录失败: %v", err)
+	}
+
+	client := open(udid, bundleID, scope == AppDataContainer)
+	roots := appDataRoots(scope)
+	for i, root := range roots {
+		if onProgress != nil {
+			onProgress(AppDataProgress{UDID: udid, BundleID: bundleID, Operation: "backup", CurrentFile: root, Done: i, Total: len(roots), Percent: i * 100 / len(roots)})
+		}
+		if err := client.GetTree(ctx, root, filepath.Join(dataDir, path.Base(root))); err != nil {
+			os.RemoveAll(backup.Path)
+			return backup, err
+		}
+	}
+
+	backup.Size, backup.FileCount = dirUsage(dataDir)
+	if options.Archive {
+		if err := zipDirectory(dataDir, filepath.Join(backup.Path, appBackupArchive)); err != nil {
+			os.RemoveAll(backup.Path)
+			return backup, err
+		}
+		os.RemoveAll(dataDir)
+	}
+
+	if data, err := json.MarshalIndent(backup, "", "  "); err == nil {
+		os.WriteFile(filepath.Join(backup.Path, appBackupInfoFile), data, 0644)
+	}
+	if err := addAppBackupToCatalog(baseDir, backup); err != nil {
+		return backup, err
+	}
+	if onProgress != nil {
+		onProgress(AppDataProgress{UDID: udid, BundleID: bundleID, Operation: "backup", Done: len(roots), Total: len(roots), Percent: 100})
+	}
+	fmt.Printf("已备份 %s 的数据到 %s (%d 个文件)\n", bundleID, backup.Path, backup.FileCount)
+	return backup, nil
+}
+
+// RestoreAppData 将应用数据备份写回设备上的同一应用，已存在的同名文件会被覆盖
+func RestoreAppData(ctx context.Context, udid string, baseDir string, backupID string, open AppDataOpener, onProgress func(AppDataProgress)) error {
+	backup, err := findAppBackup(baseDir, backupID)
+	if err != nil {
+		return err
+	}
+	app, err := findInstalledApp(udid, backup.BundleID)
+	if err != nil {
+		return err
+	}
+	if err := checkAppDataAccess(app, backup.Scope); err != nil {
+		return err
+	}
+
+	dataDir := filepath.Join(backup.Path, appBackupDataDir)
+	if backup.Archived {
+		tmp, err := os.MkdirTemp("", "myitools-appdata-*")
+		if err != nil {
+			return fmt.Errorf("创建临时目录失败: %v", err)
+		}
+		defer os.RemoveAll(tmp)
+		if err := unzipToDirectory(filepath.Join(backup.Path, appBackupArchive), tmp); err != nil {
+			return err
+		}
+		dataDir = tmp
+	}
+
+	// 先建立目录结构，再逐个上传文件，以便报告进度
+	var dirs, files []string
+	err = filepath.Walk(dataDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dataDir {
+			return err
+		}
+		rel, _ := filepath.Rel(dataDir, p)
+		if info.IsDir() {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		} else if info.Mode().IsRegular() {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取备份数据失败: %v", err)
+	}
+
+	client := open(udid, backup.BundleID, backup.Scope == AppDataContainer)
+	for _, dir := range dirs {
+		// 目录已存在时 afcclient 会报错，忽略即可
+		client.Mkdir(ctx, "/"+dir)
+	}
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("恢复已取消")
+		}
+		if onProgress != nil {
+			onProgress(AppDataProgress{UDID: udid, BundleID: backup.BundleID, Operation: "restore", CurrentFile: file, Done: i, Total: len(files), Percent: i * 100 / len(files)})
+		}
+		if err := client.PutFile(ctx, filepath.Join(dataDir, filepath.FromSlash(file)), "/"+file); err != nil {
+			return err
+		}
+	}
+
+	if onProgress != nil {
+		onProgress(AppDataProgress{UDID: udid, BundleID: backup.BundleID, Operation: "restore", Done: len(files), Total: len(files), Percent: 100})
+	}
+	fmt.Printf("已将 %s 的数据恢复到设备 %s (%d 个文件)\n", backup.BundleID, udid, len(files))
+	return nil
+}
+
+// ListAppBackups 列出备份目录中记录的应用数据备份，按时间从新到旧排列
+// 已被手动删除的备份不会返回。
+func ListAppBackups(baseDir string) ([]AppBackupInfo, error) {
+	appBackupCatalogMu.Lock()
+	defer appBackupCatalogMu.Unlock()
+
+	entries, err := readAppBackupCatalog(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	backups := []AppBackupInfo{}
+	for _, entry := range entries {
+		if _, err := os.Stat(entry.Path); err == nil {
+			backups = append(backups, entry)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// DeleteAppBackup 删除应用数据备份及其目录记录
+func DeleteAppBackup(baseDir string, backupID string) error {
+	appBackupCatalogMu.Lock()
+	defer appBackupCatalogMu.Unlock()
+
+	entries, err := readAppBackupCatalog(baseDir)
+	if err != nil {
+		return err
+	}
+	// kept 使用新的切片，与 entries 共用底层数组时被删除的记录会被后面的记录覆盖
+	kept := make([]AppBackupInfo, 0, len(entries))
+	var removed AppBackupInfo
+	found := false
+	for _, entry := range entries {
+		if entry.ID == backupID {
+			removed, found = entry, true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if !found {
+		return fmt.Errorf("找不到备份 %s", backupID)
+	}
+	if err := os.RemoveAll(removed.Path); err != nil {
+		return fmt.Errorf("删除备份失败: %v", err)
+	}
+	return writeAppBackupCatalog(baseDir, kept)
+}
+
+// GetBackupCatalog 汇总整机备份和应用数据备份
+func GetBackupCatalog(fullBackupDir string, appBackupDir string) (BackupCatalog, error) {
+	full, err := ListBackups(fullBackupDir)
+	if err != nil {
+		return BackupCatalog{}, err
+	}
+	apps, err := ListAppBackups(appBackupDir)
+	if err != nil {
+		return BackupCatalog{}, err
+	}
+	return BackupCatalog{Full: full, Apps: apps}, nil
+}
+
+// checkAppDataAccess 检查应用是否允许通过 house_arrest 访问数据
+func checkAppDataAccess(app AppInfo, scope string) error {
+	switch scope {
+	case AppDataDocuments:
+		if !app.FileSharing {
+			return ErrAppAccessDenied.WithDetail(app.Name+" 未开启文件共享", nil)
+		}
+	case AppDataContainer:
+		if !app.Entitlements.Debuggable {
+			return ErrAppAccessDenied.WithDetail(app.Name+" 不是开发签名的应用，无法访问完整数据容器", nil)
+		}
+	default:
+		return fmt.Errorf("不支持的数据范围: %s", scope)
+	}
+	return nil
+}
+
+// appDataRoots 返回需要备份的设备目录
+func appDataRoots(scope string) []string {
+	if scope == AppDataContainer {
+		return []string{"/Documents", "/Library"}
+	}
+	return []string{"/Documents"}
+}
+
+// readAppBackupCatalog 读取备份目录文件，文件不存在时返回空列表
+func readAppBackupCatalog(baseDir string) ([]AppBackupInfo, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, appBackupCatalogFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取备份目录失败: %v", err)
+	}
+	var entries []AppBackupInfo
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("备份目录文件损坏: %v", err)
+	}
+	return entries, nil
+}
+
+// writeAppBackupCatalog 写入备份目录文件
+func writeAppBackupCatalog(baseDir string, entries []AppBackupInfo) error {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return fmt.Errorf("创建备份目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(baseDir, appBackupCatalogFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入备份目录失败: %v", err)
+	}
+	return os.Rename(tmp, filepath.Join(baseDir, appBackupCatalogFile))
+}
+
+// addAppBackupToCatalog 将备份记录追加到备份目录文件
+func addAppBackupToCatalog(baseDir string, backup AppBackupInfo) error {
+	appBackupCatalogMu.Lock()
+	defer appBackupCatalogMu.Unlock()
+
+	entries, err := readAppBackupCatalog(baseDir)
+	if err != nil {
+		return err
+	}
+	return writeAppBackupCatalog(baseDir, append(entries, backup))
+}
+
+// findAppBackup 在备份目录中查找备份
+func findAppBackup(baseDir string, backupID string) (AppBackupInfo, error) {
+	backups, err := ListAppBackups(baseDir)
+	if err != nil {
+		return AppBackupInfo{}, err
+	}
+	for _, backup := range backups {
+		if backup.ID == backupID {
+			return backup, nil
+		}
+	}
+	return AppBackupInfo{}, fmt.Errorf("找不到备份 %s", backupID)
+}
+
+// shortUDID 返回 UDID 的后 8 位，用于文件名
+func shortUDID(udid string) string {
+	if len(udid) > 8 {
+		return udid[len(udid)-8:]
+	}
+	return udid
+}
+
+// dirUsage 统计目录中文件的总大小和数量
+func dirUsage(dir string) (int64, int) {
+	var size int64
+	var count int
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+			count++
+		}
+		return nil
+	})
+	return size, count
+}
+
+// zipDirectory 将目录打包为 zip 文件
+func zipDirectory(src string, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("创建压缩文件失败: %v", err)
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+	err = filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		writer.Close()
+		return fmt.Errorf("打包备份数据失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("打包备份数据失败: %v", err)
+	}
+	return nil
+}
+
+// unzipToDirectory 将 zip 文件解压到目录，拒绝指向目录之外的路径
+func unzipToDirectory(src string, dst string) error {
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("打开备份压缩文件失败: %v", err)
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		target := filepath.Join(dst, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dst)+string(filepath.Separator)) {
+			return fmt.Errorf("备份压缩文件包含非法路径: %s", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			os.MkdirAll(target, 0755)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return fmt.Errorf("解压 %s 失败: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// extractZipFile 将压缩包中的一个文件写到 target
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return err
+}
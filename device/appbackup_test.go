@@ -0,0 +1,45 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteAppBackupRemovesOnlyTarget(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, id := range []string{"A", "B", "C"} {
+		path := filepath.Join(baseDir, id)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := addAppBackupToCatalog(baseDir, AppBackupInfo{ID: id, Path: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DeleteAppBackup(baseDir, "B"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, "B")); !os.IsNotExist(err) {
+		t.Error("备份 B 的目录应被删除")
+	}
+	for _, id := range []string{"A", "C"} {
+		if _, err := os.Stat(filepath.Join(baseDir, id)); err != nil {
+			t.Errorf("备份 %s 的目录不应被删除: %v", id, err)
+		}
+	}
+
+	entries, err := readAppBackupCatalog(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ID != "A" || entries[1].ID != "C" {
+		t.Errorf("备份目录 = %+v, want A 和 C", entries)
+	}
+
+	if err := DeleteAppBackup(baseDir, "B"); err == nil {
+		t.Error("删除不存在的备份应返回错误")
+	}
+}
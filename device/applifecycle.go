@@ -0,0 +1,293 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// 归档内容
+const (
+	ArchiveAll      = "all"       // 应用和文档
+	ArchiveAppOnly  = "app_only"  // 仅应用本身
+	ArchiveDocsOnly = "docs_only" // 仅文档数据
+)
+
+// maxArchiveIOSVersion installation_proxy 的 Archive/Restore 在此版本起不再支持
+const maxArchiveIOSVersion = "7.0"
+
+// ArchiveOptions 归档应用的选项
+// 默认只归档文档并删除应用本身，以释放空间并保留数据。
+type ArchiveOptions struct {
+	Content string `json:"content"`  // all、app_only 或 docs_only，为空时为 docs_only
+	KeepApp bool   `json:"keep_app"` // 归档后保留应用，默认删除应用只保留归档
+	CopyTo  string `json:"copy_to"`  // 将归档复制到本地目录，为空时不复制
+}
+
+// AppArchive 设备上的应用归档
+type AppArchive struct {
+	BundleID string `json:"bundle_id"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Build    string `json:"build"`
+}
+
+// UninstallApp 卸载应用
+// 系统应用不能卸载，返回 ErrAppNotRemovable；应用不存在时返回 ErrAppNotFound。
+func UninstallApp(ctx context.Context, udid string, bundleID string, onProgress func(InstallProgress)) error {
+	app, err := findInstalledApp(udid, bundleID)
+	if err != nil {
+		return err
+	}
+	if app.Type == AppTypeSystem {
+		return ErrAppNotRemovable.WithDetail(app.Name, nil)
+	}
+
+	report := appProgressReporter(udid, OperationUninstall, bundleID, onProgress)
+	report("Preparing", 0)
+	err = runInstaller(ctx, udid, "卸载 "+app.Name+" 失败", report, "-U", bundleID)
+	InvalidateAppCache(udid)
+	if err != nil {
+		return err
+	}
+	report("Complete", 100)
+	fmt.Printf("已从设备 %s 卸载 %s\n", udid, bundleID)
+	return nil
+}
+
+// UninstallApps 依次卸载多个应用，返回每个应用的结果
+func UninstallApps(ctx context.Context, udid string, bundleIDs []string, onProgress func(InstallProgress)) []InstallResult {
+	results := make([]InstallResult, len(bundleIDs))
+	for i, bundleID := range bundleIDs {
+		results[i] = InstallResult{UDID: udid, BundleID: bundleID}
+		if ctx.Err() != nil {
+			results[i].Error = "已取消"
+			continue
+		}
+		if err := UninstallApp(ctx, udid, bundleID, onProgress); err != nil {
+			results[i].Error = err.Error()
+			results[i].ErrorCode = ErrorCodeOf(err)
+			continue
+		}
+		results[i].Success = true
+	}
+	return results
+}
+
+// ArchiveApp 在设备上归档应用，默认保留文档并删除应用本身以释放空间
+// iOS 7 起系统不再支持归档，返回 ErrArchiveUnsupported。
+func ArchiveApp(ctx context.Context, udid string, bundleID string, options ArchiveOptions, onProgress func(InstallProgress)) error {
+	if err := checkArchiveSupported(udid); err != nil {
+		return err
+	}
+	app, err := findInstalledApp(udid, bundleID)
+	if err != nil {
+		return err
+	}
+	if !options.KeepApp && app.Type == AppTypeSystem {
+		return ErrAppNotRemovable.WithDetail(app.Name, nil)
+	}
+
+	args, err := archiveArgs(bundleID, options)
+	if err != nil {
+		return err
+	}
+	if options.CopyTo != "" {
+		if err := os.MkdirAll(options.CopyTo, 0755); err != nil {
+			return fmt.Errorf("创建归档目录失败: %v", err)
+		}
+		args = append(args, "-o", "copy="+options.CopyTo)
+	}
+
+	report := appProgressReporter(udid, OperationArchive, bundleID, onProgress)
+	report("Preparing", 0)
+	err = runInstaller(ctx, udid, "归档 "+app.Name+" 失败", report, args...)
+	InvalidateAppCache(udid)
+	if err != nil {
+		return err
+	}
+	report("Complete", 100)
+	fmt.Printf("已在设备 %s 上归档 %s\n", udid, bundleID)
+	return nil
+}
+
+// archiveArgs 根据归档选项生成 ideviceinstaller 参数，不包括 CopyTo
+func archiveArgs(bundleID string, options ArchiveOptions) ([]string, error) {
+	args := []string{"-a", bundleID}
+	switch options.Content {
+	case "", ArchiveDocsOnly:
+		args = append(args, "-o", ArchiveDocsOnly)
+	case ArchiveAll:
+	case ArchiveAppOnly:
+		args = append(args, "-o", ArchiveAppOnly)
+	default:
+		return nil, fmt.Errorf("不支持的归档内容: %s", options.Content)
+	}
+	if !options.KeepApp {
+		args = append(args, "-o", "uninstall")
+	}
+	return args, nil
+}
+
+// checkArchiveSupported 检查设备系统是否支持应用归档
+func checkArchiveSupported(udid string) error {
+	if !IsDeviceConnected(udid) {
+		return ErrDeviceNotConnected
+	}
+	version := getDeviceValue(udid, "ProductVersion")
+	if version == "" {
+		return fmt.Errorf("无法读取系统版本")
+	}
+	if !archiveSupported(version) {
+		return ErrArchiveUnsupported.WithDetail("iOS "+version, nil)
+	}
+	return nil
+}
+
+// archiveSupported installation_proxy 在该系统版本上是否支持 Archive/Restore
+func archiveSupported(version string) bool {
+	return compareVersions(version, maxArchiveIOSVersion) < 0
+}
+
+// RestoreApp 从设备上的归档恢复应用
+func RestoreApp(ctx context.Context, udid string, bundleID string, onProgress func(InstallProgress)) error {
+	if err := checkArchiveSupported(udid); err != nil {
+		return err
+	}
+	if err := findArchive(udid, bundleID); err != nil {
+		return err
+	}
+
+	report := appProgressReporter(udid, OperationRestore, bundleID, onProgress)
+	report("Preparing", 0)
+	err := runInstaller(ctx, udid, "恢复 "+bundleID+" 失败", report, "-r", bundleID)
+	InvalidateAppCache(udid)
+	if err != nil {
+		return err
+	}
+	report("Complete", 100)
+	fmt.Printf("已在设备 %s 上恢复 %s\n", udid, bundleID)
+	return nil
+}
+
+// RemoveAppArchive 删除设备上的应用归档
+func RemoveAppArchive(ctx context.Context, udid string, bundleID string, onProgress func(InstallProgress)) error {
+	if err := checkArchiveSupported(udid); err != nil {
+		return err
+	}
+	if err := findArchive(udid, bundleID); err != nil {
+		return err
+	}
+
+	report := appProgressReporter(udid, OperationRemoveArchive, bundleID, onProgress)
+	report("Preparing", 0)
+	if err := runInstaller(ctx, udid, "删除 "+bundleID+" 的归档失败", report, "-R", bundleID); err != nil {
+		return err
+	}
+	report("Complete", 100)
+	return nil
+}
+
+// ListAppArchives 列出设备上的应用归档
+func ListAppArchives(udid string) ([]AppArchive, error) {
+	cmd := exec.Command("ideviceinstaller", "-u", udid, "-L", "-o", "xml")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("ideviceinstaller", "获取应用归档失败", err, stderr.String())
+	}
+	return parseAppArchives(output)
+}
+
+// parseAppArchives 解析 installation_proxy 返回的归档列表，键为 Bundle ID
+func parseAppArchives(data []byte) ([]AppArchive, error) {
+	archives := []AppArchive{}
+	if strings.TrimSpace(string(data)) == "" {
+		return archives, nil
+	}
+	root, err := parseXMLPlist(data)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("应用归档列表格式错误")
+	}
+
+	for bundleID, value := range dict {
+		info := parseAppInfo(plistDict(value))
+		archive := AppArchive{BundleID: bundleID, Name: info.Name, Version: info.Version, Build: info.Build}
+		if archive.Name == "" {
+			archive.Name = bundleID
+		}
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return strings.ToLower(archives[i].Name) < strings.ToLower(archives[j].Name)
+	})
+	return archives, nil
+}
+
+// findInstalledApp 在应用列表中查找应用，找不到时返回 ErrAppNotFound
+// 缓存中找不到时重新读取一次应用列表，应用可能是在缓存之后从设备上安装的。
+func findInstalledApp(udid string, bundleID string) (AppInfo, error) {
+	if !IsDeviceConnected(udid) {
+		return AppInfo{}, ErrDeviceNotConnected
+	}
+	apps, err := cachedApps(udid)
+	if err != nil {
+		return AppInfo{}, err
+	}
+	if app, ok := findApp(apps, bundleID); ok {
+		return app, nil
+	}
+
+	InvalidateAppCache(udid)
+	if apps, err = cachedApps(udid); err != nil {
+		return AppInfo{}, err
+	}
+	if app, ok := findApp(apps, bundleID); ok {
+		return app, nil
+	}
+	return AppInfo{}, ErrAppNotFound.WithDetail(bundleID, nil)
+}
+
+// findApp 在应用列表中按 Bundle ID 查找应用
+func findApp(apps []AppInfo, bundleID string) (AppInfo, bool) {
+	for _, app := range apps {
+		if app.BundleID == bundleID {
+			return app, true
+		}
+	}
+	return AppInfo{}, false
+}
+
+// findArchive 检查设备上是否有应用的归档，没有时返回 ErrArchiveNotFound
+func findArchive(udid string, bundleID string) error {
+	if !IsDeviceConnected(udid) {
+		return ErrDeviceNotConnected
+	}
+	archives, err := ListAppArchives(udid)
+	if err != nil {
+		return err
+	}
+	for _, archive := range archives {
+		if archive.BundleID == bundleID {
+			return nil
+		}
+	}
+	return ErrArchiveNotFound.WithDetail(bundleID, nil)
+}
+
+// appProgressReporter 返回向 onProgress 报告进度的函数
+func appProgressReporter(udid string, operation string, bundleID string, onProgress func(InstallProgress)) func(stage string, percent int) {
+	return func(stage string, percent int) {
+		if onProgress != nil {
+			onProgress(InstallProgress{UDID: udid, Operation: operation, BundleID: bundleID, Stage: stage, Percent: percent})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package device
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"myitools/internal/testutil"
+)
+
+func TestArchiveArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ArchiveOptions
+		want    []string
+	}{
+		{"默认保留文档并删除应用", ArchiveOptions{}, []string{"-a", "com.example", "-o", "docs_only", "-o", "uninstall"}},
+		{"保留应用", ArchiveOptions{KeepApp: true}, []string{"-a", "com.example", "-o", "docs_only"}},
+		{"全部内容", ArchiveOptions{Content: ArchiveAll, KeepApp: true}, []string{"-a", "com.example"}},
+		{"仅应用", ArchiveOptions{Content: ArchiveAppOnly}, []string{"-a", "com.example", "-o", "app_only", "-o", "uninstall"}},
+	}
+	for _, tt := range tests {
+		got, err := archiveArgs("com.example", tt.options)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: archiveArgs = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := archiveArgs("com.example", ArchiveOptions{Content: "bogus"}); err == nil {
+		t.Error("不支持的归档内容应返回错误")
+	}
+}
+
+func TestArchiveSupported(t *testing.T) {
+	for version, want := range map[string]bool{"6.1.6": true, "7.0": false, "12.5.7": false, "17.4": false} {
+		if got := archiveSupported(version); got != want {
+			t.Errorf("archiveSupported(%q) = %v, want %v", version, got, want)
+		}
+	}
+	if err := ErrArchiveUnsupported.WithDetail("iOS 17.4", nil); ErrorCodeOf(err) != CodeArchiveUnsupported {
+		t.Errorf("错误码 = %s", ErrorCodeOf(err))
+	}
+}
+
+func TestFindInstalledAppRefreshesOnMiss(t *testing.T) {
+	list, err := filepath.Abs(filepath.Join("testdata", "ideviceinstaller_list.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.NewFakeTools(t, map[string]string{
+		"idevice_id":       "echo test-udid\n",
+		"ideviceinstaller": "cat '" + list + "'\n",
+	})
+	t.Cleanup(func() { InvalidateAppCache("test-udid") })
+
+	// 缓存中还没有在设备上新安装的应用
+	appCacheMu.Lock()
+	appCache["test-udid"] = appCacheEntry{apps: []AppInfo{}, updated: time.Now()}
+	appCacheMu.Unlock()
+
+	app, err := findInstalledApp("test-udid", "com.tencent.xin")
+	if err != nil {
+		t.Fatalf("缓存未命中时应重新读取应用列表: %v", err)
+	}
+	if app.Name != "微信" {
+		t.Errorf("Name = %q", app.Name)
+	}
+
+	if _, err := findInstalledApp("test-udid", "com.example.missing"); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("不存在的应用应返回 ErrAppNotFound, got %v", err)
+	}
+}
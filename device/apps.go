@@ -0,0 +1,215 @@
+package device
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 应用类型
+const (
+	AppTypeUser   = "user"
+	AppTypeSystem = "system"
+	AppTypeAll    = "all"
+)
+
+// appCacheTTL 应用列表缓存的有效期，安装、卸载应用以及设备接入或断开时会立即失效
+const appCacheTTL = 5 * time.Minute
+
+// AppInfo 已安装应用信息
+type AppInfo struct {
+	BundleID         string            `json:"bundle_id"`
+	Name             string            `json:"name"`               // 显示名称
+	Version          string            `json:"version"`            // CFBundleShortVersionString
+	Build            string            `json:"build"`              // CFBundleVersion
+	Type             string            `json:"type"`               // user 或 system
+	SignerIdentity   string            `json:"signer_identity"`    // 签名证书，App Store 应用为 Apple iPhone OS Application Signing
+	StaticDiskUsage  int64             `json:"static_disk_usage"`  // 应用本身大小(字节)
+	DynamicDiskUsage int64             `json:"dynamic_disk_usage"` // 数据和缓存大小(字节)
+	Path             string            `json:"path"`               // 应用包路径
+	Container        string            `json:"container"`          // 数据容器路径
+	GroupContainers  map[string]string `json:"group_containers"`   // App Group 容器路径
+	Entitlements     AppEntitlements   `json:"entitlements"`
+	MinimumOS        string            `json:"minimum_os"`
+	FileSharing      bool              `json:"file_sharing"` // 是否开启 iTunes 文件共享
+}
+
+// AppEntitlements 应用权限中常用的标志
+type AppEntitlements struct {
+	Debuggable        bool     `json:"debuggable"`         // get-task-allow，开发签名的应用
+	PushNotifications string   `json:"push_notifications"` // aps-environment: development 或 production
+	AppGroups         []string `json:"app_groups"`
+	ICloud            bool     `json:"icloud"`
+	KeychainGroups    []string `json:"keychain_groups"`
+	TeamID            string   `json:"team_id"`
+}
+
+// AppFilter 应用列表过滤条件
+type AppFilter struct {
+	Type   string `json:"type"`   // user、system 或 all，为空时为 user
+	Search string `json:"search"` // 按名称或 Bundle ID 搜索，不区分大小写
+}
+
+// appCacheEntry 一台设备的应用列表缓存
+type appCacheEntry struct {
+	apps    []AppInfo
+	updated time.Time
+}
+
+var (
+	appCacheMu sync.Mutex
+	appCache   = map[string]appCacheEntry{}
+)
+
+// ListApps 获取设备上已安装的应用，结果按设备缓存
+func ListApps(udid string, filter AppFilter) ([]AppInfo, error) {
+	apps, err := cachedApps(udid)
+	if err != nil {
+		return nil, err
+	}
+	return filterApps(apps, filter), nil
+}
+
+// InvalidateAppCache 使设备的应用列表缓存失效，安装、卸载应用或设备接入、断开后调用
+func InvalidateAppCache(udid string) {
+	appCacheMu.Lock()
+	delete(appCache, udid)
+	appCacheMu.Unlock()
+}
+
+// cachedApps 返回缓存的应用列表，缓存不存在或过期时重新读取
+func cachedApps(udid string) ([]AppInfo, error) {
+	appCacheMu.Lock()
+	entry, ok := appCache[udid]
+	appCacheMu.Unlock()
+	if ok && time.Since(entry.updated) < appCacheTTL {
+		return entry.apps, nil
+	}
+
+	apps, err := browseApps(udid)
+	if err != nil {
+		return nil, err
+	}
+
+	appCacheMu.Lock()
+	appCache[udid] = appCacheEntry{apps: apps, updated: time.Now()}
+	appCacheMu.Unlock()
+	return apps, nil
+}
+
+// browseApps 通过 ideviceinstaller 读取全部应用
+func browseApps(udid string) ([]AppInfo, error) {
+	cmd := exec.Command("ideviceinstaller", "-u", udid, "-l", "-o", "list_all", "-o", "xml")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("ideviceinstaller", "获取应用列表失败", err, stderr.String())
+	}
+
+	root, err := parseXMLPlist(output)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := root.([]any)
+	if !ok {
+		return nil, fmt.Errorf("应用列表格式错误")
+	}
+
+	apps := make([]AppInfo, 0, len(items))
+	for _, item := range items {
+		if dict, ok := item.(map[string]any); ok {
+			apps = append(apps, parseAppInfo(dict))
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return strings.ToLower(apps[i].Name) < strings.ToLower(apps[j].Name)
+	})
+	return apps, nil
+}
+
+// parseAppInfo 将 installation_proxy 返回的字典转换为 AppInfo
+func parseAppInfo(dict map[string]any) AppInfo {
+	app := AppInfo{
+		BundleID:       plistString(dict, "CFBundleIdentifier"),
+		Name:           plistString(dict, "CFBundleDisplayName"),
+		Version:        plistString(dict, "CFBundleShortVersionString"),
+		Build:          plistString(dict, "CFBundleVersion"),
+		SignerIdentity: plistString(dict, "SignerIdentity"),
+		Path:           plistString(dict, "Path"),
+		Container:      plistString(dict, "Container"),
+		MinimumOS:      plistString(dict, "MinimumOSVersion"),
+		FileSharing:    plistBool(dict, "UIFileSharingEnabled"),
+	}
+	if app.Name == "" {
+		app.Name = plistString(dict, "CFBundleName")
+	}
+	if app.Name == "" {
+		app.Name = app.BundleID
+	}
+	if app.Container == "" {
+		app.Container = plistString(plistDict(dict, "EnvironmentVariables"), "HOME")
+	}
+
+	app.Type = AppTypeSystem
+	if plistString(dict, "ApplicationType") == "User" {
+		app.Type = AppTypeUser
+	}
+
+	app.StaticDiskUsage, _ = plistInt(dict, "StaticDiskUsage")
+	app.DynamicDiskUsage, _ = plistInt(dict, "DynamicDiskUsage")
+
+	if groups := plistDict(dict, "GroupContainers"); len(groups) > 0 {
+		app.GroupContainers = make(map[string]string, len(groups))
+		for id := range groups {
+			app.GroupContainers[id] = plistString(groups, id)
+		}
+	}
+
+	if entitlements := plistDict(dict, "Entitlements"); entitlements != nil {
+		app.Entitlements = parseEntitlements(entitlements)
+	}
+	return app
+}
+
+// parseEntitlements 提取常用的权限标志
+func parseEntitlements(entitlements map[string]any) AppEntitlements {
+	e := AppEntitlements{
+		Debuggable:        plistBool(entitlements, "get-task-allow"),
+		PushNotifications: plistString(entitlements, "aps-environment"),
+		AppGroups:         plistStrings(entitlements, "com.apple.security.application-groups"),
+		KeychainGroups:    plistStrings(entitlements, "keychain-access-groups"),
+		TeamID:            plistString(entitlements, "com.apple.developer.team-identifier"),
+	}
+	for key := range entitlements {
+		if strings.HasPrefix(key, "com.apple.developer.icloud") || strings.HasPrefix(key, "com.apple.developer.ubiquity") {
+			e.ICloud = true
+			break
+		}
+	}
+	return e
+}
+
+// filterApps 按过滤条件筛选应用
+func filterApps(apps []AppInfo, filter AppFilter) []AppInfo {
+	appType := filter.Type
+	if appType == "" {
+		appType = AppTypeUser
+	}
+	search := strings.ToLower(strings.TrimSpace(filter.Search))
+
+	result := []AppInfo{}
+	for _, app := range apps {
+		if appType != AppTypeAll && app.Type != appType {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(app.Name), search) && !strings.Contains(strings.ToLower(app.BundleID), search) {
+			continue
+		}
+		result = append(result, app)
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInstallerListPlist(t *testing.T) {
+	items, ok := loadPlistFixture(t, "ideviceinstaller_list.plist").([]any)
+	if !ok || len(items) != 2 {
+		t.Fatal("ideviceinstaller_list.plist: 应为 2 个应用的数组")
+	}
+
+	wechat := parseAppInfo(items[0].(map[string]any))
+	want := AppInfo{
+		BundleID:         "com.tencent.xin",
+		Name:             "微信",
+		Version:          "8.0.49",
+		Build:            "8.0.49.33",
+		Type:             AppTypeUser,
+		SignerIdentity:   "Apple iPhone OS Application Signing",
+		StaticDiskUsage:  812646400,
+		DynamicDiskUsage: 5368709120,
+		Path:             "/private/var/containers/Bundle/Application/9A7B6C5D-4E3F-2A1B-0C9D-8E7F6A5B4C3D/WeChat.app",
+		Container:        "/private/var/mobile/Containers/Data/Application/6C1E2C9A-3F0B-4C55-9A2B-1F4E1C2D3E4F",
+		GroupContainers: map[string]string{
+			"group.com.tencent.xin": "/private/var/mobile/Containers/Shared/AppGroup/0B8D6E3A-5C1F-4E7A-8D2B-3A4C5D6E7F80",
+		},
+		MinimumOS:   "12.0",
+		FileSharing: true,
+	}
+	entitlements := wechat.Entitlements
+	wechat.Entitlements = AppEntitlements{}
+	if !reflect.DeepEqual(wechat, want) {
+		t.Errorf("ideviceinstaller_list.plist 用户应用:\n got %+v\nwant %+v", wechat, want)
+	}
+	if entitlements.Debuggable || entitlements.PushNotifications != "production" || entitlements.TeamID != "88L2Q4487U" ||
+		len(entitlements.KeychainGroups) != 2 || !reflect.DeepEqual(entitlements.AppGroups, []string{"group.com.tencent.xin"}) {
+		t.Errorf("ideviceinstaller_list.plist 权限解析错误: %+v", entitlements)
+	}
+
+	safari := parseAppInfo(items[1].(map[string]any))
+	if safari.Type != AppTypeSystem || safari.Name != "Safari" || safari.Container != "/private/var/mobile" {
+		t.Errorf("ideviceinstaller_list.plist 系统应用: %+v", safari)
+	}
+}
@@ -24,10 +24,11 @@ type BackupInfo struct {
 
 // BackupProgress 备份进度结构体
 type BackupProgress struct {
-	Status      string  `json:"status"`       // 状态: preparing, backing_up, finishing, completed, failed
-	Progress    float64 `json:"progress"`     // 进度百分比 (0-100)
-	CurrentFile string  `json:"current_file"` // 当前正在备份的文件
-	Error       string  `json:"error"`        // 错误信息(如果有)
+	Status      string    `json:"status"`       // 状态: preparing, backing_up, finishing, completed, failed
+	Progress    float64   `json:"progress"`     // 进度百分比 (0-100)
+	CurrentFile string    `json:"current_file"` // 当前正在备份的文件
+	Error       string    `json:"error"`        // 错误信息(如果有)
+	ErrorCode   ErrorCode `json:"error_code"`   // 错误码(如果有)
 }
 
 // 全局变量，用于跟踪备份进度
@@ -48,7 +49,7 @@ func GetBackupProgress(backupID string) *BackupProgress {
 // CheckBackupEncryptionStatus 检查设备备份加密状态
 func CheckBackupEncryptionStatus(udid string) (bool, error) {
 	if !IsDeviceConnected(udid) {
-		return false, errors.New("设备未连接")
+		return false, ErrDeviceNotConnected
 	}
 
 	// 使用 ideviceinfo 命令查询备份加密状态
@@ -66,7 +67,7 @@ func CheckBackupEncryptionStatus(udid string) (bool, error) {
 	
 	if err != nil {
 		fmt.Printf("执行ideviceinfo命令失败: %v\n", err)
-		return false, fmt.Errorf("无法获取设备备份信息: %v", err)
+		return false, commandError("ideviceinfo", "无法获取设备备份信息", err, outputStr)
 	}
 	
 	// 检查 PasswordProtected 和 WillEncrypt 字段
@@ -84,101 +85,92 @@ func CheckBackupEncryptionStatus(udid string) (bool, error) {
 // SetBackupEncryption 设置备份加密状态
 func SetBackupEncryption(udid string, enable bool, password string) error {
 	if !IsDeviceConnected(udid) {
-		return errors.New("设备未连接")
+		return ErrDeviceNotConnected
 	}
 
-	// 构建命令
+	pw := newSecret(password)
+	defer pw.Wipe()
+
+	// 使用交互式方式设置加密，密码在检测到提示后通过标准输入传递
 	var args []string
+	var answers []*secret
 	if enable {
-		// 使用交互式方式设置加密
+		// 启用加密需要输入两次密码
 		args = []string{"-u", udid, "encryption", "on", "-i"}
+		answers = []*secret{pw, pw}
 	} else {
-		// 使用交互式方式关闭加密
+		// 禁用加密只需要输入一次密码
 		args = []string{"-u", udid, "encryption", "off", "-i"}
+		answers = []*secret{pw}
 	}
 
-	fmt.Printf("执行命令: idevicebackup2 %s\n", strings.Join(args, " "))
-	
-	// 创建命令
-	cmd := exec.Command("idevicebackup2", args...)
-	
-	// 创建管道用于输入密码
-	stdin, err := cmd.StdinPipe()
+	output, err := runWithPasswordPrompts("idevicebackup2", args, answers...)
 	if err != nil {
-		return fmt.Errorf("创建输入管道失败: %v", err)
+		return commandError("idevicebackup2", "设置备份加密状态失败", err, output)
 	}
-	
-	// 创建管道用于获取输出
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("创建输出管道失败: %v", err)
+
+	return nil
+}
+
+// ChangeBackupPassword 修改备份加密密码
+// 使用 idevicebackup2 changepw 直接更换密码，过程中备份始终保持加密。
+func ChangeBackupPassword(udid string, oldPassword string, newPassword string) error {
+	if !IsDeviceConnected(udid) {
+		return ErrDeviceNotConnected
 	}
-	
-	stderr, err := cmd.StderrPipe()
+
+	encrypted, err := CheckBackupEncryptionStatus(udid)
 	if err != nil {
-		return fmt.Errorf("创建错误输出管道失败: %v", err)
+		return err
 	}
-	
-	// 启动命令
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Errorf("启动命令失败: %v", err)
+	if !encrypted {
+		return ErrNoBackupPassword
 	}
-	
-	// 创建一个goroutine来读取输出
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stdout.Read(buf)
-			if n > 0 {
-				fmt.Printf("命令输出: %s", string(buf[:n]))
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-	
-	// 创建一个goroutine来读取错误输出
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderr.Read(buf)
-			if n > 0 {
-				fmt.Printf("命令错误输出: %s", string(buf[:n]))
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-	
-	// 等待一段时间，让命令有时间启动并输出提示
-	time.Sleep(500 * time.Millisecond)
-	
-	// 输入密码
-	if enable {
-		// 启用加密需要输入两次密码
-		fmt.Fprintf(stdin, "%s\n%s\n", password, password)
-	} else {
-		// 禁用加密只需要输入一次密码
-		fmt.Fprintf(stdin, "%s\n", password)
+
+	if newPassword == "" {
+		return errors.New("新密码不能为空")
+	}
+
+	oldPw := newSecret(oldPassword)
+	defer oldPw.Wipe()
+	newPw := newSecret(newPassword)
+	defer newPw.Wipe()
+
+	// 依次输入旧密码、新密码、确认新密码
+	args := []string{"-u", udid, "changepw", "-i"}
+	output, err := runWithPasswordPrompts("idevicebackup2", args, oldPw, newPw, newPw)
+	// 设备锁定时 mobilebackup2 拒绝修改密码，部分版本仍返回0
+	if isBackupDeviceLocked(output) {
+		return ErrPasscodeLocked.WithDetail(output, err)
 	}
-	stdin.Close()
-	
-	// 等待命令完成
-	err = cmd.Wait()
 	if err != nil {
-		return fmt.Errorf("设置备份加密状态失败: %v", err)
+		return commandError("idevicebackup2", "修改备份密码失败", err, output)
 	}
-	
+	// 部分版本在密码错误时也返回0，需要检查输出
+	// 成功时输出会提示在设备上输入密码确认，因此这里只识别密码错误
+	if classifyOutput(output) == ErrWrongPassword {
+		return ErrWrongPassword.WithDetail(output, nil)
+	}
+
 	return nil
 }
 
+// isBackupDeviceLocked 检查 idevicebackup2 的输出是否为设备锁定的提示
+// 修改成功时的 "entering the passcode on the device" 不是锁定提示，不能按 passcode 匹配。
+func isBackupDeviceLocked(output string) bool {
+	lower := strings.ToLower(output)
+	for _, marker := range []string{"device locked", "device is locked", "devicelocked", "please unlock"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateBackup 创建设备备份
 func CreateBackup(udid string, backupDir string, encrypt bool, password string) (string, error) {
 	if !IsDeviceConnected(udid) {
-		return "", errors.New("设备未连接")
+		return "", ErrDeviceNotConnected
 	}
 
 	// 确保备份目录存在
@@ -207,24 +199,33 @@ func CreateBackup(udid string, backupDir string, encrypt bool, password string)
 		Progress: 0,
 	}
 
+	// 密码只在备份命令运行期间保存在内存中
+	var pw *secret
+	if encrypt {
+		pw = newSecret(password)
+	}
+
 	// 在后台执行备份
 	go func() {
 		// 使用原始UDID进行备份
 		args := []string{"-u", udid, "backup", "--full", backupDir}
-		if encrypt && password != "" {
-			args = append(args, "--password", password)
-			fmt.Printf("添加加密参数: %v\n", args)
-		}
 
 		fmt.Printf("备份命令参数: %v\n", args)
 		cmd := exec.Command("idevicebackup2", args...)
+		// 密码通过环境变量传递，不出现在命令行参数中
+		setPasswordEnv(cmd, pw)
 		output, err := cmd.CombinedOutput()
-		
-		fmt.Printf("备份命令输出: %s\n", string(output))
+		outputStr := maskPasswords(string(output), pw)
+		cmd.Env = nil
+		pw.Wipe()
+
+		fmt.Printf("备份命令输出: %s\n", outputStr)
 
 		if err != nil {
+			backupErr := commandError("idevicebackup2", "备份失败", err, outputStr)
 			backupProgressMap[backupID].Status = "failed"
-			backupProgressMap[backupID].Error = fmt.Sprintf("备份失败: %v - %s", err, string(output))
+			backupProgressMap[backupID].Error = backupErr.Error()
+			backupProgressMap[backupID].ErrorCode = ErrorCodeOf(backupErr)
 			return
 		}
 
@@ -335,7 +336,7 @@ func CreateBackup(udid string, backupDir string, encrypt bool, password string)
 // RestoreBackup 恢复设备备份
 func RestoreBackup(udid string, backupDir string, password string) error {
 	if !IsDeviceConnected(udid) {
-		return errors.New("设备未连接")
+		return ErrDeviceNotConnected
 	}
 
 	// 检查备份是否加密
@@ -344,7 +345,7 @@ func RestoreBackup(udid string, backupDir string, password string) error {
 
 	// 如果备份加密了但没有提供密码
 	if isEncrypted && password == "" {
-		return errors.New("此备份已加密，请提供密码")
+		return ErrPasswordRequired
 	}
 
 	// 如果备份没有加密但提供了密码
@@ -352,18 +353,49 @@ func RestoreBackup(udid string, backupDir string, password string) error {
 		fmt.Printf("警告：备份未加密，但提供了密码，将忽略密码\n")
 	}
 
-	args := []string{"-u", udid, "restore", "--full", backupDir}
-	if isEncrypted && password != "" {
-		args = append(args, "--password", password)
+	// 检查备份与目标设备是否兼容
+	compat, err := CheckRestoreCompatibility(udid, backupDir)
+	if err != nil {
+		return err
+	}
+	if !compat.Compatible {
+		return fmt.Errorf("备份无法恢复到此设备: %s", strings.Join(compat.Errors, "; "))
+	}
+	for _, warning := range compat.Warnings {
+		fmt.Printf("恢复警告: %s\n", warning)
+	}
+
+	// 跨设备恢复时通过 --source 指定备份来源设备，备份需暂存在以来源UDID命名的目录下
+	sourceUDID := compat.SourceUDID
+	if sourceUDID == "" {
+		sourceUDID = udid
+	}
+	stagingDir, cleanup, err := stageBackupForRestore(sourceUDID, backupDir)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
+
+	args := []string{"-u", udid, "restore", "--full", "--source", sourceUDID, stagingDir}
+
+	pw := newSecret(password)
+	defer pw.Wipe()
 
 	fmt.Printf("恢复命令参数: %v\n", args)
 	cmd := exec.Command("idevicebackup2", args...)
+	// 密码通过环境变量传递，不出现在命令行参数中
+	if isEncrypted {
+		setPasswordEnv(cmd, pw)
+	} else {
+		setPasswordEnv(cmd, nil)
+	}
 	output, err := cmd.CombinedOutput()
-	fmt.Printf("恢复命令输出: %s\n", string(output))
+	cmd.Env = nil
+	outputStr := maskPasswords(string(output), pw)
+	fmt.Printf("恢复命令输出: %s\n", outputStr)
 	
 	if err != nil {
-		return fmt.Errorf("恢复失败: %v - %s", err, string(output))
+		return commandError("idevicebackup2", "恢复失败", err, outputStr)
 	}
 
 	return nil
@@ -515,7 +547,7 @@ func GetBackupInfo(backupPath string) (BackupInfo, error) {
 	// 检查Info.plist是否存在
 	if _, err := os.Stat(infoPath); os.IsNotExist(err) {
 		fmt.Printf("Info.plist文件不存在: %s, 错误: %v\n", infoPath, err)
-		return backup, fmt.Errorf("Info.plist文件不存在: %v", err)
+		return backup, ErrBackupCorrupt.WithDetail("Info.plist文件不存在", err)
 	}
 	
 	// 获取备份大小
@@ -0,0 +1,44 @@
+package device
+
+import (
+	"errors"
+	"testing"
+
+	"myitools/internal/testutil"
+)
+
+// fakeChangepw 假的 idevicebackup2 changepw，依次读取三个密码后输出 result 并以 code 退出
+func fakeChangepw(t *testing.T, result string, code string) {
+	t.Helper()
+	testutil.NewFakeTools(t, map[string]string{
+		"idevice_id":  "echo test-udid\n",
+		"ideviceinfo": "echo 'WillEncrypt: true'\n",
+		"idevicebackup2": "printf 'Enter old backup password: '\nread old\n" +
+			"printf '\\nEnter new backup password: '\nread new\n" +
+			"printf '\\nEnter new backup password (repeat): '\nread repeat\n" +
+			"echo\necho '" + result + "'\nexit " + code + "\n",
+	})
+}
+
+func TestChangeBackupPassword(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		code   string
+		want   error
+	}{
+		{"成功", "Please confirm changing the backup password by entering the passcode on the device.", "0", nil},
+		{"设备锁定", "ErrorCode 208: Device locked (MBErrorDomain/208)", "0", ErrPasscodeLocked},
+		{"设备锁定并失败", "ERROR: Device is locked, please unlock it first", "1", ErrPasscodeLocked},
+		{"旧密码错误", "ErrorCode 207: Wrong password (MBErrorDomain/207)", "0", ErrWrongPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeChangepw(t, tt.result, tt.code)
+			err := ChangeBackupPassword("test-udid", "old", "new")
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package device
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BatteryInfo 电池信息
+type BatteryInfo struct {
+	Level                 int     `json:"level"`                   // 当前电量百分比
+	CurrentCapacity       int64   `json:"current_capacity"`        // 当前容量(mAh)
+	MaxCapacity           int64   `json:"max_capacity"`            // 实际最大容量(mAh)
+	DesignCapacity        int64   `json:"design_capacity"`         // 设计容量(mAh)
+	NominalChargeCapacity int64   `json:"nominal_charge_capacity"` // 标称充电容量(mAh)
+	HealthPercent         float64 `json:"health_percent"`          // 电池健康度百分比
+	CycleCount            int64   `json:"cycle_count"`             // 充电次数
+	Temperature           float64 `json:"temperature"`             // 温度(摄氏度)
+	Voltage               int64   `json:"voltage"`                 // 电压(mV)
+	Amperage              int64   `json:"amperage"`                // 电流(mA)，负数表示放电
+	IsCharging            bool    `json:"is_charging"`             // 是否正在充电
+	FullyCharged          bool    `json:"fully_charged"`           // 是否已充满
+	ExternalConnected     bool    `json:"external_connected"`      // 是否连接外部电源
+	ExternalChargeCapable bool    `json:"external_charge_capable"` // 外部电源是否可以充电
+	AdapterName           string  `json:"adapter_name"`            // 充电器名称
+	AdapterManufacturer   string  `json:"adapter_manufacturer"`    // 充电器厂商
+	AdapterWatts          int64   `json:"adapter_watts"`           // 充电器功率(W)
+	AdapterDescription    string  `json:"adapter_description"`     // 充电器描述
+	Serial                string  `json:"serial"`                  // 电池序列号
+	ManufactureDate       string  `json:"manufacture_date"`        // 生产日期
+	Source                string  `json:"source"`                  // 数据来源: ioreg 或 lockdown
+}
+
+// GetBatteryInfo 获取电池信息
+// 优先读取 AppleSmartBattery 的 ioreg 信息，失败时退回到 com.apple.mobile.battery 域。
+func GetBatteryInfo(udid string) (BatteryInfo, error) {
+	registry, err := queryBatteryRegistry(udid)
+	if err == nil {
+		battery := parseBatteryRegistry(registry)
+		battery.Source = "ioreg"
+		return battery, nil
+	}
+	fmt.Printf("读取 AppleSmartBattery 失败，尝试 lockdown 电池域: %v\n", err)
+
+	values, domainErr := queryDomain(udid, "com.apple.mobile.battery")
+	if domainErr != nil {
+		return BatteryInfo{}, domainErr
+	}
+	battery := parseBatteryDomain(values)
+	battery.Source = "lockdown"
+	return battery, nil
+}
+
+// queryBatteryRegistry 读取 AppleSmartBattery 的 ioreg 字典
+func queryBatteryRegistry(udid string) (map[string]any, error) {
+	registry, err := queryIoregEntry(udid, "AppleSmartBattery")
+	if err != nil {
+		return nil, err
+	}
+	if len(registry) == 0 {
+		return nil, fmt.Errorf("电池信息为空")
+	}
+	return registry, nil
+}
+
+// queryIoregEntry 读取指定 ioreg 条目的属性字典
+func queryIoregEntry(udid string, entry string) (map[string]any, error) {
+	cmd := exec.Command("idevicediagnostics", "-u", udid, "ioregentry", entry)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("idevicediagnostics", "读取 "+entry+" 失败", err, stderr.String())
+	}
+
+	// 新版本输出 XML plist，旧版本输出 ioreg 文本格式
+	if strings.Contains(string(output), "<plist") {
+		root, err := parseXMLPlist(output)
+		if err != nil {
+			return nil, err
+		}
+		if registry := plistDict(root, "IORegistry"); registry != nil {
+			return registry, nil
+		}
+		if registry := plistDict(root); registry != nil {
+			return registry, nil
+		}
+		return nil, fmt.Errorf("%s 格式错误", entry)
+	}
+
+	return parseIoregText(string(output)), nil
+}
+
+var ioregLinePattern = regexp.MustCompile(`"([^"]+)"\s*=\s*(.+)`)
+
+// parseIoregText 解析 ioreg 文本格式的键值，例如 "CycleCount" = 177
+func parseIoregText(output string) map[string]any {
+	registry := map[string]any{}
+	for _, line := range strings.Split(output, "\n") {
+		match := ioregLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(match[2])
+		switch {
+		case value == "Yes":
+			registry[match[1]] = true
+		case value == "No":
+			registry[match[1]] = false
+		default:
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				registry[match[1]] = n
+			} else {
+				registry[match[1]] = strings.Trim(value, "\"")
+			}
+		}
+	}
+	return registry
+}
+
+// parseBatteryRegistry 将 AppleSmartBattery 字典转换为 BatteryInfo
+func parseBatteryRegistry(registry map[string]any) BatteryInfo {
+	var battery BatteryInfo
+
+	current, _ := plistInt(registry, "CurrentCapacity")
+	max, _ := plistInt(registry, "MaxCapacity")
+	rawCurrent, hasRawCurrent := plistInt(registry, "AppleRawCurrentCapacity")
+	rawMax, hasRawMax := plistInt(registry, "AppleRawMaxCapacity")
+
+	// 新系统中 CurrentCapacity/MaxCapacity 为百分比，实际毫安时在 AppleRaw* 中
+	if max > 0 {
+		battery.Level = int(current * 100 / max)
+	}
+	if hasRawCurrent {
+		battery.CurrentCapacity = rawCurrent
+	} else {
+		battery.CurrentCapacity = current
+	}
+	if hasRawMax {
+		battery.MaxCapacity = rawMax
+	} else {
+		battery.MaxCapacity = max
+	}
+
+	battery.DesignCapacity, _ = plistInt(registry, "DesignCapacity")
+	battery.NominalChargeCapacity, _ = plistInt(registry, "NominalChargeCapacity")
+	battery.CycleCount, _ = plistInt(registry, "CycleCount")
+	battery.Voltage, _ = plistInt(registry, "Voltage")
+	if amperage, ok := plistInt(registry, "InstantAmperage"); ok {
+		battery.Amperage = amperage
+	} else {
+		battery.Amperage, _ = plistInt(registry, "Amperage")
+	}
+	// 温度单位为 0.01 摄氏度
+	if temperature, ok := plistInt(registry, "Temperature"); ok {
+		battery.Temperature = float64(temperature) / 100
+	}
+
+	// 健康度优先使用标称充电容量计算，与系统设置中的"最大容量"一致
+	full := battery.NominalChargeCapacity
+	if full == 0 {
+		full = battery.MaxCapacity
+	}
+	if battery.DesignCapacity > 0 && full > 0 {
+		battery.HealthPercent = percentOf(full, battery.DesignCapacity)
+		if battery.HealthPercent > 100 {
+			battery.HealthPercent = 100
+		}
+	}
+
+	battery.IsCharging = plistBool(registry, "IsCharging")
+	battery.FullyCharged = plistBool(registry, "FullyCharged")
+	battery.ExternalConnected = plistBool(registry, "ExternalConnected")
+	battery.ExternalChargeCapable = plistBool(registry, "ExternalChargeCapable")
+
+	if adapter := plistDict(registry, "AdapterDetails"); adapter != nil {
+		battery.AdapterName = plistString(adapter, "Name")
+		battery.AdapterManufacturer = plistString(adapter, "Manufacturer")
+		battery.AdapterWatts, _ = plistInt(adapter, "Watts")
+		battery.AdapterDescription = plistString(adapter, "Description")
+	}
+
+	battery.Serial = plistString(registry, "Serial")
+	if battery.Serial == "" {
+		battery.Serial = plistString(registry, "BatterySerialNumber")
+	}
+	battery.ManufactureDate = parseBatteryManufactureDate(registry)
+
+	return battery
+}
+
+// parseBatteryManufactureDate 解析电池生产日期
+// ManufactureDate 为 16 位整数：bit 15-9 为自 1980 年起的年份，bit 8-5 为月，bit 4-0 为日。
+func parseBatteryManufactureDate(registry map[string]any) string {
+	if data := plistDict(registry, "BatteryData"); data != nil {
+		if date := plistString(data, "ManufactureDate"); date != "" {
+			if _, err := strconv.ParseInt(date, 10, 64); err != nil {
+				return date
+			}
+		}
+	}
+
+	code, ok := plistInt(registry, "ManufactureDate")
+	if !ok || code <= 0 || code > 0xFFFF {
+		return ""
+	}
+	year := 1980 + (code >> 9)
+	month := (code >> 5) & 0xF
+	day := code & 0x1F
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
+
+// parseBatteryDomain 将 com.apple.mobile.battery 域的键值转换为 BatteryInfo
+// 该域只提供电量和充电状态
+func parseBatteryDomain(values map[string]string) BatteryInfo {
+	var battery BatteryInfo
+	if level, err := strconv.Atoi(values["BatteryCurrentCapacity"]); err == nil {
+		battery.Level = level
+	}
+	battery.IsCharging = values["BatteryIsCharging"] == "true"
+	battery.FullyCharged = values["FullyCharged"] == "true"
+	battery.ExternalConnected = values["ExternalConnected"] == "true"
+	battery.ExternalChargeCapable = values["ExternalChargeCapable"] == "true"
+	return battery
+}
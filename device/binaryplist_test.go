@@ -0,0 +1,88 @@
+package device
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// plistFixtures testdata 中的 plist 样本，每个 .plist 都有一个由 plistlib 生成的 .bplist 副本
+var plistFixtures = []string{"ideviceinfo", "ideviceinstaller_list", "battery_ioregentry"}
+
+func TestBinaryPlistMatchesXML(t *testing.T) {
+	for _, name := range plistFixtures {
+		xmlRoot := loadPlistFixture(t, name+".plist")
+		binRoot := loadPlistFixture(t, name+".bplist")
+		if !reflect.DeepEqual(xmlRoot, binRoot) {
+			t.Errorf("%s: XML 与二进制解析结果不一致\nxml: %#v\nbin: %#v", name, xmlRoot, binRoot)
+		}
+	}
+}
+
+func TestParseBinaryPlistRejectsMalformed(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "ideviceinfo.bplist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{8, 40, len(data) / 2, len(data) - 1} {
+		if _, err := parseBinaryPlist(data[:n]); err == nil {
+			t.Errorf("截断到 %d 字节的二进制 plist 应返回错误", n)
+		}
+	}
+}
+
+// sharedChainPlist 构造 depth 层数组，每层的两个元素都引用下一层，最后一层为整数 1
+// 不缓存已解析的对象时需要解析 2^depth 次。
+func sharedChainPlist(depth int) []byte {
+	data := []byte("bplist00")
+	offsets := make([]uint64, 0, depth+1)
+	for i := 0; i < depth; i++ {
+		offsets = append(offsets, uint64(len(data)))
+		data = append(data, 0xA2, byte(i+1), byte(i+1))
+	}
+	offsets = append(offsets, uint64(len(data)))
+	data = append(data, 0x10, 0x01)
+
+	tableOffset := uint64(len(data))
+	for _, offset := range offsets {
+		data = binary.BigEndian.AppendUint16(data, uint16(offset))
+	}
+	trailer := make([]byte, 32)
+	trailer[6] = 2
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(offsets)))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	return append(data, trailer...)
+}
+
+func TestParseBinaryPlistSharedObjects(t *testing.T) {
+	done := make(chan struct{})
+	var root any
+	var err error
+	go func() {
+		defer close(done)
+		root, err = parseBinaryPlist(sharedChainPlist(64))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("共享子对象的二进制 plist 解析超时")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for depth := 0; depth < 64; depth++ {
+		array, ok := root.([]any)
+		if !ok || len(array) != 2 {
+			t.Fatalf("第 %d 层: %#v", depth, root)
+		}
+		root = array[1]
+	}
+	if root != int64(1) {
+		t.Errorf("最后一层 = %#v, 期望 1", root)
+	}
+}
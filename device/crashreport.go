@@ -0,0 +1,485 @@
+package device
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 崩溃报告类型
+const (
+	CrashTypeCrash     = "crash"     // 应用崩溃
+	CrashTypeJetsam    = "jetsam"    // 内存不足被系统结束
+	CrashTypePanic     = "panic"     // 内核崩溃
+	CrashTypeStackshot = "stackshot" // 卡死等情况的堆栈快照
+	CrashTypeOther     = "other"
+)
+
+// crashBugTypes .ips 头部 bug_type 与报告类型的对应关系
+var crashBugTypes = map[string]string{
+	"109": CrashTypeCrash,
+	"309": CrashTypeCrash,
+	"298": CrashTypeJetsam,
+	"210": CrashTypePanic,
+	"288": CrashTypeStackshot,
+	"409": CrashTypeStackshot,
+}
+
+// CrashReport 崩溃报告摘要
+type CrashReport struct {
+	Name         string    `json:"name"`          // 文件名
+	Path         string    `json:"path"`          // 本地路径
+	RelativePath string    `json:"relative_path"` // 相对于报告目录的路径
+	Process      string    `json:"process"`       // 进程名
+	BundleID     string    `json:"bundle_id"`     // 应用 Bundle ID
+	Date         time.Time `json:"date"`          // 发生时间
+	Type         string    `json:"type"`          // 报告类型
+	BugType      string    `json:"bug_type"`      // .ips 头部中的 bug_type
+	OSVersion    string    `json:"os_version"`    // 系统版本
+	Size         int64     `json:"size"`          // 文件大小
+}
+
+// IPSReport 解析后的 .ips 崩溃报告
+type IPSReport struct {
+	CrashReport
+	PID           int            `json:"pid"`
+	Version       string         `json:"version"`        // 应用版本
+	Build         string         `json:"build"`          // 应用构建号
+	CPUType       string         `json:"cpu_type"`       // CPU 架构
+	Incident      string         `json:"incident"`       // 事件ID
+	Exception     CrashException `json:"exception"`      // 异常信息
+	Termination   string         `json:"termination"`    // 终止原因
+	CrashedThread int            `json:"crashed_thread"` // 崩溃线程序号，没有时为 -1
+	Threads       []CrashThread  `json:"threads"`
+	BinaryImages  []BinaryImage  `json:"binary_images"`
+	Raw           string         `json:"raw"` // 原始内容，无法解析为 JSON 的报告只有这一项
+}
+
+// CrashException 异常信息
+type CrashException struct {
+	Type    string `json:"type"`    // 例如 EXC_BAD_ACCESS
+	Signal  string `json:"signal"`  // 例如 SIGSEGV
+	Codes   string `json:"codes"`   // 异常代码
+	Subtype string `json:"subtype"` // 例如 KERN_INVALID_ADDRESS at 0x0
+	Message string `json:"message"` // 断言等附加信息
+}
+
+// CrashThread 线程堆栈
+type CrashThread struct {
+	Index     int          `json:"index"`
+	Name      string       `json:"name"`
+	Queue     string       `json:"queue"`
+	Triggered bool         `json:"triggered"` // 是否为崩溃线程
+	Frames    []CrashFrame `json:"frames"`
+}
+
+// CrashFrame 一个堆栈帧
+type CrashFrame struct {
+	ImageName      string `json:"image_name"`      // 所在镜像
+	ImageOffset    uint64 `json:"image_offset"`    // 镜像内偏移
+	Address        string `json:"address"`         // 运行时地址
+	Symbol         string `json:"symbol"`          // 符号，未符号化时为空
+	SymbolLocation uint64 `json:"symbol_location"` // 符号内偏移
+	SourceFile     string `json:"source_file"`
+	SourceLine     int    `json:"source_line"`
+}
+
+// BinaryImage 加载的二进制镜像
+type BinaryImage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	UUID string `json:"uuid"`
+	Base string `json:"base"` // 加载地址
+	Size uint64 `json:"size"`
+	Arch string `json:"arch"`
+}
+
+// crashReportExtensions 视为崩溃报告的文件扩展名
+var crashReportExtensions = map[string]bool{
+	".ips":    true,
+	".crash":  true,
+	".panic":  true,
+	".synced": true,
+}
+
+// crashFileDatePattern 文件名中的时间，例如 MyApp-2024-05-01-101112.ips
+var crashFileDatePattern = regexp.MustCompile(`(\d{4}-\d{2}-\d{2}-\d{6})`)
+
+// PullCrashReports 将设备上的崩溃报告复制到本地目录并返回本地报告列表
+// removeFromDevice 为 true 时复制后从设备上删除。
+func PullCrashReports(udid string, storeDir string, removeFromDevice bool) ([]CrashReport, error) {
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建崩溃报告目录失败: %v", err)
+	}
+	if err := copyCrashReports(udid, storeDir, removeFromDevice); err != nil {
+		return nil, err
+	}
+	fmt.Printf("已复制设备 %s 的崩溃报告到 %s\n", udid, storeDir)
+
+	return ListCrashReports(storeDir)
+}
+
+// ListDeviceCrashReports 列出设备上的崩溃报告，不从设备上删除
+// idevicecrashreport 没有只列出文件的模式，只能复制全部报告；复制下来的报告保存到 storeDir，
+// 之后打开或再次列出时直接使用本地副本，不会被丢弃。返回的报告 Path 为 storeDir 中的路径。
+func ListDeviceCrashReports(udid string, storeDir string) ([]CrashReport, error) {
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建崩溃报告目录失败: %v", err)
+	}
+	// 先复制到同一磁盘上的临时目录，得到设备上当前的报告，再移动到 storeDir
+	tmp, err := os.MkdirTemp(filepath.Dir(storeDir), ".listing-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyCrashReports(udid, tmp, false); err != nil {
+		return nil, err
+	}
+	reports, err := ListCrashReports(tmp)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		dst := filepath.Join(storeDir, reports[i].RelativePath)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, fmt.Errorf("创建崩溃报告目录失败: %v", err)
+		}
+		if err := os.Rename(reports[i].Path, dst); err != nil {
+			return nil, fmt.Errorf("保存崩溃报告失败: %v", err)
+		}
+		reports[i].Path = dst
+	}
+	return reports, nil
+}
+
+// copyCrashReports 通过 crashreportcopymobile 服务将崩溃报告复制到 dir
+func copyCrashReports(udid string, dir string, removeFromDevice bool) error {
+	// idevicecrashreport 默认会从设备上删除已复制的报告，-k 保留
+	args := []string{"-u", udid, "-e"}
+	if !removeFromDevice {
+		args = append(args, "-k")
+	}
+	args = append(args, dir)
+
+	output, err := exec.Command("idevicecrashreport", args...).CombinedOutput()
+	if err != nil {
+		return commandError("idevicecrashreport", "复制崩溃报告失败", err, string(output))
+	}
+	return nil
+}
+
+// ListCrashReports 列出本地目录中的崩溃报告，按时间从新到旧排列
+func ListCrashReports(dir string) ([]CrashReport, error) {
+	reports := []CrashReport{}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return reports, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if !crashReportExtensions[ext] {
+			return nil
+		}
+
+		report := CrashReport{
+			Name:    name,
+			Path:    path,
+			Size:    info.Size(),
+			Date:    info.ModTime(),
+			Process: crashProcessFromName(name),
+		}
+		report.RelativePath, _ = filepath.Rel(dir, path)
+		if match := crashFileDatePattern.FindString(name); match != "" {
+			if t, err := time.ParseInLocation("2006-01-02-150405", match, time.Local); err == nil {
+				report.Date = t
+			}
+		}
+		if ext == ".ips" {
+			if header, err := readIPSHeader(path); err == nil {
+				applyIPSHeader(&report, header)
+			}
+		}
+		if report.Type == "" {
+			report.Type = crashTypeFromName(name)
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取崩溃报告目录失败: %v", err)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Date.After(reports[j].Date)
+	})
+	return reports, nil
+}
+
+// ParseIPSReport 解析 .ips 崩溃报告
+// iOS 15 起 .ips 由一行 JSON 头部和一个 JSON 正文组成；旧格式的文本报告只返回原始内容。
+func ParseIPSReport(path string) (IPSReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return IPSReport{}, fmt.Errorf("读取崩溃报告失败: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return IPSReport{}, fmt.Errorf("读取崩溃报告失败: %v", err)
+	}
+
+	report := IPSReport{
+		CrashReport: CrashReport{
+			Name:    filepath.Base(path),
+			Path:    path,
+			Size:    info.Size(),
+			Date:    info.ModTime(),
+			Process: crashProcessFromName(filepath.Base(path)),
+			Type:    crashTypeFromName(filepath.Base(path)),
+		},
+		CrashedThread: -1,
+		Raw:           string(data),
+	}
+
+	headerLine, body, _ := bytes.Cut(data, []byte("\n"))
+	var header map[string]any
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		// 旧格式文本报告
+		return report, nil
+	}
+	applyIPSHeader(&report.CrashReport, header)
+
+	var content ipsBody
+	if err := json.Unmarshal(body, &content); err != nil {
+		return report, nil
+	}
+	content.apply(&report)
+	return report, nil
+}
+
+// ipsBody .ips 正文中用到的字段
+type ipsBody struct {
+	ProcName   string `json:"procName"`
+	PID        int    `json:"pid"`
+	CPUType    string `json:"cpuType"`
+	Incident   string `json:"incident"`
+	BundleInfo struct {
+		ShortVersion string `json:"CFBundleShortVersionString"`
+		Version      string `json:"CFBundleVersion"`
+		Identifier   string `json:"CFBundleIdentifier"`
+	} `json:"bundleInfo"`
+	OSVersion struct {
+		Train   string `json:"train"`
+		Build   string `json:"build"`
+		Release string `json:"releaseType"`
+	} `json:"osVersion"`
+	Exception struct {
+		Type    string `json:"type"`
+		Signal  string `json:"signal"`
+		Codes   string `json:"codes"`
+		Subtype string `json:"subtype"`
+		Message string `json:"message"`
+	} `json:"exception"`
+	Termination struct {
+		Namespace string      `json:"namespace"`
+		Code      json.Number `json:"code"`
+		Indicator string      `json:"indicator"`
+	} `json:"termination"`
+	ASI            map[string][]string `json:"asi"`
+	FaultingThread *int                `json:"faultingThread"`
+	Threads        []struct {
+		Name      string `json:"name"`
+		Queue     string `json:"queue"`
+		Triggered bool   `json:"triggered"`
+		Frames    []struct {
+			ImageIndex     int    `json:"imageIndex"`
+			ImageOffset    uint64 `json:"imageOffset"`
+			Symbol         string `json:"symbol"`
+			SymbolLocation uint64 `json:"symbolLocation"`
+			SourceFile     string `json:"sourceFile"`
+			SourceLine     int    `json:"sourceLine"`
+		} `json:"frames"`
+	} `json:"threads"`
+	UsedImages []struct {
+		Name   string `json:"name"`
+		Path   string `json:"path"`
+		UUID   string `json:"uuid"`
+		Base   uint64 `json:"base"`
+		Size   uint64 `json:"size"`
+		Arch   string `json:"arch"`
+		Source string `json:"source"`
+	} `json:"usedImages"`
+}
+
+// apply 将正文内容填入报告
+func (b ipsBody) apply(report *IPSReport) {
+	if b.ProcName != "" {
+		report.Process = b.ProcName
+	}
+	report.PID = b.PID
+	report.CPUType = b.CPUType
+	report.Incident = b.Incident
+	report.Version = b.BundleInfo.ShortVersion
+	report.Build = b.BundleInfo.Version
+	if b.BundleInfo.Identifier != "" {
+		report.BundleID = b.BundleInfo.Identifier
+	}
+	if report.OSVersion == "" && b.OSVersion.Train != "" {
+		report.OSVersion = strings.TrimSpace(b.OSVersion.Train + " (" + b.OSVersion.Build + ")")
+	}
+
+	report.Exception = CrashException(b.Exception)
+	if report.Exception.Message == "" && len(b.ASI) > 0 {
+		// 断言失败等信息在 asi 中，按库名排序使结果稳定
+		libraries := make([]string, 0, len(b.ASI))
+		for library := range b.ASI {
+			libraries = append(libraries, library)
+		}
+		sort.Strings(libraries)
+		var messages []string
+		for _, library := range libraries {
+			messages = append(messages, b.ASI[library]...)
+		}
+		report.Exception.Message = strings.Join(messages, "\n")
+	}
+	if b.Termination.Namespace != "" {
+		report.Termination = fmt.Sprintf("Namespace %s, Code %v", b.Termination.Namespace, b.Termination.Code)
+		if b.Termination.Indicator != "" {
+			report.Termination += ", " + b.Termination.Indicator
+		}
+	}
+
+	for _, image := range b.UsedImages {
+		name := image.Name
+		if name == "" {
+			name = filepath.Base(image.Path)
+		}
+		report.BinaryImages = append(report.BinaryImages, BinaryImage{
+			Name: name,
+			Path: image.Path,
+			UUID: image.UUID,
+			Base: "0x" + strconv.FormatUint(image.Base, 16),
+			Size: image.Size,
+			Arch: image.Arch,
+		})
+	}
+
+	if b.FaultingThread != nil {
+		report.CrashedThread = *b.FaultingThread
+	}
+	for i, thread := range b.Threads {
+		t := CrashThread{
+			Index:     i,
+			Name:      thread.Name,
+			Queue:     thread.Queue,
+			Triggered: thread.Triggered || i == report.CrashedThread,
+		}
+		if thread.Triggered && report.CrashedThread == -1 {
+			report.CrashedThread = i
+		}
+		for _, frame := range thread.Frames {
+			f := CrashFrame{
+				ImageOffset:    frame.ImageOffset,
+				Symbol:         frame.Symbol,
+				SymbolLocation: frame.SymbolLocation,
+				SourceFile:     frame.SourceFile,
+				SourceLine:     frame.SourceLine,
+			}
+			if frame.ImageIndex >= 0 && frame.ImageIndex < len(b.UsedImages) {
+				image := b.UsedImages[frame.ImageIndex]
+				f.ImageName = report.BinaryImages[frame.ImageIndex].Name
+				f.Address = "0x" + strconv.FormatUint(image.Base+frame.ImageOffset, 16)
+			}
+			t.Frames = append(t.Frames, f)
+		}
+		report.Threads = append(report.Threads, t)
+	}
+}
+
+// readIPSHeader 读取 .ips 第一行的 JSON 头部
+func readIPSHeader(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	var header map[string]any
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// applyIPSHeader 使用 .ips 头部信息补充报告摘要
+func applyIPSHeader(report *CrashReport, header map[string]any) {
+	if name := plistString(header, "app_name"); name != "" {
+		report.Process = name
+	} else if name := plistString(header, "name"); name != "" {
+		report.Process = name
+	}
+	report.BundleID = plistString(header, "bundleID")
+	report.OSVersion = plistString(header, "os_version")
+
+	if bugType, ok := header["bug_type"]; ok {
+		report.BugType = fmt.Sprint(bugType)
+		if crashType, ok := crashBugTypes[report.BugType]; ok {
+			report.Type = crashType
+		} else {
+			report.Type = CrashTypeOther
+		}
+	}
+
+	// 例如 2024-05-01 10:11:12.00 +0800
+	if timestamp := plistString(header, "timestamp"); timestamp != "" {
+		for _, layout := range []string{"2006-01-02 15:04:05.00 -0700", "2006-01-02 15:04:05 -0700"} {
+			if t, err := time.Parse(layout, timestamp); err == nil {
+				report.Date = t
+				break
+			}
+		}
+	}
+}
+
+// crashTypeFromName 根据文件名判断报告类型
+func crashTypeFromName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(lower, "jetsamevent"):
+		return CrashTypeJetsam
+	case strings.HasPrefix(lower, "panic"):
+		return CrashTypePanic
+	case strings.HasPrefix(lower, "stacks"):
+		return CrashTypeStackshot
+	case strings.HasSuffix(lower, ".crash"), strings.HasSuffix(lower, ".ips"):
+		return CrashTypeCrash
+	}
+	return CrashTypeOther
+}
+
+// crashProcessFromName 从文件名中取出进程名，例如 MyApp-2024-05-01-101112.ips 中的 MyApp
+func crashProcessFromName(name string) string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if loc := crashFileDatePattern.FindStringIndex(base); loc != nil && loc[0] > 0 {
+		return strings.TrimRight(base[:loc[0]], "-_.")
+	}
+	return base
+}
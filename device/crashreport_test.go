@@ -0,0 +1,187 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"myitools/internal/testutil"
+)
+
+func TestParseIPSReport(t *testing.T) {
+	report, err := ParseIPSReport(filepath.Join("testdata", "crashreports", "MyApp-2024-05-01-101112.ips"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := report.CrashReport
+	summary.Path, summary.Size = "", 0
+	wantSummary := CrashReport{
+		Name:      "MyApp-2024-05-01-101112.ips",
+		Process:   "MyApp",
+		BundleID:  "com.example.myapp",
+		Date:      time.Date(2024, 5, 1, 10, 11, 12, 0, time.FixedZone("", 8*3600)),
+		Type:      CrashTypeCrash,
+		BugType:   "309",
+		OSVersion: "iPhone OS 17.4 (21E219)",
+	}
+	if !summary.Date.Equal(wantSummary.Date) {
+		t.Errorf("Date = %v, want %v", summary.Date, wantSummary.Date)
+	}
+	summary.Date = wantSummary.Date
+	if !reflect.DeepEqual(summary, wantSummary) {
+		t.Errorf("摘要:\n got %+v\nwant %+v", summary, wantSummary)
+	}
+
+	if report.PID != 4321 || report.Version != "1.2.3" || report.Build != "45" || report.CPUType != "ARM-64" || report.Incident != "0C7E1F2A-5B3D-4E6F-8A9B-1C2D3E4F5A6B" {
+		t.Errorf("进程信息 = pid %d, version %s (%s), cpu %s, incident %s", report.PID, report.Version, report.Build, report.CPUType, report.Incident)
+	}
+	wantException := CrashException{
+		Type:   "EXC_BREAKPOINT",
+		Signal: "SIGTRAP",
+		Codes:  "0x0000000000000001, 0x00000001a2b3c4d8",
+		// asi 按库名排序后合并
+		Message: "*** Terminating app due to uncaught exception\nMyApp/ViewController.swift:42: Fatal error: Unexpectedly found nil while unwrapping an Optional value",
+	}
+	if report.Exception != wantException {
+		t.Errorf("Exception:\n got %+v\nwant %+v", report.Exception, wantException)
+	}
+	if report.Termination != "Namespace SIGNAL, Code 5, Trace/BPT trap: 5" {
+		t.Errorf("Termination = %q", report.Termination)
+	}
+
+	wantImages := []BinaryImage{
+		{Name: "MyApp", Path: "/private/var/containers/Bundle/Application/5E1B/MyApp.app/MyApp", UUID: "6a1f3c0e-3b7d-3a9e-9b5f-2f7d0c9a1b22", Base: "0x100000000", Size: 65536, Arch: "arm64"},
+		{Name: "libsystem_kernel.dylib", Path: "/usr/lib/system/libsystem_kernel.dylib", UUID: "1b2c3d4e-0000-3000-8000-00000000beef", Base: "0x1c0000000", Size: 32768, Arch: "arm64e"},
+	}
+	if !reflect.DeepEqual(report.BinaryImages, wantImages) {
+		t.Errorf("BinaryImages:\n got %+v\nwant %+v", report.BinaryImages, wantImages)
+	}
+
+	if report.CrashedThread != 1 || len(report.Threads) != 2 {
+		t.Fatalf("CrashedThread = %d, %d 个线程", report.CrashedThread, len(report.Threads))
+	}
+	if main := report.Threads[0]; main.Triggered || main.Queue != "com.apple.main-thread" || main.Frames[0].ImageName != "libsystem_kernel.dylib" || main.Frames[0].Address != "0x1c0001234" {
+		t.Errorf("主线程 = %+v", main)
+	}
+	crashed := report.Threads[1]
+	wantFrames := []CrashFrame{
+		{ImageName: "MyApp", ImageOffset: 16384, Address: "0x100004000", Symbol: "ViewController.load()", SymbolLocation: 128, SourceFile: "ViewController.swift", SourceLine: 42},
+		// 镜像序号越界时不填写镜像和地址
+		{ImageOffset: 99},
+	}
+	if !crashed.Triggered || crashed.Name != "worker" || !reflect.DeepEqual(crashed.Frames, wantFrames) {
+		t.Errorf("崩溃线程:\n got %+v\nwant frames %+v", crashed, wantFrames)
+	}
+}
+
+func TestParseIPSReportASIIsStable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MyApp.ips")
+	header := `{"bug_type":"309","name":"MyApp"}` + "\n"
+	body := `{"asi":{"z.dylib":["last"],"a.dylib":["first","second"],"m.dylib":["middle"]}}`
+	if err := os.WriteFile(path, []byte(header+body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		report, err := ParseIPSReport(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Exception.Message != "first\nsecond\nmiddle\nlast" {
+			t.Fatalf("Message = %q", report.Exception.Message)
+		}
+	}
+}
+
+func TestParseIPSReportLegacyText(t *testing.T) {
+	path := filepath.Join("testdata", "crashreports", "MyApp-2019-01-01-000000.crash")
+	report, err := ParseIPSReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if report.Raw != string(data) || report.Process != "MyApp" || report.Type != CrashTypeCrash || report.CrashedThread != -1 || report.Threads != nil {
+		t.Errorf("旧格式报告 = %+v", report)
+	}
+
+	if _, err := ParseIPSReport(filepath.Join("testdata", "crashreports", "missing.ips")); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
+
+func TestListCrashReports(t *testing.T) {
+	reports, err := ListCrashReports(filepath.Join("testdata", "crashreports"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type summary struct {
+		relativePath, process, crashType, bugType string
+	}
+	var got []summary
+	for _, r := range reports {
+		got = append(got, summary{r.RelativePath, r.Process, r.Type, r.BugType})
+	}
+	// 按时间从新到旧，跳过不是崩溃报告的文件
+	want := []summary{
+		{"MyApp-2024-05-01-101112.ips", "MyApp", CrashTypeCrash, "309"},
+		{filepath.Join("Retired", "JetsamEvent-2024-04-30-080000.ips"), "JetsamEvent", CrashTypeJetsam, "298"},
+		{"MyApp-2019-01-01-000000.crash", "MyApp", CrashTypeCrash, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListCrashReports:\n got %+v\nwant %+v", got, want)
+	}
+
+	if reports, err := ListCrashReports(filepath.Join(t.TempDir(), "missing")); err != nil || len(reports) != 0 {
+		t.Errorf("目录不存在: %v, %v", reports, err)
+	}
+}
+
+func TestListDeviceCrashReportsKeepsLocalCopy(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "crashreports"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 参数为 -u udid -e [-k] DIR
+	tools := testutil.NewFakeTools(t, map[string]string{
+		"idevicecrashreport": "eval dir=\\${$#}\ncp -R '" + fixtures + "'/. \"$dir\"\n",
+	})
+
+	root := t.TempDir()
+	storeDir := filepath.Join(root, "crashreports", "test-udid")
+	reports, err := ListDeviceCrashReports("test-udid", storeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := tools.Calls(t, "idevicecrashreport"); len(calls) != 1 || !strings.HasPrefix(calls[0], "-u test-udid -e -k ") {
+		t.Errorf("idevicecrashreport 调用 = %q", calls)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("报告数 = %d", len(reports))
+	}
+	for _, r := range reports {
+		if r.Path != filepath.Join(storeDir, r.RelativePath) {
+			t.Errorf("%s: Path = %q", r.Name, r.Path)
+		}
+		if _, err := os.Stat(r.Path); err != nil {
+			t.Errorf("本地副本不存在: %v", err)
+		}
+	}
+	if reports[0].BundleID != "com.example.myapp" {
+		t.Errorf("摘要 = %+v", reports[0])
+	}
+	if _, err := ParseIPSReport(reports[0].Path); err != nil {
+		t.Errorf("打开本地副本: %v", err)
+	}
+
+	// 临时目录已清理
+	entries, err := os.ReadDir(filepath.Join(root, "crashreports"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "test-udid" {
+		t.Errorf("报告目录中残留: %v", entries)
+	}
+}
@@ -4,6 +4,13 @@ import (
 	"errors"
 	"os/exec"
 	"strings"
+	"sync"
+)
+
+// 设备状态
+const (
+	DeviceStatusConnected = "connected"  // 已连接并已配对
+	DeviceStatusNotPaired = "not_paired" // 已连接但未配对，需要在设备上点击"信任"
 )
 
 // Device 表示iOS设备
@@ -14,8 +21,13 @@ type Device struct {
 	Status string `json:"status"`
 }
 
+var (
+	attachedMu sync.Mutex
+	attached   = map[string]bool{} // 上次列出设备时已连接的设备
+)
+
 // ListDevices 列出所有已连接的iOS设备
-func ListDevices() []Device {
+func ListDevices() ([]Device, error) {
 	devices := []Device{}
 
 	// 调用 idevice_id -l 命令获取设备列表
@@ -23,7 +35,7 @@ func ListDevices() []Device {
 	output, err := cmd.Output()
 	if err != nil {
 		println("获取设备列表失败:", err.Error())
-		return devices
+		return devices, commandError("idevice_id", "获取设备列表失败", err, "")
 	}
 
 	// 解析命令输出
@@ -32,9 +44,17 @@ func ListDevices() []Device {
 		udid := strings.TrimSpace(line)
 		if udid != "" {
 			// 获取设备名称
-			name := getDeviceName(udid)
+			name, nameErr := getDeviceName(udid)
 			// 获取设备型号
-			model := getDeviceModel(udid)
+			model, modelErr := getDeviceModel(udid)
+
+			// 缺少工具时无法继续
+			if errors.Is(nameErr, &ErrToolMissing{}) {
+				return devices, nameErr
+			}
+			if errors.Is(modelErr, &ErrToolMissing{}) {
+				return devices, modelErr
+			}
 
 			// 添加调试信息
 			println("设备UDID:", udid)
@@ -42,27 +62,51 @@ func ListDevices() []Device {
 			println("设备型号:", model)
 
 			// 检查是否需要配对
-			if name == "未命名设备" && model == "未知型号" {
+			if nameErr != nil && modelErr != nil {
 				pairingStatus := checkPairingStatus(udid)
 				println("设备配对状态:", pairingStatus)
-				
+
 				devices = append(devices, Device{
 					UDID:   udid,
 					Name:   "需要在设备上确认信任",
 					Model:  "请在设备上点击\"信任\"按钮",
-					Status: "需要配对",
+					Status: DeviceStatusNotPaired,
 				})
 			} else {
 				devices = append(devices, Device{
 					UDID:   udid,
 					Name:   name,
-					Model:  model,
-					Status: "connected",
+					Model:  marketingName(model),
+					Status: DeviceStatusConnected,
 				})
 			}
 		}
 	}
-	return devices
+	trackAttachedDevices(devices)
+	return devices, nil
+}
+
+// trackAttachedDevices 与上次列出的设备比较，设备接入或断开时使其应用列表缓存失效
+// 断开期间设备上可能安装或删除了应用，重新接入后需要重新读取。
+func trackAttachedDevices(devices []Device) {
+	current := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		current[d.UDID] = true
+	}
+
+	attachedMu.Lock()
+	defer attachedMu.Unlock()
+	for udid := range current {
+		if !attached[udid] {
+			InvalidateAppCache(udid)
+		}
+	}
+	for udid := range attached {
+		if !current[udid] {
+			InvalidateAppCache(udid)
+		}
+	}
+	attached = current
 }
 
 // checkPairingStatus 检查设备配对状态
@@ -79,41 +123,33 @@ func checkPairingStatus(udid string) string {
 }
 
 // getDeviceName 获取设备名称
-func getDeviceName(udid string) string {
+func getDeviceName(udid string) (string, error) {
 	cmd := exec.Command("ideviceinfo", "-u", udid, "-k", "DeviceName")
 	output, err := cmd.Output()
 	if err != nil {
-		// 如果命令执行失败，返回更详细的错误信息
 		println("获取设备名称失败:", err.Error())
-		if strings.Contains(err.Error(), "executable file not found") {
-			return "需要安装libimobiledevice"
-		}
-		return "未命名设备"
+		return "未命名设备", commandError("ideviceinfo", "获取设备名称失败", err, "")
 	}
 	result := strings.TrimSpace(string(output))
 	if result == "" {
-		return "未命名设备"
+		return "未命名设备", nil
 	}
-	return result
+	return result, nil
 }
 
 // getDeviceModel 获取设备型号
-func getDeviceModel(udid string) string {
+func getDeviceModel(udid string) (string, error) {
 	cmd := exec.Command("ideviceinfo", "-u", udid, "-k", "ProductType")
 	output, err := cmd.Output()
 	if err != nil {
-		// 如果命令执行失败，返回更详细的错误信息
 		println("获取设备型号失败:", err.Error())
-		if strings.Contains(err.Error(), "executable file not found") {
-			return "需要安装libimobiledevice"
-		}
-		return "未知型号"
+		return "未知型号", commandError("ideviceinfo", "获取设备型号失败", err, "")
 	}
 	result := strings.TrimSpace(string(output))
 	if result == "" {
-		return "未知型号"
+		return "未知型号", nil
 	}
-	return result
+	return result, nil
 }
 
 // IsDeviceConnected 检查设备是否已连接
@@ -130,15 +166,17 @@ func IsDeviceConnected(udid string) bool {
 // BackupDevice 备份设备数据
 func BackupDevice(udid string, backupDir string, encrypt bool, password string) error {
 	if !IsDeviceConnected(udid) {
-		return errors.New("设备未连接")
+		return ErrDeviceNotConnected
 	}
 
 	args := []string{"-u", udid, "backup", "--full", backupDir}
-	if encrypt {
-		args = append(args, "--password", password)
-	}
 
 	cmd := exec.Command("idevicebackup2", args...)
+	if encrypt {
+		pw := newSecret(password)
+		defer pw.Wipe()
+		setPasswordEnv(cmd, pw)
+	}
 	_, err := cmd.Output()
 	return err
 }
@@ -146,15 +184,15 @@ func BackupDevice(udid string, backupDir string, encrypt bool, password string)
 // RestoreDevice 恢复设备数据
 func RestoreDevice(udid string, backupDir string, password string) error {
 	if !IsDeviceConnected(udid) {
-		return errors.New("设备未连接")
+		return ErrDeviceNotConnected
 	}
 
 	args := []string{"-u", udid, "restore", "--full", backupDir}
-	if password != "" {
-		args = append(args, "--password", password)
-	}
 
 	cmd := exec.Command("idevicebackup2", args...)
+	pw := newSecret(password)
+	defer pw.Wipe()
+	setPasswordEnv(cmd, pw)
 	_, err := cmd.Output()
 	return err
 }
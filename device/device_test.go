@@ -0,0 +1,50 @@
+package device
+
+import "testing"
+
+func TestTrackAttachedDevicesInvalidatesAppCache(t *testing.T) {
+	cached := func(udid string) bool {
+		appCacheMu.Lock()
+		defer appCacheMu.Unlock()
+		_, ok := appCache[udid]
+		return ok
+	}
+	fill := func(udids ...string) {
+		appCacheMu.Lock()
+		defer appCacheMu.Unlock()
+		for _, udid := range udids {
+			appCache[udid] = appCacheEntry{apps: []AppInfo{}}
+		}
+	}
+	t.Cleanup(func() {
+		attached = map[string]bool{}
+		appCache = map[string]appCacheEntry{}
+	})
+
+	// A 接入
+	fill("A")
+	trackAttachedDevices([]Device{{UDID: "A"}})
+	if cached("A") {
+		t.Error("设备接入后缓存应失效")
+	}
+
+	// A 保持连接，B 接入
+	fill("A", "B")
+	trackAttachedDevices([]Device{{UDID: "A"}, {UDID: "B"}})
+	if !cached("A") {
+		t.Error("保持连接的设备缓存不应失效")
+	}
+	if cached("B") {
+		t.Error("新接入设备的缓存应失效")
+	}
+
+	// A 断开
+	fill("A", "B")
+	trackAttachedDevices([]Device{{UDID: "B"}})
+	if cached("A") {
+		t.Error("设备断开后缓存应失效")
+	}
+	if !cached("B") {
+		t.Error("保持连接的设备缓存不应失效")
+	}
+}
@@ -0,0 +1,273 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// minLibimobiledeviceVersion 要求的 libimobiledevice 最低版本
+const minLibimobiledeviceVersion = "1.3.0"
+
+// libimobiledeviceWindows Windows 下提供 libimobiledevice 工具的安装包
+const libimobiledeviceWindows = "libimobiledevice-win32 (https://github.com/libimobiledevice-win32/imobiledevice-net)"
+
+// toolVersionTimeout 查询工具版本的超时时间
+const toolVersionTimeout = 5 * time.Second
+
+// ToolStatus 外部工具检测结果
+type ToolStatus struct {
+	Name     string `json:"name"`     // 工具名称
+	Purpose  string `json:"purpose"`  // 用途
+	Required bool   `json:"required"` // 是否为必需工具
+	Found    bool   `json:"found"`    // 是否找到
+	Path     string `json:"path"`     // 可执行文件路径
+	Version  string `json:"version"`  // 版本号
+	Outdated bool   `json:"outdated"` // 版本是否过旧
+	Hint     string `json:"hint"`     // 修复建议
+}
+
+// UsbmuxdStatus usbmuxd 服务检测结果
+type UsbmuxdStatus struct {
+	Address   string `json:"address"`    // 连接地址
+	Reachable bool   `json:"reachable"`  // 是否可以连接
+	HasAccess bool   `json:"has_access"` // 当前用户是否有访问权限
+	Error     string `json:"error"`      // 错误信息
+	Hint      string `json:"hint"`       // 修复建议
+}
+
+// DoctorReport 运行环境诊断报告
+type DoctorReport struct {
+	OS      string        `json:"os"`      // 操作系统
+	Tools   []ToolStatus  `json:"tools"`   // 外部工具检测结果
+	Usbmuxd UsbmuxdStatus `json:"usbmuxd"` // usbmuxd 检测结果
+	Healthy bool          `json:"healthy"` // 必需工具和 usbmuxd 是否全部正常
+}
+
+// toolSpec 外部工具说明
+type toolSpec struct {
+	name     string
+	purpose  string
+	required bool
+	// minVersion 非空时使用 --version 查询版本并检查最低版本，各工具有各自的版本号
+	minVersion string
+	// alternatives 可以代替此工具的其他工具，找到任意一个即可
+	alternatives []string
+	// packages 各系统下提供此工具的安装包，为空表示该系统下没有此工具
+	packages map[string]string
+}
+
+// libimobiledevicePackages libimobiledevice 自带工具的安装包
+var libimobiledevicePackages = map[string]string{
+	"darwin":  "libimobiledevice",
+	"linux":   "libimobiledevice-utils",
+	"windows": libimobiledeviceWindows,
+}
+
+// externalTools 应用使用的全部外部工具
+var externalTools = []toolSpec{
+	{name: "idevice_id", purpose: "列出已连接设备", required: true, minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "ideviceinfo", purpose: "读取设备信息", required: true, minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicepair", purpose: "设备配对", required: true, minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicebackup2", purpose: "备份与恢复", required: true, minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicediagnostics", purpose: "电池与诊断信息", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicesyslog", purpose: "实时系统日志", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicecrashreport", purpose: "导出崩溃日志", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "idevicescreenshot", purpose: "屏幕截图", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "ideviceimagemounter", purpose: "挂载开发者磁盘映像", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{name: "afcclient", purpose: "文件管理、应用数据备份与照片导出", minVersion: minLibimobiledeviceVersion, packages: libimobiledevicePackages},
+	{
+		// ideviceinstaller 单独发布，版本号与 libimobiledevice 无关
+		name: "ideviceinstaller", purpose: "应用安装与管理", minVersion: "1.1.1",
+		packages: map[string]string{"darwin": "ideviceinstaller", "linux": "ideviceinstaller", "windows": libimobiledeviceWindows},
+	},
+	{
+		name: "ifuse", purpose: "越狱检测中的 afc2 挂载", minVersion: "1.1.4",
+		packages: map[string]string{"darwin": "gromgit/fuse/ifuse-mac", "linux": "ifuse"},
+	},
+	{
+		name: "ffmpeg", purpose: "视频缩略图",
+		packages: map[string]string{"darwin": "ffmpeg", "linux": "ffmpeg", "windows": "FFmpeg (https://ffmpeg.org/download.html)"},
+	},
+	{
+		name: "heif-convert", purpose: "HEIC 照片转换", alternatives: []string{"sips", "magick"},
+		packages: map[string]string{"darwin": "libheif", "linux": "libheif-examples", "windows": "ImageMagick (https://imagemagick.org)"},
+	},
+	{
+		name: "plutil", purpose: "解析备份中的 plist 文件",
+		packages: map[string]string{"darwin": "系统自带"},
+	},
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// RunDiagnostics 检查外部工具和 usbmuxd 服务，生成诊断报告
+func RunDiagnostics() DoctorReport {
+	report := DoctorReport{
+		OS:      runtime.GOOS,
+		Tools:   []ToolStatus{},
+		Healthy: true,
+	}
+
+	for _, spec := range externalTools {
+		status := checkTool(spec)
+		if spec.required && (!status.Found || status.Outdated) {
+			report.Healthy = false
+		}
+		report.Tools = append(report.Tools, status)
+	}
+
+	report.Usbmuxd = checkUsbmuxd()
+	if !report.Usbmuxd.Reachable || !report.Usbmuxd.HasAccess {
+		report.Healthy = false
+	}
+
+	return report
+}
+
+// checkTool 检查单个外部工具
+func checkTool(spec toolSpec) ToolStatus {
+	status := ToolStatus{
+		Name:     spec.name,
+		Purpose:  spec.purpose,
+		Required: spec.required,
+	}
+
+	var path, name string
+	for _, candidate := range append([]string{spec.name}, spec.alternatives...) {
+		if found, err := exec.LookPath(candidate); err == nil {
+			path, name = found, candidate
+			break
+		}
+	}
+	if path == "" {
+		status.Hint = installHint(spec)
+		return status
+	}
+	status.Found = true
+	status.Path = path
+
+	// 找到的是替代工具时不检查版本
+	if spec.minVersion == "" || name != spec.name {
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolVersionTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if version := versionPattern.FindString(string(output)); err == nil && version != "" {
+		status.Version = version
+		status.Outdated = compareVersions(version, spec.minVersion) < 0
+	} else {
+		// 过旧的版本不支持 --version
+		status.Outdated = true
+	}
+	if status.Outdated {
+		status.Hint = "版本过旧，需要 " + spec.minVersion + " 或更高版本。" + installHint(spec)
+	}
+
+	return status
+}
+
+// installHint 返回当前系统下安装工具的建议
+func installHint(spec toolSpec) string {
+	pkg := spec.packages[runtime.GOOS]
+
+	switch runtime.GOOS {
+	case "darwin":
+		if pkg == "系统自带" {
+			return spec.name + " 是 macOS 系统自带工具，请检查 PATH 设置"
+		}
+		return "运行 brew install " + pkg + " 安装"
+	case "linux":
+		if spec.name == "plutil" {
+			return "Linux 下没有 plutil，备份信息将无法从 Info.plist 读取"
+		}
+		return "运行 sudo apt-get install " + pkg + " 安装（其他发行版请使用对应的包管理器）"
+	case "windows":
+		if spec.name == "plutil" {
+			return "Windows 下没有 plutil，备份信息将无法从 Info.plist 读取"
+		}
+		if pkg == "" {
+			return "Windows 下没有 " + spec.name + "，" + spec.purpose + "功能不可用"
+		}
+		return "请安装 " + pkg + " 并将其目录加入 PATH"
+	}
+	return "请安装 " + spec.name + " 并确保其在 PATH 中"
+}
+
+// ToolInstallHint 返回当前系统下安装指定外部工具的建议
+// 工具不在 externalTools 中时按 libimobiledevice 自带工具处理。
+func ToolInstallHint(name string) string {
+	for _, spec := range externalTools {
+		if spec.name == name {
+			return installHint(spec)
+		}
+		for _, alternative := range spec.alternatives {
+			if alternative == name {
+				return installHint(spec)
+			}
+		}
+	}
+	return installHint(toolSpec{name: name, packages: libimobiledevicePackages})
+}
+
+// usbmuxdAddress 返回 usbmuxd 的连接地址
+// 与 libusbmuxd 一致，优先使用 USBMUXD_SOCKET_ADDRESS 环境变量
+func usbmuxdAddress() (string, string) {
+	if addr := os.Getenv("USBMUXD_SOCKET_ADDRESS"); addr != "" {
+		if strings.HasPrefix(addr, "UNIX:") {
+			return "unix", strings.TrimPrefix(addr, "UNIX:")
+		}
+		return "tcp", addr
+	}
+	if runtime.GOOS == "windows" {
+		return "tcp", "127.0.0.1:27015"
+	}
+	return "unix", "/var/run/usbmuxd"
+}
+
+// checkUsbmuxd 检查 usbmuxd 服务是否可以连接
+func checkUsbmuxd() UsbmuxdStatus {
+	network, address := usbmuxdAddress()
+	status := UsbmuxdStatus{Address: address}
+
+	conn, err := net.DialTimeout(network, address, 2*time.Second)
+	if err == nil {
+		conn.Close()
+		status.Reachable = true
+		status.HasAccess = true
+		return status
+	}
+	status.Error = err.Error()
+
+	if errors.Is(err, os.ErrPermission) {
+		// socket 存在但当前用户没有权限
+		status.Reachable = true
+		switch runtime.GOOS {
+		case "linux":
+			status.Hint = "当前用户无权访问 " + address + "，请将用户加入 plugdev 组或检查 udev 规则后重新登录"
+		default:
+			status.Hint = "当前用户无权访问 " + address + "，请检查文件权限"
+		}
+		return status
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		status.Hint = "usbmuxd 由系统提供，请确认设备已通过 USB 连接，或重启电脑"
+	case "linux":
+		status.Hint = "请安装并启动 usbmuxd: sudo apt-get install usbmuxd && sudo systemctl start usbmuxd"
+	case "windows":
+		status.Hint = "请安装 iTunes 或 Apple Devices 应用以提供 Apple Mobile Device Service"
+	default:
+		status.Hint = "请安装并启动 usbmuxd"
+	}
+	return status
+}
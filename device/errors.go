@@ -0,0 +1,228 @@
+package device
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrorCode 错误码，前端根据错误码判断错误类型并显示本地化的提示
+type ErrorCode string
+
+const (
+	CodeUnknown                  ErrorCode = "UNKNOWN"
+	CodeDeviceNotConnected       ErrorCode = "DEVICE_NOT_CONNECTED"
+	CodeNotPaired                ErrorCode = "NOT_PAIRED"
+	CodePasscodeLocked           ErrorCode = "PASSCODE_LOCKED"
+	CodeToolMissing              ErrorCode = "TOOL_MISSING"
+	CodeWrongPassword            ErrorCode = "WRONG_PASSWORD"
+	CodePasswordRequired         ErrorCode = "PASSWORD_REQUIRED"
+	CodeNoBackupPassword         ErrorCode = "NO_BACKUP_PASSWORD"
+	CodeBackupCorrupt            ErrorCode = "BACKUP_CORRUPT"
+	CodeInsufficientSpace        ErrorCode = "INSUFFICIENT_SPACE"
+	CodeDeveloperImageNotMounted ErrorCode = "DEVELOPER_IMAGE_NOT_MOUNTED"
+	CodeDeveloperModeDisabled    ErrorCode = "DEVELOPER_MODE_DISABLED"
+	CodeAppNotFound              ErrorCode = "APP_NOT_FOUND"
+	CodeAppNotRemovable          ErrorCode = "APP_NOT_REMOVABLE"
+	CodeArchiveNotFound          ErrorCode = "ARCHIVE_NOT_FOUND"
+	CodeAppAccessDenied          ErrorCode = "APP_ACCESS_DENIED"
+	CodeArchiveUnsupported       ErrorCode = "ARCHIVE_UNSUPPORTED"
+)
+
+// Error 设备操作错误
+type Error struct {
+	Code    ErrorCode // 错误码
+	Message string    // 错误描述
+	Detail  string    // 详细信息，例如命令输出
+	Err     error     // 原始错误
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Message + ": " + e.Detail
+	}
+	return e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误码相同即视为同一种错误，便于使用 errors.Is 与预定义错误比较
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail 返回带有详细信息的同类错误
+func (e *Error) WithDetail(detail string, err error) *Error {
+	return &Error{
+		Code:    e.Code,
+		Message: e.Message,
+		Detail:  strings.TrimSpace(detail),
+		Err:     err,
+	}
+}
+
+// 预定义的设备操作错误
+var (
+	ErrDeviceNotConnected       = &Error{Code: CodeDeviceNotConnected, Message: "设备未连接"}
+	ErrNotPaired                = &Error{Code: CodeNotPaired, Message: "设备未配对，请在设备上点击\"信任\"按钮"}
+	ErrPasscodeLocked           = &Error{Code: CodePasscodeLocked, Message: "设备已锁定，请解锁后重试"}
+	ErrWrongPassword            = &Error{Code: CodeWrongPassword, Message: "备份密码错误"}
+	ErrPasswordRequired         = &Error{Code: CodePasswordRequired, Message: "此备份已加密，请提供密码"}
+	ErrNoBackupPassword         = &Error{Code: CodeNoBackupPassword, Message: "设备未设置备份密码"}
+	ErrBackupCorrupt            = &Error{Code: CodeBackupCorrupt, Message: "备份数据损坏或不完整"}
+	ErrInsufficientSpace        = &Error{Code: CodeInsufficientSpace, Message: "存储空间不足"}
+	ErrDeveloperImageNotMounted = &Error{Code: CodeDeveloperImageNotMounted, Message: "设备未挂载开发者磁盘镜像"}
+	ErrDeveloperModeDisabled    = &Error{Code: CodeDeveloperModeDisabled, Message: "设备未开启开发者模式，请在 设置-隐私与安全性 中开启"}
+	ErrAppNotFound              = &Error{Code: CodeAppNotFound, Message: "设备上没有安装此应用"}
+	ErrAppNotRemovable          = &Error{Code: CodeAppNotRemovable, Message: "系统应用不能卸载"}
+	ErrArchiveNotFound          = &Error{Code: CodeArchiveNotFound, Message: "设备上没有此应用的归档"}
+	ErrAppAccessDenied          = &Error{Code: CodeAppAccessDenied, Message: "应用不允许访问其数据，仅支持开启了文件共享的应用"}
+	ErrArchiveUnsupported       = &Error{Code: CodeArchiveUnsupported, Message: "设备系统不支持应用归档"}
+)
+
+// ErrToolMissing 缺少外部命令行工具
+type ErrToolMissing struct {
+	Tool string // 缺少的工具名称
+}
+
+// Error 实现 error 接口，安装建议因工具而异
+func (e *ErrToolMissing) Error() string {
+	return fmt.Sprintf("找不到工具 %s，%s", e.Tool, ToolInstallHint(e.Tool))
+}
+
+// Is 与任意 ErrToolMissing 比较时视为同一种错误
+func (e *ErrToolMissing) Is(target error) bool {
+	_, ok := target.(*ErrToolMissing)
+	return ok
+}
+
+// ErrorPayload 通过 Wails 绑定返回给前端的错误结构
+type ErrorPayload struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Detail  string    `json:"detail,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+}
+
+// FormatError 将错误转换为前端可识别的结构，用作 Wails 的 ErrorFormatter
+func FormatError(err error) any {
+	return toPayload(err)
+}
+
+// ErrorCodeOf 返回错误对应的错误码
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	return toPayload(err).Code
+}
+
+// toPayload 将错误转换为 ErrorPayload
+func toPayload(err error) ErrorPayload {
+	var toolErr *ErrToolMissing
+	if errors.As(err, &toolErr) {
+		return ErrorPayload{
+			Code:    CodeToolMissing,
+			Message: toolErr.Error(),
+			Tool:    toolErr.Tool,
+		}
+	}
+
+	var deviceErr *Error
+	if errors.As(err, &deviceErr) {
+		return ErrorPayload{
+			Code:    deviceErr.Code,
+			Message: deviceErr.Message,
+			Detail:  deviceErr.Detail,
+		}
+	}
+
+	return ErrorPayload{
+		Code:    CodeUnknown,
+		Message: err.Error(),
+	}
+}
+
+// commandError 将外部命令的错误转换为设备操作错误
+// 找不到命令时返回 ErrToolMissing，能从输出中识别的错误返回对应的预定义错误，
+// 否则返回带有描述的普通错误。
+func commandError(tool string, message string, err error, output string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return &ErrToolMissing{Tool: tool}
+	}
+	if known := classifyOutput(output); known != nil {
+		return known.WithDetail(output, err)
+	}
+	if output != "" {
+		return fmt.Errorf("%s: %v - %s", message, err, strings.TrimSpace(output))
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// CommandError 供其他包将外部命令的错误转换为设备操作错误，规则与 commandError 相同
+func CommandError(tool string, message string, err error, output string) error {
+	return commandError(tool, message, err, output)
+}
+
+// classifyOutput 根据 libimobiledevice 工具的输出识别常见错误
+func classifyOutput(output string) *Error {
+	lower := strings.ToLower(output)
+	switch {
+	case lower == "":
+		return nil
+	case strings.Contains(lower, "no device found"),
+		strings.Contains(lower, "device not found"):
+		return ErrDeviceNotConnected
+	case strings.Contains(lower, "pairing dialog"),
+		strings.Contains(lower, "trust dialog"),
+		strings.Contains(lower, "invalid hostid"),
+		strings.Contains(lower, "invalid host id"),
+		strings.Contains(lower, "not paired"):
+		return ErrNotPaired
+	case strings.Contains(lower, "developer mode"),
+		strings.Contains(lower, "developermode"):
+		return ErrDeveloperModeDisabled
+	case strings.Contains(lower, "developer disk image"),
+		strings.Contains(lower, "could not start screenshotr"):
+		return ErrDeveloperImageNotMounted
+	case strings.Contains(lower, "uninstallprohibited"),
+		strings.Contains(lower, "cannot be uninstalled"),
+		strings.Contains(lower, "not removable"):
+		return ErrAppNotRemovable
+	case strings.Contains(lower, "applicationnotfound"),
+		strings.Contains(lower, "could not find application"):
+		return ErrAppNotFound
+	case strings.Contains(lower, "installationlookupfailed"),
+		strings.Contains(lower, "could not start house_arrest"):
+		return ErrAppAccessDenied
+	case strings.Contains(lower, "wrong password"),
+		strings.Contains(lower, "incorrect password"),
+		strings.Contains(lower, "invalid password"):
+		return ErrWrongPassword
+	case strings.Contains(lower, "device is locked"),
+		strings.Contains(lower, "devicelocked"),
+		strings.Contains(lower, "password protected"),
+		strings.Contains(lower, "passwordprotected"),
+		strings.Contains(lower, "please unlock"):
+		return ErrPasscodeLocked
+	case strings.Contains(lower, "encryption is not enabled"),
+		strings.Contains(lower, "no backup password"):
+		return ErrNoBackupPassword
+	case strings.Contains(lower, "no space left"),
+		strings.Contains(lower, "not enough free"),
+		strings.Contains(lower, "insufficient free disk space"):
+		return ErrInsufficientSpace
+	case strings.Contains(lower, "could not read manifest"),
+		strings.Contains(lower, "could not read status.plist"),
+		strings.Contains(lower, "could not read info.plist"),
+		strings.Contains(lower, "no backup found"):
+		return ErrBackupCorrupt
+	}
+	return nil
+}
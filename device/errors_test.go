@@ -0,0 +1,44 @@
+package device
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   *Error
+	}{
+		{"", nil},
+		{"Please confirm changing the backup password by entering the passcode on the device.", nil},
+		{"Backup password successfully changed.", nil},
+		{"ERROR: Could not connect to lockdownd: Password protected (-17)", ErrPasscodeLocked},
+		{"ERROR: Device is locked, please unlock it first", ErrPasscodeLocked},
+		{"ERROR: Wrong password", ErrWrongPassword},
+		{"Not enough free space on the disk", ErrInsufficientSpace},
+		{"ERROR: Could not read Manifest.plist", ErrBackupCorrupt},
+		{"ERROR: No device found!", ErrDeviceNotConnected},
+	}
+	for _, tt := range tests {
+		if got := classifyOutput(tt.output); got != tt.want {
+			t.Errorf("classifyOutput(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestToolMissingHintDependsOnTool(t *testing.T) {
+	heif := (&ErrToolMissing{Tool: "heif-convert"}).Error()
+	if strings.Contains(heif, "libimobiledevice") {
+		t.Errorf("heif-convert 的提示不应要求安装 libimobiledevice: %s", heif)
+	}
+	if !strings.Contains((&ErrToolMissing{Tool: "idevicescreenshot"}).Error(), "libimobiledevice") {
+		t.Error("libimobiledevice 工具的提示应包含 libimobiledevice")
+	}
+	if ToolInstallHint("magick") != ToolInstallHint("heif-convert") {
+		t.Error("替代工具应使用同一条安装建议")
+	}
+	if ToolInstallHint("ffmpeg") == ToolInstallHint("idevice_id") {
+		t.Error("ffmpeg 与 libimobiledevice 工具的安装建议应不同")
+	}
+}
@@ -0,0 +1,257 @@
+package device
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 设备信息导出格式
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatCSV      = "csv"
+	ExportFormatHTML     = "html"
+	ExportFormatMarkdown = "markdown"
+)
+
+// ExportOptions 导出时附加的信息
+type ExportOptions struct {
+	IncludeBattery      bool `json:"include_battery"`
+	IncludeStorage      bool `json:"include_storage"`
+	IncludeVerification bool `json:"include_verification"`
+}
+
+// DeviceReport 导出的设备信息
+type DeviceReport struct {
+	UDID         string              `json:"udid"`
+	GeneratedAt  time.Time           `json:"generated_at"`
+	Info         map[string]string   `json:"info"`
+	Battery      *BatteryInfo        `json:"battery,omitempty"`
+	Storage      *StorageInfo        `json:"storage,omitempty"`
+	Verification *VerificationReport `json:"verification,omitempty"`
+	Errors       []string            `json:"errors,omitempty"` // 附加信息读取失败的原因
+}
+
+// ReportSection 报告中的一节，用于 CSV、HTML 和 Markdown 输出
+type ReportSection struct {
+	Title string
+	Rows  [][2]string
+}
+
+// BuildDeviceReport 收集设备信息生成报告
+// 设备信息读取失败时返回错误，附加信息读取失败只记录在 Errors 中。
+func BuildDeviceReport(udid string, options ExportOptions) (DeviceReport, error) {
+	report := DeviceReport{UDID: udid, GeneratedAt: time.Now()}
+
+	info, err := GetDeviceInfoWithMock(udid)
+	if err != nil {
+		return report, err
+	}
+	report.Info = info
+
+	if options.IncludeBattery {
+		if battery, err := GetBatteryInfoWithMock(udid); err == nil {
+			report.Battery = &battery
+		} else {
+			report.Errors = append(report.Errors, "电池信息: "+err.Error())
+		}
+	}
+	if options.IncludeStorage {
+		if storage, err := GetStorageInfoWithMock(udid); err == nil {
+			report.Storage = &storage
+		} else {
+			report.Errors = append(report.Errors, "存储空间: "+err.Error())
+		}
+	}
+	if options.IncludeVerification {
+		if verification, err := VerifyDeviceWithMock(udid); err == nil {
+			report.Verification = &verification
+		} else {
+			report.Errors = append(report.Errors, "验机报告: "+err.Error())
+		}
+	}
+	return report, nil
+}
+
+// Sections 将报告整理为按节排列的键值
+func (r DeviceReport) Sections() []ReportSection {
+	sections := []ReportSection{{Title: "设备信息", Rows: sortedRows(r.Info)}}
+	if r.Battery != nil {
+		sections = append(sections, ReportSection{Title: "电池", Rows: structRows(r.Battery)})
+	}
+	if r.Storage != nil {
+		sections = append(sections, ReportSection{Title: "存储空间", Rows: structRows(r.Storage)})
+	}
+	if r.Verification != nil {
+		rows := [][2]string{{"结论", r.Verification.Summary}}
+		for _, check := range r.Verification.Checks {
+			rows = append(rows, [2]string{check.Name, checkStatusNames[check.Status]})
+		}
+		for _, id := range r.Verification.Identifiers {
+			rows = append(rows, [2]string{id.Label, id.Value})
+		}
+		sections = append(sections, ReportSection{Title: "验机", Rows: rows})
+	}
+	return sections
+}
+
+// WriteDeviceReport 按指定格式写出报告
+func WriteDeviceReport(w io.Writer, report DeviceReport, format string) error {
+	switch format {
+	case ExportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"section", "key", "value"})
+		for _, section := range report.Sections() {
+			for _, row := range section.Rows {
+				cw.Write([]string{section.Title, row[0], row[1]})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case ExportFormatHTML:
+		return deviceReportTemplate.Execute(w, report)
+	case ExportFormatMarkdown:
+		return writeMarkdownReport(w, report)
+	}
+	return fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// WriteDevicesCSV 将多台设备的信息写为一个 CSV，每台设备一行
+func WriteDevicesCSV(w io.Writer, reports []DeviceReport) error {
+	columnSet := map[string]bool{}
+	rows := make([]map[string]string, len(reports))
+	for i, report := range reports {
+		row := map[string]string{}
+		for _, section := range report.Sections() {
+			prefix := ""
+			if section.Title != "设备信息" {
+				prefix = section.Title + "."
+			}
+			for _, kv := range section.Rows {
+				row[prefix+kv[0]] = kv[1]
+				columnSet[prefix+kv[0]] = true
+			}
+		}
+		row["UDID"] = report.UDID
+		rows[i] = row
+	}
+	delete(columnSet, "UDID")
+
+	columns := make([]string, 0, len(columnSet)+1)
+	for column := range columnSet {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	columns = append([]string{"UDID"}, columns...)
+
+	cw := csv.NewWriter(w)
+	cw.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = row[column]
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdownReport 写出 Markdown 格式的报告
+func writeMarkdownReport(w io.Writer, report DeviceReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 设备信息 - %s\n\n", markdownEscape(report.Info["设备名称"]))
+	fmt.Fprintf(&b, "- UDID: `%s`\n- 生成时间: %s\n", report.UDID, report.GeneratedAt.Format("2006-01-02 15:04:05"))
+	for _, section := range report.Sections() {
+		fmt.Fprintf(&b, "\n## %s\n\n| 项目 | 值 |\n| --- | --- |\n", section.Title)
+		for _, row := range section.Rows {
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownEscape(row[0]), markdownEscape(row[1]))
+		}
+	}
+	if len(report.Errors) > 0 {
+		b.WriteString("\n## 读取失败\n\n")
+		for _, e := range report.Errors {
+			fmt.Fprintf(&b, "- %s\n", markdownEscape(e))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape 转义表格中的反斜杠、竖线和换行，设备名称等内容来自设备，不能破坏表格结构
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+var markdownReplacer = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r\n", " ", "\r", " ", "\n", " ")
+
+// sortedRows 将键值按键排序
+func sortedRows(values map[string]string) [][2]string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := make([][2]string, len(keys))
+	for i, k := range keys {
+		rows[i] = [2]string{k, values[k]}
+	}
+	return rows
+}
+
+// structRows 将结构体按 JSON 字段展开为键值
+func structRows(v any) [][2]string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	// 使用 json.Number 避免大整数被输出为科学计数法
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	values := make(map[string]string, len(fields))
+	for k, field := range fields {
+		values[k] = fmt.Sprint(field)
+	}
+	return sortedRows(values)
+}
+
+var deviceReportTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>设备信息 - {{index .Info "设备名称"}}</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; margin: 32px; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border: 1px solid #ddd; padding: 6px 10px; text-align: left; font-size: 13px; }
+th { background: #f6f8fa; width: 30%; }
+.errors { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{index .Info "设备名称"}}</h1>
+<div>UDID: {{.UDID}}</div>
+<div>生成时间: {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</div>
+{{range .Sections}}<h2>{{.Title}}</h2>
+<table>
+{{range .Rows}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Errors}}<h2>读取失败</h2>
+<ul class="errors">{{range .Errors}}<li>{{.}}</li>{{end}}</ul>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,192 @@
+package device
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostileName 设备名称由用户在设备上设置，可能包含 HTML、Markdown 和 CSV 的特殊字符
+const hostileName = "<script>alert(\"x\")</script> | a,b\\\nnext \"row\""
+
+func testDeviceReport() DeviceReport {
+	return DeviceReport{
+		UDID:        "00008101-000A1B2C3D4E001E",
+		GeneratedAt: time.Date(2024, 6, 10, 8, 30, 0, 0, time.UTC),
+		Info: map[string]string{
+			"设备名称": hostileName,
+			"型号":   "iPhone 13",
+			"序列号":  "F2LXXXXXXX",
+		},
+		Battery: &BatteryInfo{Level: 79, CycleCount: 412, AdapterName: "20W <USB-C>"},
+		Errors:  []string{"存储空间: ERROR | timeout"},
+	}
+}
+
+func writeReport(t *testing.T, report DeviceReport, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteDeviceReport(&buf, report, format); err != nil {
+		t.Fatalf("WriteDeviceReport(%s): %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestWriteDeviceReportJSON(t *testing.T) {
+	report := testDeviceReport()
+	var decoded DeviceReport
+	if err := json.Unmarshal([]byte(writeReport(t, report, ExportFormatJSON)), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, report) {
+		t.Errorf("JSON 往返结果不一致:\n got %+v\nwant %+v", decoded, report)
+	}
+}
+
+func TestWriteDeviceReportCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(writeReport(t, testDeviceReport(), ExportFormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records[0], []string{"section", "key", "value"}) {
+		t.Errorf("表头 = %q", records[0])
+	}
+	want := map[[2]string]string{
+		{"设备信息", "设备名称"}:       hostileName,
+		{"设备信息", "型号"}:         "iPhone 13",
+		{"电池", "level"}:        "79",
+		{"电池", "cycle_count"}:  "412",
+		{"电池", "adapter_name"}: "20W <USB-C>",
+	}
+	for _, record := range records[1:] {
+		if len(record) != 3 {
+			t.Fatalf("列数 = %d: %q", len(record), record)
+		}
+		key := [2]string{record[0], record[1]}
+		if value, ok := want[key]; ok {
+			if record[2] != value {
+				t.Errorf("%v = %q, want %q", key, record[2], value)
+			}
+			delete(want, key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("缺少的行: %v", want)
+	}
+}
+
+func TestWriteDeviceReportHTMLEscapes(t *testing.T) {
+	html := writeReport(t, testDeviceReport(), ExportFormatHTML)
+	for _, raw := range []string{"<script>", "<USB-C>"} {
+		if strings.Contains(html, raw) {
+			t.Errorf("HTML 中包含未转义的 %q", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;", "20W &lt;USB-C&gt;", "ERROR | timeout"} {
+		if !strings.Contains(html, escaped) {
+			t.Errorf("HTML 中缺少 %q", escaped)
+		}
+	}
+	if !strings.Contains(html, "<title>设备信息 - &lt;script&gt;") {
+		t.Error("标题中的设备名称未转义")
+	}
+}
+
+func TestWriteDeviceReportMarkdownEscapes(t *testing.T) {
+	markdown := writeReport(t, testDeviceReport(), ExportFormatMarkdown)
+	lines := strings.Split(markdown, "\n")
+
+	if !strings.HasPrefix(lines[0], "# 设备信息 - <script>") || !strings.Contains(lines[0], `a,b\\ next`) {
+		t.Errorf("标题 = %q", lines[0])
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		// 每行表格只能有 3 个未转义的竖线
+		unescaped := strings.Count(strings.ReplaceAll(line, `\\`, ""), "|") - strings.Count(strings.ReplaceAll(line, `\\`, ""), `\|`)
+		if unescaped != 3 {
+			t.Errorf("表格行被破坏: %q", line)
+		}
+	}
+	if !strings.Contains(markdown, "| 设备名称 | <script>alert(\"x\")</script> \\| a,b\\\\ next \"row\" |\n") {
+		t.Errorf("设备名称行:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "- 存储空间: ERROR \\| timeout\n") {
+		t.Errorf("读取失败列表:\n%s", markdown)
+	}
+}
+
+func TestWriteDeviceReportUnsupportedFormat(t *testing.T) {
+	if err := WriteDeviceReport(&bytes.Buffer{}, testDeviceReport(), "xml"); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+}
+
+func TestWriteDevicesCSVAlignsColumns(t *testing.T) {
+	first := testDeviceReport()
+	second := DeviceReport{
+		UDID: "second-udid",
+		Info: map[string]string{"设备名称": "iPad, \"Pro\"", "iOS版本": "17.4"},
+	}
+	var buf bytes.Buffer
+	if err := WriteDevicesCSV(&buf, []DeviceReport{first, second}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("行数 = %d", len(records))
+	}
+
+	header := records[0]
+	if header[0] != "UDID" {
+		t.Errorf("第一列 = %q", header[0])
+	}
+	for i := 2; i < len(header); i++ {
+		if header[i-1] >= header[i] {
+			t.Errorf("列未排序: %q", header)
+		}
+	}
+	index := map[string]int{}
+	for i, column := range header {
+		index[column] = i
+	}
+	for _, column := range []string{"iOS版本", "型号", "设备名称", "电池.level", "电池.adapter_name"} {
+		if _, ok := index[column]; !ok {
+			t.Errorf("缺少列 %q: %q", column, header)
+		}
+	}
+
+	for _, record := range records[1:] {
+		if len(record) != len(header) {
+			t.Errorf("列数 = %d, 表头 %d 列", len(record), len(header))
+		}
+	}
+	checks := []struct {
+		row    int
+		column string
+		want   string
+	}{
+		{1, "UDID", first.UDID},
+		{1, "设备名称", hostileName},
+		{1, "iOS版本", ""},
+		{1, "电池.level", "79"},
+		{2, "UDID", "second-udid"},
+		{2, "设备名称", "iPad, \"Pro\""},
+		{2, "iOS版本", "17.4"},
+		{2, "型号", ""},
+		{2, "电池.level", ""},
+	}
+	for _, c := range checks {
+		if got := records[c.row][index[c.column]]; got != c.want {
+			t.Errorf("第 %d 行 %s = %q, want %q", c.row, c.column, got, c.want)
+		}
+	}
+}
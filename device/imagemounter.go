@@ -0,0 +1,419 @@
+package device
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 镜像类型
+const (
+	ImageTypeDeveloper    = "Developer"    // iOS 16 及以前的开发者磁盘镜像
+	ImageTypePersonalized = "Personalized" // iOS 17 起的个性化镜像
+)
+
+// mountTimeout 挂载镜像的超时时间，个性化镜像需要联网签名
+const mountTimeout = 3 * time.Minute
+
+// MountedImage 设备上已挂载的镜像
+type MountedImage struct {
+	Type      string `json:"type"`      // 镜像类型，旧版本系统不返回时为空
+	Signature string `json:"signature"` // 签名的前 16 个字节（十六进制）
+}
+
+// ImageMountStatus 镜像挂载状态
+type ImageMountStatus struct {
+	Mounted       bool           `json:"mounted"`        // 是否已挂载开发者镜像
+	Images        []MountedImage `json:"images"`         // 已挂载的镜像
+	DeveloperMode bool           `json:"developer_mode"` // 是否已开启开发者模式，iOS 16 以前始终为 true
+	Personalized  bool           `json:"personalized"`   // 设备是否需要个性化镜像 (iOS 17+)
+	IOSVersion    string         `json:"ios_version"`
+}
+
+// PersonalizedImage 个性化镜像文件，对应 Xcode 中 DeveloperDiskImages/iOS_DDI/Restore 目录
+// 目录中包含 BuildManifest.plist、镜像 .dmg 和 Firmware/ 下的信任缓存，文件名以 BuildManifest 为准。
+type PersonalizedImage struct {
+	Dir               string `json:"dir"`                 // 镜像目录
+	ImagePath         string `json:"image_path"`          // 镜像 .dmg
+	TrustCachePath    string `json:"trust_cache_path"`    // Firmware/ 下的 .trustcache
+	BuildManifestPath string `json:"build_manifest_path"` // BuildManifest.plist
+}
+
+// PersonalizationRequest 个性化签名请求所需的信息
+type PersonalizationRequest struct {
+	UDID     string            `json:"udid"`
+	ECID     uint64            `json:"ecid"`
+	ChipID   uint64            `json:"chip_id"`
+	BoardID  uint64            `json:"board_id"`
+	Image    PersonalizedImage `json:"image"`
+	Digests  map[string][]byte `json:"digests"`  // BuildManifest 中与设备匹配的组件摘要
+	Identity map[string]any    `json:"identity"` // 与设备匹配的 BuildIdentity
+}
+
+// Signer 完成个性化镜像的签名
+// 签名需要根据 Digests 和 Identity 向苹果签名服务器请求，通过接口隔离这一步，测试时可以使用本地实现。
+// 返回的签名清单 (IM4M) 由 Mounter 随镜像一起上传到设备。
+type Signer interface {
+	Sign(ctx context.Context, req PersonalizationRequest) ([]byte, error)
+}
+
+// Mounter 将个性化镜像上传并挂载到设备
+// manifest 为 Signer 返回的签名清单，为 nil 时由 Mounter 自行完成签名。
+type Mounter interface {
+	Mount(ctx context.Context, req PersonalizationRequest, manifest []byte) error
+}
+
+// ImageMounterTool 默认的挂载实现，调用 ideviceimagemounter
+// iOS 17 起 ideviceimagemounter 接收镜像目录，自行向苹果服务器请求签名后上传挂载，
+// 命令行不支持传入外部签名的清单，使用自定义 Signer 时需要同时提供能上传清单的 Mounter。
+type ImageMounterTool struct{}
+
+// Mount 调用 ideviceimagemounter 挂载镜像目录
+func (ImageMounterTool) Mount(ctx context.Context, req PersonalizationRequest, manifest []byte) error {
+	if manifest != nil {
+		return errors.New("ideviceimagemounter 不支持上传外部签名的清单")
+	}
+	args := []string{"-u", req.UDID, "-t", ImageTypePersonalized, "mount", req.Image.Dir}
+	output, err := exec.CommandContext(ctx, "ideviceimagemounter", args...).CombinedOutput()
+	if err != nil {
+		return commandError("ideviceimagemounter", "挂载个性化镜像失败", err, string(output))
+	}
+	return nil
+}
+
+// GetImageMountStatus 查询镜像挂载状态和开发者模式
+func GetImageMountStatus(udid string) (ImageMountStatus, error) {
+	status := ImageMountStatus{Images: []MountedImage{}, DeveloperMode: true}
+
+	if !IsDeviceConnected(udid) {
+		return status, ErrDeviceNotConnected
+	}
+	version, err := queryDeviceValue(udid, "ProductVersion")
+	if err != nil {
+		return status, err
+	}
+	if version == "" {
+		return status, fmt.Errorf("无法读取系统版本")
+	}
+	status.IOSVersion = version
+	status.Personalized = compareVersions(version, "17.0") >= 0
+
+	if compareVersions(version, "16.0") >= 0 {
+		enabled, err := developerModeEnabled(udid)
+		if err != nil {
+			return status, err
+		}
+		status.DeveloperMode = enabled
+	}
+
+	images, err := listMountedImages(udid)
+	if err != nil {
+		return status, err
+	}
+	status.Images = images
+	status.Mounted = len(images) > 0
+	return status, nil
+}
+
+// MountImage 根据系统版本挂载开发者磁盘镜像或个性化镜像，只查询一次挂载状态
+// signer 和 mounter 的含义与 MountPersonalizedImage 相同。
+func MountImage(ctx context.Context, udid string, dir string, signer Signer, mounter Mounter) error {
+	status, err := GetImageMountStatus(udid)
+	if err != nil {
+		return err
+	}
+	if status.Personalized {
+		return mountPersonalizedImage(ctx, udid, dir, status, signer, mounter)
+	}
+	return mountDeveloperImage(udid, dir, status)
+}
+
+// MountDeveloperImage 挂载 iOS 16 及以前的开发者磁盘镜像
+// dir 为 Xcode DeviceSupport 中对应系统版本的目录，包含 DeveloperDiskImage.dmg 和 .signature 文件。
+func MountDeveloperImage(udid string, dir string) error {
+	status, err := GetImageMountStatus(udid)
+	if err != nil {
+		return err
+	}
+	return mountDeveloperImage(udid, dir, status)
+}
+
+// mountDeveloperImage 按已查询的挂载状态挂载开发者磁盘镜像
+func mountDeveloperImage(udid string, dir string, status ImageMountStatus) error {
+	if status.Personalized {
+		return fmt.Errorf("iOS %s 需要使用个性化镜像", status.IOSVersion)
+	}
+	if !status.DeveloperMode {
+		return ErrDeveloperModeDisabled
+	}
+	if status.Mounted {
+		return nil
+	}
+
+	image := filepath.Join(dir, "DeveloperDiskImage.dmg")
+	signature := image + ".signature"
+	for _, path := range []string{image, signature} {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("找不到镜像文件: %s", path)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mountTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "ideviceimagemounter", "-u", udid, "-t", ImageTypeDeveloper, "mount", image, signature).CombinedOutput()
+	if err != nil {
+		return commandError("ideviceimagemounter", "挂载开发者镜像失败", err, string(output))
+	}
+	fmt.Printf("设备 %s 已挂载开发者镜像 %s\n", udid, image)
+	return nil
+}
+
+// MountPersonalizedImage 挂载 iOS 17 起的个性化镜像
+// 先检查开发者模式并根据设备信息在 BuildManifest 中找到匹配的组件，再由 signer 签名、mounter 上传挂载。
+// signer 为 nil 时不单独签名，由 mounter 自行完成；mounter 为 nil 时使用 ImageMounterTool。
+func MountPersonalizedImage(ctx context.Context, udid string, dir string, signer Signer, mounter Mounter) error {
+	status, err := GetImageMountStatus(udid)
+	if err != nil {
+		return err
+	}
+	return mountPersonalizedImage(ctx, udid, dir, status, signer, mounter)
+}
+
+// mountPersonalizedImage 按已查询的挂载状态挂载个性化镜像
+func mountPersonalizedImage(ctx context.Context, udid string, dir string, status ImageMountStatus, signer Signer, mounter Mounter) error {
+	if !status.Personalized {
+		return fmt.Errorf("iOS %s 需要使用开发者磁盘镜像", status.IOSVersion)
+	}
+	if !status.DeveloperMode {
+		return ErrDeveloperModeDisabled
+	}
+	if status.Mounted {
+		return nil
+	}
+
+	identifiers := map[string]string{}
+	for _, key := range []string{"UniqueChipID", "ChipID", "BoardId"} {
+		identifiers[key] = getDeviceValue(udid, key)
+	}
+	req, err := BuildPersonalizationRequest(udid, identifiers, FindPersonalizedImage(dir))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mountTimeout)
+	defer cancel()
+	if err := personalizeAndMount(ctx, req, signer, mounter); err != nil {
+		return err
+	}
+	fmt.Printf("设备 %s 已挂载个性化镜像\n", udid)
+	return nil
+}
+
+// personalizeAndMount 签名并挂载镜像
+func personalizeAndMount(ctx context.Context, req PersonalizationRequest, signer Signer, mounter Mounter) error {
+	if mounter == nil {
+		mounter = ImageMounterTool{}
+	}
+
+	var manifest []byte
+	if signer != nil {
+		var err error
+		if manifest, err = signer.Sign(ctx, req); err != nil {
+			return fmt.Errorf("个性化签名失败: %w", err)
+		}
+		if len(manifest) == 0 {
+			return errors.New("个性化签名失败: 签名清单为空")
+		}
+	}
+	return mounter.Mount(ctx, req, manifest)
+}
+
+// FindPersonalizedImage 返回目录中个性化镜像各文件的路径
+// 镜像和信任缓存的实际路径在 BuildPersonalizationRequest 中按 BuildManifest 确定，这里按 Xcode 的目录结构预先查找。
+func FindPersonalizedImage(dir string) PersonalizedImage {
+	image := PersonalizedImage{
+		Dir:               dir,
+		BuildManifestPath: filepath.Join(dir, "BuildManifest.plist"),
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.dmg")); len(matches) > 0 {
+		image.ImagePath = matches[0]
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "Firmware", "*.trustcache")); len(matches) > 0 {
+		image.TrustCachePath = matches[0]
+	}
+	return image
+}
+
+// BuildPersonalizationRequest 根据设备信息和镜像文件生成签名请求
+// identifiers 为 lockdown 返回的 ChipID、BoardId、UniqueChipID 等信息。
+func BuildPersonalizationRequest(udid string, identifiers map[string]string, image PersonalizedImage) (PersonalizationRequest, error) {
+	req := PersonalizationRequest{UDID: udid, Image: image, Digests: map[string][]byte{}}
+
+	var err error
+	if req.ECID, err = strconv.ParseUint(identifiers["UniqueChipID"], 10, 64); err != nil {
+		return req, fmt.Errorf("无法读取设备 ECID")
+	}
+	if req.ChipID, err = strconv.ParseUint(identifiers["ChipID"], 10, 64); err != nil {
+		return req, fmt.Errorf("无法读取设备 ChipID")
+	}
+	if req.BoardID, err = strconv.ParseUint(identifiers["BoardId"], 10, 64); err != nil {
+		return req, fmt.Errorf("无法读取设备 BoardId")
+	}
+
+	data, err := os.ReadFile(image.BuildManifestPath)
+	if err != nil {
+		return req, fmt.Errorf("读取 BuildManifest 失败: %v", err)
+	}
+	root, err := parseXMLPlist(data)
+	if err != nil {
+		return req, err
+	}
+	identity, err := matchBuildIdentity(root, req.ChipID, req.BoardID)
+	if err != nil {
+		return req, err
+	}
+	req.Identity = identity
+
+	// 个性化签名需要镜像和信任缓存两个组件，文件路径以 BuildManifest 中记录的相对路径为准
+	paths := map[string]*string{
+		"PersonalizedDMG":    &req.Image.ImagePath,
+		"LoadableTrustCache": &req.Image.TrustCachePath,
+	}
+	for _, component := range []string{"PersonalizedDMG", "LoadableTrustCache"} {
+		entry := plistDict(identity, "Manifest", component)
+		digest, ok := entry["Digest"].([]byte)
+		if !ok {
+			return req, fmt.Errorf("BuildManifest 中缺少 %s", component)
+		}
+		req.Digests[component] = digest
+		if path := plistString(plistDict(entry, "Info"), "Path"); path != "" {
+			*paths[component] = filepath.Join(image.Dir, filepath.FromSlash(path))
+		}
+	}
+
+	for _, path := range []string{req.Image.ImagePath, req.Image.TrustCachePath} {
+		if path == "" {
+			return req, fmt.Errorf("在 %s 中找不到镜像文件", image.Dir)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return req, fmt.Errorf("找不到镜像文件: %s", path)
+		}
+	}
+	return req, nil
+}
+
+// matchBuildIdentity 找到与设备芯片和主板匹配的 BuildIdentity
+func matchBuildIdentity(manifest any, chipID uint64, boardID uint64) (map[string]any, error) {
+	root, ok := manifest.(map[string]any)
+	if !ok {
+		return nil, errors.New("BuildManifest 格式错误")
+	}
+	identities, _ := root["BuildIdentities"].([]any)
+	for _, item := range identities {
+		identity, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		chip, errChip := strconv.ParseUint(strings.TrimPrefix(plistString(identity, "ApChipID"), "0x"), 16, 64)
+		board, errBoard := strconv.ParseUint(strings.TrimPrefix(plistString(identity, "ApBoardID"), "0x"), 16, 64)
+		if errChip == nil && errBoard == nil && chip == chipID && board == boardID {
+			return identity, nil
+		}
+	}
+	return nil, fmt.Errorf("镜像不支持该设备 (ChipID 0x%X, BoardID 0x%X)", chipID, boardID)
+}
+
+// UnmountImage 卸载镜像，mountPath 为空时卸载开发者镜像默认的 /Developer
+func UnmountImage(udid string, mountPath string) error {
+	if mountPath == "" {
+		mountPath = "/Developer"
+	}
+	output, err := exec.Command("ideviceimagemounter", "-u", udid, "unmount", mountPath).CombinedOutput()
+	if err != nil {
+		return commandError("ideviceimagemounter", "卸载镜像失败", err, string(output))
+	}
+	return nil
+}
+
+// UnmountDeveloperImage 卸载开发者镜像，根据系统版本选择挂载点
+// iOS 17 起个性化镜像挂载在 /System/Developer，以前的版本挂载在 /Developer。
+func UnmountDeveloperImage(udid string) error {
+	version, err := queryDeviceValue(udid, "ProductVersion")
+	if err != nil {
+		return err
+	}
+	if compareVersions(version, "17.0") >= 0 {
+		return UnmountImage(udid, "/System/Developer")
+	}
+	return UnmountImage(udid, "")
+}
+
+// developerModeEnabled 查询开发者模式是否开启 (iOS 16+)
+func developerModeEnabled(udid string) (bool, error) {
+	cmd := exec.Command("ideviceinfo", "-u", udid, "-q", "com.apple.security.mac.amfi", "-k", "DeveloperModeStatus")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return false, commandError("ideviceinfo", "查询开发者模式失败", err, stderr.String())
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// listMountedImages 查询已挂载的镜像
+func listMountedImages(udid string) ([]MountedImage, error) {
+	cmd := exec.Command("ideviceimagemounter", "-u", udid, "-x", "list")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("ideviceimagemounter", "查询已挂载镜像失败", err, stderr.String())
+	}
+	root, err := parseXMLPlist(output)
+	if err != nil {
+		return nil, err
+	}
+	return parseMountedImages(plistDict(root)), nil
+}
+
+// parseMountedImages 解析 LookupImage 的返回结果
+// iOS 17 起返回 EntryList，以前的版本只返回 ImageSignature 列表。
+func parseMountedImages(result map[string]any) []MountedImage {
+	images := []MountedImage{}
+	if entries, ok := result["EntryList"].([]any); ok {
+		for _, item := range entries {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			signature, _ := entry["ImageSignature"].([]byte)
+			images = append(images, MountedImage{
+				Type:      plistString(entry, "ImageType"),
+				Signature: signaturePrefix(signature),
+			})
+		}
+		return images
+	}
+
+	signatures, _ := result["ImageSignature"].([]any)
+	for _, item := range signatures {
+		signature, _ := item.([]byte)
+		images = append(images, MountedImage{Type: ImageTypeDeveloper, Signature: signaturePrefix(signature)})
+	}
+	return images
+}
+
+// signaturePrefix 签名前 16 个字节的十六进制表示
+func signaturePrefix(signature []byte) string {
+	if len(signature) > 16 {
+		signature = signature[:16]
+	}
+	return hex.EncodeToString(signature)
+}
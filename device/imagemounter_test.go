@@ -0,0 +1,218 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"myitools/internal/testutil"
+)
+
+const testBuildManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>BuildIdentities</key>
+	<array>
+		<dict>
+			<key>ApBoardID</key>
+			<string>0x08</string>
+			<key>ApChipID</key>
+			<string>0x8020</string>
+			<key>Manifest</key>
+			<dict/>
+		</dict>
+		<dict>
+			<key>ApBoardID</key>
+			<string>0x0C</string>
+			<key>ApChipID</key>
+			<string>0x8110</string>
+			<key>Manifest</key>
+			<dict>
+				<key>LoadableTrustCache</key>
+				<dict>
+					<key>Digest</key>
+					<data>dHJ1c3RjYWNoZQ==</data>
+					<key>Info</key>
+					<dict>
+						<key>Path</key>
+						<string>Firmware/018-12345-001.dmg.trustcache</string>
+					</dict>
+				</dict>
+				<key>PersonalizedDMG</key>
+				<dict>
+					<key>Digest</key>
+					<data>aW1hZ2U=</data>
+					<key>Info</key>
+					<dict>
+						<key>Path</key>
+						<string>018-12345-001.dmg</string>
+					</dict>
+				</dict>
+			</dict>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+
+// writeDDIRestore 按 Xcode DeveloperDiskImages/iOS_DDI/Restore 的结构生成镜像目录
+func writeDDIRestore(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"BuildManifest.plist":                   testBuildManifest,
+		"018-12345-001.dmg":                     "image",
+		"Firmware/018-12345-001.dmg.trustcache": "trustcache",
+		"Firmware/018-12345-001.dmg.root_hash":  "hash",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var testIdentifiers = map[string]string{
+	"UniqueChipID": "1234567890",
+	"ChipID":       "33040", // 0x8110
+	"BoardId":      "12",
+}
+
+func TestBuildPersonalizationRequest(t *testing.T) {
+	dir := writeDDIRestore(t)
+	req, err := BuildPersonalizationRequest("udid", testIdentifiers, FindPersonalizedImage(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ECID != 1234567890 || req.ChipID != 0x8110 || req.BoardID != 0x0C {
+		t.Errorf("设备信息错误: %+v", req)
+	}
+	if want := filepath.Join(dir, "018-12345-001.dmg"); req.Image.ImagePath != want {
+		t.Errorf("ImagePath = %q, want %q", req.Image.ImagePath, want)
+	}
+	if want := filepath.Join(dir, "Firmware", "018-12345-001.dmg.trustcache"); req.Image.TrustCachePath != want {
+		t.Errorf("TrustCachePath = %q, want %q", req.Image.TrustCachePath, want)
+	}
+	if string(req.Digests["PersonalizedDMG"]) != "image" || string(req.Digests["LoadableTrustCache"]) != "trustcache" {
+		t.Errorf("Digests = %q", req.Digests)
+	}
+	if plistString(req.Identity, "ApChipID") != "0x8110" {
+		t.Errorf("匹配到错误的 BuildIdentity: %v", req.Identity)
+	}
+}
+
+func TestBuildPersonalizationRequestErrors(t *testing.T) {
+	dir := writeDDIRestore(t)
+
+	other := map[string]string{"UniqueChipID": "1", "ChipID": "1", "BoardId": "1"}
+	if _, err := BuildPersonalizationRequest("udid", other, FindPersonalizedImage(dir)); err == nil {
+		t.Error("不匹配的设备应返回错误")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "Firmware", "018-12345-001.dmg.trustcache")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildPersonalizationRequest("udid", testIdentifiers, FindPersonalizedImage(dir)); err == nil {
+		t.Error("缺少信任缓存应返回错误")
+	}
+}
+
+type stubSigner struct {
+	req      PersonalizationRequest
+	manifest []byte
+	err      error
+}
+
+func (s *stubSigner) Sign(ctx context.Context, req PersonalizationRequest) ([]byte, error) {
+	s.req = req
+	return s.manifest, s.err
+}
+
+type stubMounter struct {
+	called   bool
+	manifest []byte
+}
+
+func (m *stubMounter) Mount(ctx context.Context, req PersonalizationRequest, manifest []byte) error {
+	m.called = true
+	m.manifest = manifest
+	return nil
+}
+
+func TestPersonalizeAndMountWithStubSigner(t *testing.T) {
+	req, err := BuildPersonalizationRequest("udid", testIdentifiers, FindPersonalizedImage(writeDDIRestore(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := &stubSigner{manifest: []byte("IM4M")}
+	mounter := &stubMounter{}
+	if err := personalizeAndMount(context.Background(), req, signer, mounter); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signer.req.Digests["PersonalizedDMG"], []byte("image")) || signer.req.Identity == nil {
+		t.Errorf("签名器没有收到摘要和 BuildIdentity: %+v", signer.req)
+	}
+	if !mounter.called || string(mounter.manifest) != "IM4M" {
+		t.Errorf("挂载时没有上传签名清单: %q", mounter.manifest)
+	}
+
+	// 签名失败时不挂载
+	signer = &stubSigner{err: errors.New("tss error")}
+	mounter = &stubMounter{}
+	if err := personalizeAndMount(context.Background(), req, signer, mounter); err == nil || mounter.called {
+		t.Error("签名失败时不应挂载")
+	}
+
+	// 签名清单为空时不挂载
+	signer = &stubSigner{}
+	if err := personalizeAndMount(context.Background(), req, signer, mounter); err == nil || mounter.called {
+		t.Error("签名清单为空时不应挂载")
+	}
+}
+
+func TestImageMounterToolRejectsExternalManifest(t *testing.T) {
+	if err := (ImageMounterTool{}).Mount(context.Background(), PersonalizationRequest{}, []byte("IM4M")); err == nil {
+		t.Error("ideviceimagemounter 不支持外部签名清单，应返回错误")
+	}
+}
+
+func TestGetImageMountStatusLockedDevice(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{
+		"idevice_id":  "echo test-udid\n",
+		"ideviceinfo": "echo 'ERROR: Could not connect to lockdownd: Password protected (-17)' >&2\nexit 1\n",
+	})
+	if _, err := GetImageMountStatus("test-udid"); !errors.Is(err, ErrPasscodeLocked) {
+		t.Errorf("设备锁定: err = %v", err)
+	}
+	if err := UnmountDeveloperImage("test-udid"); !errors.Is(err, ErrPasscodeLocked) {
+		t.Errorf("卸载: err = %v", err)
+	}
+}
+
+func TestMountImageQueriesStatusOnce(t *testing.T) {
+	tools := testutil.NewFakeTools(t, map[string]string{
+		"idevice_id":  "echo test-udid\n",
+		"ideviceinfo": "case \"$*\" in *DeveloperModeStatus*) echo true ;; *) echo 17.4 ;; esac\n",
+		// 已挂载个性化镜像，不需要再次挂载
+		"ideviceimagemounter": "echo '<plist version=\"1.0\"><dict><key>EntryList</key><array><dict><key>ImageType</key><string>Personalized</string></dict></array></dict></plist>'\n",
+	})
+	if err := MountImage(context.Background(), "test-udid", t.TempDir(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls := tools.Calls(t, "ideviceimagemounter"); len(calls) != 1 || calls[0] != "-u test-udid -x list" {
+		t.Errorf("ideviceimagemounter 调用 = %q", calls)
+	}
+	if calls := tools.Calls(t, "idevice_id"); len(calls) != 1 {
+		t.Errorf("idevice_id 调用 %d 次", len(calls))
+	}
+}
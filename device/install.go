@@ -0,0 +1,337 @@
+package device
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 设备类型，对应 Info.plist 中 UIDeviceFamily 的取值
+const (
+	DeviceFamilyIPhone = 1
+	DeviceFamilyIPad   = 2
+)
+
+// maxInfoPlistSize Info.plist 的最大大小，防止读取异常的压缩包
+const maxInfoPlistSize = 8 << 20
+
+// DefaultInstallConcurrency 批量安装默认同时进行的任务数
+const DefaultInstallConcurrency = 2
+
+// IPAInfo 从 IPA 的 Info.plist 中读取的信息
+type IPAInfo struct {
+	Path           string         `json:"path"`
+	AppDir         string         `json:"app_dir"` // 压缩包中的 .app 目录，例如 Payload/Demo.app
+	BundleID       string         `json:"bundle_id"`
+	Name           string         `json:"name"`
+	Version        string         `json:"version"`
+	Build          string         `json:"build"`
+	MinimumOS      string         `json:"minimum_os"`
+	DeviceFamilies []int          `json:"device_families"` // 1 为 iPhone，2 为 iPad
+	Executable     string         `json:"executable"`
+	InfoPlist      map[string]any `json:"-"`
+}
+
+// 应用操作类型
+const (
+	OperationInstall       = "install"
+	OperationUninstall     = "uninstall"
+	OperationArchive       = "archive"
+	OperationRestore       = "restore"
+	OperationRemoveArchive = "remove_archive"
+)
+
+// InstallProgress 安装、卸载等应用操作的进度
+type InstallProgress struct {
+	UDID      string `json:"udid"`
+	Operation string `json:"operation"`      // install、uninstall、archive、restore 或 remove_archive
+	Path      string `json:"path,omitempty"` // 安装时为 IPA 路径
+	BundleID  string `json:"bundle_id"`
+	Stage     string `json:"stage"`   // 当前阶段，例如 Uploading、CopyingApplication
+	Percent   int    `json:"percent"` // 总进度 0-100
+}
+
+// InstallResult 一个安装或卸载任务的结果
+type InstallResult struct {
+	UDID      string    `json:"udid"`
+	Path      string    `json:"path,omitempty"`
+	BundleID  string    `json:"bundle_id"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+}
+
+// installProgressPattern 匹配 ideviceinstaller 的进度输出，例如 Install: CopyingApplication (45%)
+var installProgressPattern = regexp.MustCompile(`(?:Install|Upgrade|Uninstall|Archive|Restore|RemoveArchive|Status):\s*(\w+)(?:\s*\((\d+)%\))?`)
+
+// ReadIPAInfo 读取 IPA 中主应用的 Info.plist
+func ReadIPAInfo(ipaPath string) (IPAInfo, error) {
+	reader, err := zip.OpenReader(ipaPath)
+	if err != nil {
+		return IPAInfo{}, fmt.Errorf("打开 IPA 失败: %v", err)
+	}
+	defer reader.Close()
+	return readIPAInfo(ipaPath, &reader.Reader)
+}
+
+// readIPAInfo 从已打开的压缩包中读取主应用的 Info.plist
+func readIPAInfo(ipaPath string, reader *zip.Reader) (IPAInfo, error) {
+	info := IPAInfo{Path: ipaPath}
+
+	// 主应用的 Info.plist 位于 Payload/Xxx.app/Info.plist，扩展和框架中的不算
+	var plistFile *zip.File
+	for _, f := range reader.File {
+		parts := strings.Split(f.Name, "/")
+		if len(parts) == 3 && parts[0] == "Payload" && strings.HasSuffix(parts[1], ".app") && parts[2] == "Info.plist" {
+			plistFile = f
+			info.AppDir = parts[0] + "/" + parts[1]
+			break
+		}
+	}
+	if plistFile == nil {
+		return info, fmt.Errorf("IPA 中找不到 Info.plist")
+	}
+
+	data, err := readZipFile(plistFile, maxInfoPlistSize)
+	if err != nil {
+		return info, err
+	}
+	root, err := parsePlist(data)
+	if err != nil {
+		return info, fmt.Errorf("解析 Info.plist 失败: %v", err)
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return info, fmt.Errorf("Info.plist 格式错误")
+	}
+
+	info.InfoPlist = dict
+	info.BundleID = plistString(dict, "CFBundleIdentifier")
+	info.Name = plistString(dict, "CFBundleDisplayName")
+	if info.Name == "" {
+		info.Name = plistString(dict, "CFBundleName")
+	}
+	info.Version = plistString(dict, "CFBundleShortVersionString")
+	info.Build = plistString(dict, "CFBundleVersion")
+	info.MinimumOS = plistString(dict, "MinimumOSVersion")
+	info.Executable = plistString(dict, "CFBundleExecutable")
+	families, _ := dict["UIDeviceFamily"].([]any)
+	for _, family := range families {
+		switch v := family.(type) {
+		case int64:
+			info.DeviceFamilies = append(info.DeviceFamilies, int(v))
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				info.DeviceFamilies = append(info.DeviceFamilies, n)
+			}
+		}
+	}
+	// 没有声明时默认只支持 iPhone
+	if len(info.DeviceFamilies) == 0 {
+		info.DeviceFamilies = []int{DeviceFamilyIPhone}
+	}
+
+	if info.BundleID == "" {
+		return info, fmt.Errorf("Info.plist 中缺少 CFBundleIdentifier")
+	}
+	return info, nil
+}
+
+// readZipFile 读取压缩包中的文件，超过 limit 字节时返回错误
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s 过大", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", f.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s 过大", f.Name)
+	}
+	return data, nil
+}
+
+// CheckIPACompatibility 检查 IPA 是否可以安装到指定机型和系统版本
+func CheckIPACompatibility(info IPAInfo, productType string, productVersion string) error {
+	if info.MinimumOS != "" && productVersion != "" && compareVersions(productVersion, info.MinimumOS) < 0 {
+		return fmt.Errorf("%s 需要 iOS %s 或更高版本，设备为 iOS %s", info.Name, info.MinimumOS, productVersion)
+	}
+
+	family := deviceFamilyOf(productType)
+	if family == 0 {
+		return nil
+	}
+	for _, supported := range info.DeviceFamilies {
+		// iPad 可以运行仅支持 iPhone 的应用
+		if supported == family || (family == DeviceFamilyIPad && supported == DeviceFamilyIPhone) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 不支持 %s", info.Name, marketingName(productType))
+}
+
+// deviceFamilyOf 根据 ProductType 返回设备类型，未知时返回 0
+func deviceFamilyOf(productType string) int {
+	switch {
+	case strings.HasPrefix(productType, "iPhone"), strings.HasPrefix(productType, "iPod"):
+		return DeviceFamilyIPhone
+	case strings.HasPrefix(productType, "iPad"):
+		return DeviceFamilyIPad
+	}
+	return 0
+}
+
+// InstallIPA 安装或升级 IPA
+// 安装前检查 IPA 与设备是否兼容；ideviceinstaller 会先将 IPA 上传到 PublicStaging 再安装。
+func InstallIPA(ctx context.Context, udid string, ipaPath string, onProgress func(InstallProgress)) (IPAInfo, error) {
+	info, err := ReadIPAInfo(ipaPath)
+	if err != nil {
+		return info, err
+	}
+	if !IsDeviceConnected(udid) {
+		return info, ErrDeviceNotConnected
+	}
+	if err := CheckIPACompatibility(info, getDeviceValue(udid, "ProductType"), getDeviceValue(udid, "ProductVersion")); err != nil {
+		return info, err
+	}
+
+	report := func(stage string, percent int) {
+		if onProgress != nil {
+			onProgress(InstallProgress{UDID: udid, Operation: OperationInstall, Path: ipaPath, BundleID: info.BundleID, Stage: stage, Percent: percent})
+		}
+	}
+
+	// 已安装的应用使用升级，保留应用数据
+	mode := "-i"
+	if installed, err := ListApps(udid, AppFilter{Type: AppTypeAll}); err == nil {
+		for _, app := range installed {
+			if app.BundleID == info.BundleID {
+				mode = "-g"
+				break
+			}
+		}
+	}
+
+	report("Preparing", 0)
+	err = runInstaller(ctx, udid, "安装 "+info.Name+" 失败", report, mode, ipaPath)
+	InvalidateAppCache(udid)
+	if err != nil {
+		return info, err
+	}
+	report("Complete", 100)
+	fmt.Printf("已在设备 %s 上安装 %s (%s)\n", udid, info.Name, filepath.Base(ipaPath))
+	return info, nil
+}
+
+// BatchInstall 将多个 IPA 安装到多台设备
+// 同一台设备上的安装依次进行，不同设备之间最多同时进行 concurrency 个任务。
+func BatchInstall(ctx context.Context, udids []string, ipaPaths []string, concurrency int, onProgress func(InstallProgress)) []InstallResult {
+	if concurrency <= 0 {
+		concurrency = DefaultInstallConcurrency
+	}
+
+	results := make([]InstallResult, len(udids)*len(ipaPaths))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for d, udid := range udids {
+		wg.Add(1)
+		go func(d int, udid string) {
+			defer wg.Done()
+			for i, ipaPath := range ipaPaths {
+				result := &results[d*len(ipaPaths)+i]
+				result.UDID = udid
+				result.Path = ipaPath
+
+				select {
+				case semaphore <- struct{}{}:
+				case <-ctx.Done():
+					result.Error = "已取消"
+					continue
+				}
+				info, err := InstallIPA(ctx, udid, ipaPath, onProgress)
+				<-semaphore
+
+				result.BundleID = info.BundleID
+				if err != nil {
+					result.Error = err.Error()
+					result.ErrorCode = ErrorCodeOf(err)
+					continue
+				}
+				result.Success = true
+			}
+		}(d, udid)
+	}
+	wg.Wait()
+	return results
+}
+
+// runInstaller 运行 ideviceinstaller 并解析输出中的进度
+func runInstaller(ctx context.Context, udid string, message string, report func(stage string, percent int), args ...string) error {
+	cmd := exec.CommandContext(ctx, "ideviceinstaller", append([]string{"-u", udid}, args...)...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	var output strings.Builder
+	scanDone := make(chan struct{})
+	go func() {
+		defer close(scanDone)
+		// 进度可能以 \r 刷新，按 \r 和 \n 分行读取
+		scanner := bufio.NewScanner(pr)
+		scanner.Split(ScanProgressLines)
+		for scanner.Scan() {
+			line := scanner.Text()
+			output.WriteString(line + "\n")
+			if strings.HasPrefix(line, "Uploading") || strings.HasPrefix(line, "Copying") {
+				report("Uploading", 0)
+				continue
+			}
+			// 完成状态由调用方在确认成功后报告
+			if match := installProgressPattern.FindStringSubmatch(line); match != nil && match[1] != "Complete" {
+				percent, _ := strconv.Atoi(match[2])
+				report(match[1], percent)
+			}
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-scanDone
+
+	if err != nil {
+		return commandError("ideviceinstaller", message, err, output.String())
+	}
+	// ideviceinstaller 在部分错误下仍返回 0
+	if strings.Contains(output.String(), "ERROR") {
+		return commandError("ideviceinstaller", message, fmt.Errorf("操作失败"), output.String())
+	}
+	return nil
+}
+
+// ScanProgressLines 按 \r 或 \n 分行，用于读取以 \r 刷新进度的命令输出
+func ScanProgressLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,52 @@
+package device
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"myitools/internal/testutil"
+)
+
+func TestRunInstallerReadsCarriageReturnProgress(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{
+		"ideviceinstaller": `printf 'Uploading app.ipa\rInstall: CreatingStagingDirectory (5%%)\rInstall: ExtractingPackage (15%%)\rInstall: InstallingApplication (60%%)\rInstall: Complete\n'` + "\n",
+	})
+
+	type stage struct {
+		name    string
+		percent int
+	}
+	var got []stage
+	err := runInstaller(context.Background(), "test-udid", "安装失败", func(name string, percent int) {
+		got = append(got, stage{name, percent})
+	}, "install", "app.ipa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []stage{
+		{"Uploading", 0},
+		{"CreatingStagingDirectory", 5},
+		{"ExtractingPackage", 15},
+		{"InstallingApplication", 60},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("进度 = %v, want %v", got, want)
+	}
+}
+
+func TestScanProgressLines(t *testing.T) {
+	data := []byte("a\rb\nc")
+	var lines []string
+	for len(data) > 0 {
+		advance, token, err := ScanProgressLines(data, true)
+		if err != nil || advance == 0 {
+			t.Fatalf("advance = %d, err = %v", advance, err)
+		}
+		lines = append(lines, string(token))
+		data = data[advance:]
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
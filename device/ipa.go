@@ -0,0 +1,507 @@
+package device
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IPA 中各类文件的读取上限
+const (
+	maxIconSize      = 4 << 20
+	maxProvisionSize = 1 << 20
+	machOHeaderSize  = 4096
+)
+
+// 描述文件类型
+const (
+	ProvisionDevelopment = "development"
+	ProvisionAdHoc       = "ad-hoc"
+	ProvisionEnterprise  = "enterprise"
+	ProvisionAppStore    = "app-store"
+)
+
+// IPAInspection IPA 离线检查结果
+type IPAInspection struct {
+	IPAInfo
+	Size          int64                `json:"size"`  // IPA 文件大小(字节)
+	Icons         []IPAIcon            `json:"icons"` // 按尺寸从大到小排列
+	Provision     *ProvisioningProfile `json:"provision"`
+	Signed        bool                 `json:"signed"`        // 是否包含 _CodeSignature
+	Architectures []string             `json:"architectures"` // 主程序支持的 CPU 架构
+	Frameworks    []string             `json:"frameworks"`
+	Extensions    []IPAExtension       `json:"extensions"`
+	InfoPlist     map[string]any       `json:"info_plist"`
+}
+
+// IPAIcon 应用图标，CgBI 格式的图标已转换为标准 PNG
+type IPAIcon struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Data   []byte `json:"data"`
+}
+
+// IPAExtension 应用扩展(PlugIns 目录中的 .appex)
+type IPAExtension struct {
+	Name     string `json:"name"`
+	BundleID string `json:"bundle_id"`
+	Version  string `json:"version"`
+	Point    string `json:"point"` // NSExtensionPointIdentifier，例如 com.apple.widgetkit-extension
+}
+
+// ProvisioningProfile embedded.mobileprovision 中的信息
+type ProvisioningProfile struct {
+	Name                 string         `json:"name"`
+	UUID                 string         `json:"uuid"`
+	AppIDName            string         `json:"app_id_name"`
+	TeamID               string         `json:"team_id"`
+	TeamName             string         `json:"team_name"`
+	Type                 string         `json:"type"` // development、ad-hoc、enterprise 或 app-store
+	CreationDate         time.Time      `json:"creation_date"`
+	ExpirationDate       time.Time      `json:"expiration_date"`
+	Expired              bool           `json:"expired"`
+	ProvisionedDevices   []string       `json:"provisioned_devices"`
+	ProvisionsAllDevices bool           `json:"provisions_all_devices"`
+	Entitlements         map[string]any `json:"entitlements"`
+}
+
+// Mach-O 文件头魔数
+const (
+	machOMagic32  = 0xfeedface
+	machOMagic64  = 0xfeedfacf
+	machOFatMagic = 0xcafebabe
+	machOFat64    = 0xcafebabf
+)
+
+// InspectIPA 离线读取 IPA 的信息、图标、描述文件、签名和架构，不需要连接设备
+func InspectIPA(ipaPath string) (IPAInspection, error) {
+	reader, err := zip.OpenReader(ipaPath)
+	if err != nil {
+		return IPAInspection{}, fmt.Errorf("打开 IPA 失败: %v", err)
+	}
+	defer reader.Close()
+
+	info, err := readIPAInfo(ipaPath, &reader.Reader)
+	if err != nil {
+		return IPAInspection{}, err
+	}
+	result := IPAInspection{IPAInfo: info, InfoPlist: info.InfoPlist}
+	if stat, err := os.Stat(ipaPath); err == nil {
+		result.Size = stat.Size()
+	}
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+	prefix := info.AppDir + "/"
+
+	result.Signed = files[prefix+"_CodeSignature/CodeResources"] != nil
+	result.Icons = readIPAIcons(&reader.Reader, info)
+
+	if f := files[prefix+"embedded.mobileprovision"]; f != nil {
+		if data, err := readZipFile(f, maxProvisionSize); err == nil {
+			if profile, err := parseProvisioningProfile(data); err == nil {
+				result.Provision = &profile
+			} else {
+				fmt.Printf("解析描述文件失败: %v\n", err)
+			}
+		}
+	}
+
+	if f := files[prefix+info.Executable]; f != nil && info.Executable != "" {
+		if archs, err := readZipMachOArchs(f); err == nil {
+			result.Architectures = archs
+		} else {
+			fmt.Printf("读取主程序架构失败: %v\n", err)
+		}
+	}
+
+	result.Frameworks, result.Extensions = readIPABundles(&reader.Reader, files, prefix)
+	return result, nil
+}
+
+// readIPABundles 列出 Frameworks 目录中的框架和 PlugIns 目录中的扩展
+func readIPABundles(reader *zip.Reader, files map[string]*zip.File, prefix string) ([]string, []IPAExtension) {
+	frameworks := []string{}
+	extensions := []IPAExtension{}
+	seen := map[string]bool{}
+
+	for _, f := range reader.File {
+		rest, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(rest, "/")
+		if len(parts) < 2 || seen[parts[0]+"/"+parts[1]] {
+			continue
+		}
+		name := parts[1]
+
+		switch {
+		case parts[0] == "Frameworks" && (strings.HasSuffix(name, ".framework") || strings.HasSuffix(name, ".dylib")):
+			seen[parts[0]+"/"+name] = true
+			frameworks = append(frameworks, name)
+		case parts[0] == "PlugIns" && strings.HasSuffix(name, ".appex"):
+			seen[parts[0]+"/"+name] = true
+			ext := IPAExtension{Name: strings.TrimSuffix(name, ".appex")}
+			if plistFile := files[prefix+"PlugIns/"+name+"/Info.plist"]; plistFile != nil {
+				if data, err := readZipFile(plistFile, maxInfoPlistSize); err == nil {
+					if root, err := parsePlist(data); err == nil {
+						dict, _ := root.(map[string]any)
+						ext.BundleID = plistString(dict, "CFBundleIdentifier")
+						ext.Version = plistString(dict, "CFBundleShortVersionString")
+						ext.Point = plistString(plistDict(dict, "NSExtension"), "NSExtensionPointIdentifier")
+						if displayName := plistString(dict, "CFBundleDisplayName"); displayName != "" {
+							ext.Name = displayName
+						}
+					}
+				}
+			}
+			extensions = append(extensions, ext)
+		}
+	}
+	sort.Strings(frameworks)
+	return frameworks, extensions
+}
+
+// readIPAIcons 读取 Info.plist 中声明的应用图标
+func readIPAIcons(reader *zip.Reader, info IPAInfo) []IPAIcon {
+	// 图标文件名前缀，例如 AppIcon60x60，实际文件为 AppIcon60x60@2x.png
+	var names []string
+	for _, key := range []string{"CFBundleIcons", "CFBundleIcons~ipad"} {
+		names = append(names, plistStrings(plistDict(info.InfoPlist, key, "CFBundlePrimaryIcon"), "CFBundleIconFiles")...)
+	}
+	names = append(names, plistStrings(info.InfoPlist, "CFBundleIconFiles")...)
+	if icon := plistString(info.InfoPlist, "CFBundleIconFile"); icon != "" {
+		names = append(names, icon)
+	}
+	if len(names) == 0 {
+		names = []string{"AppIcon", "Icon"}
+	}
+
+	icons := []IPAIcon{}
+	for _, f := range reader.File {
+		dir, file := path.Split(f.Name)
+		if dir != info.AppDir+"/" || !strings.HasSuffix(strings.ToLower(file), ".png") {
+			continue
+		}
+		matched := false
+		for _, name := range names {
+			if strings.HasPrefix(file, strings.TrimSuffix(name, ".png")) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		data, err := readZipFile(f, maxIconSize)
+		if err != nil {
+			continue
+		}
+		data, err = normalizePNG(data)
+		if err != nil {
+			fmt.Printf("读取图标 %s 失败: %v\n", file, err)
+			continue
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		icons = append(icons, IPAIcon{Name: file, Width: config.Width, Height: config.Height, Data: data})
+	}
+
+	sort.SliceStable(icons, func(i, j int) bool {
+		return icons[i].Width*icons[i].Height > icons[j].Width*icons[j].Height
+	})
+	return icons
+}
+
+// normalizePNG 将 Xcode 压缩过的 CgBI 格式 PNG 转换为标准 PNG，标准 PNG 原样返回
+// CgBI 格式的 IDAT 为不带 zlib 头的 deflate 数据，像素为预乘 alpha 的 BGRA。
+func normalizePNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngMagic) {
+		return nil, fmt.Errorf("不是 PNG 文件")
+	}
+
+	var (
+		width, height uint32
+		bitDepth      byte
+		colorType     byte
+		interlace     byte
+		cgbi          bool
+		idat          bytes.Buffer
+	)
+	for pos := len(pngMagic); pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			return nil, fmt.Errorf("PNG 数据损坏")
+		}
+		chunk := data[pos+8 : pos+8+length]
+		pos += 12 + length
+
+		switch chunkType {
+		case "CgBI":
+			cgbi = true
+		case "IHDR":
+			if length < 13 {
+				return nil, fmt.Errorf("PNG 数据损坏")
+			}
+			width = binary.BigEndian.Uint32(chunk)
+			height = binary.BigEndian.Uint32(chunk[4:])
+			bitDepth, colorType, interlace = chunk[8], chunk[9], chunk[12]
+		case "IDAT":
+			idat.Write(chunk)
+		}
+	}
+	if !cgbi {
+		return data, nil
+	}
+	// Xcode 只会生成 8 位 RGBA、非隔行扫描的 CgBI 图片
+	if bitDepth != 8 || colorType != 6 || interlace != 0 {
+		return nil, fmt.Errorf("不支持的 CgBI 格式")
+	}
+	if width == 0 || height == 0 || uint64(width)*uint64(height)*4 > maxIconSize*16 {
+		return nil, fmt.Errorf("图片尺寸无效")
+	}
+
+	stride := int(width) * 4
+	raw := make([]byte, (stride+1)*int(height))
+	if _, err := io.ReadFull(flate.NewReader(&idat), raw); err != nil {
+		return nil, fmt.Errorf("解压图片数据失败: %v", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+	prev := make([]byte, stride)
+	for y := 0; y < int(height); y++ {
+		row := raw[y*(stride+1)+1 : (y+1)*(stride+1)]
+		if err := unfilterPNGRow(raw[y*(stride+1)], row, prev, 4); err != nil {
+			return nil, err
+		}
+		out := img.Pix[y*img.Stride : y*img.Stride+stride]
+		for x := 0; x < stride; x += 4 {
+			b, g, r, a := row[x], row[x+1], row[x+2], row[x+3]
+			if a > 0 && a < 255 {
+				r = byte(min(255, int(r)*255/int(a)))
+				g = byte(min(255, int(g)*255/int(a)))
+				b = byte(min(255, int(b)*255/int(a)))
+			}
+			out[x], out[x+1], out[x+2], out[x+3] = r, g, b, a
+		}
+		prev = row
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("转换图片失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// unfilterPNGRow 还原 PNG 的行过滤，bpp 为每像素字节数
+func unfilterPNGRow(filter byte, row []byte, prev []byte, bpp int) error {
+	switch filter {
+	case 0:
+	case 1:
+		for i := bpp; i < len(row); i++ {
+			row[i] += row[i-bpp]
+		}
+	case 2:
+		for i := range row {
+			row[i] += prev[i]
+		}
+	case 3:
+		for i := range row {
+			var left int
+			if i >= bpp {
+				left = int(row[i-bpp])
+			}
+			row[i] += byte((left + int(prev[i])) / 2)
+		}
+	case 4:
+		for i := range row {
+			var left, upLeft int
+			if i >= bpp {
+				left = int(row[i-bpp])
+				upLeft = int(prev[i-bpp])
+			}
+			row[i] += paeth(left, int(prev[i]), upLeft)
+		}
+	default:
+		return fmt.Errorf("PNG 过滤类型无效: %d", filter)
+	}
+	return nil
+}
+
+// paeth PNG Paeth 预测
+func paeth(a, b, c int) byte {
+	p := a + b - c
+	pa, pb, pc := abs(p-a), abs(p-b), abs(p-c)
+	switch {
+	case pa <= pb && pa <= pc:
+		return byte(a)
+	case pb <= pc:
+		return byte(b)
+	}
+	return byte(c)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// parseProvisioningProfile 解析 mobileprovision
+// 描述文件是 CMS 签名数据，其中内嵌一段 XML plist，这里直接截取该段解析，不校验签名。
+func parseProvisioningProfile(data []byte) (ProvisioningProfile, error) {
+	start := bytes.Index(data, []byte("<?xml"))
+	end := bytes.LastIndex(data, []byte("</plist>"))
+	if start < 0 || end < start {
+		return ProvisioningProfile{}, fmt.Errorf("描述文件中找不到 plist")
+	}
+	root, err := parseXMLPlist(data[start : end+len("</plist>")])
+	if err != nil {
+		return ProvisioningProfile{}, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return ProvisioningProfile{}, fmt.Errorf("描述文件格式错误")
+	}
+
+	profile := ProvisioningProfile{
+		Name:                 plistString(dict, "Name"),
+		UUID:                 plistString(dict, "UUID"),
+		AppIDName:            plistString(dict, "AppIDName"),
+		TeamName:             plistString(dict, "TeamName"),
+		ProvisionedDevices:   plistStrings(dict, "ProvisionedDevices"),
+		ProvisionsAllDevices: plistBool(dict, "ProvisionsAllDevices"),
+		Entitlements:         plistDict(dict, "Entitlements"),
+	}
+	if teams := plistStrings(dict, "TeamIdentifier"); len(teams) > 0 {
+		profile.TeamID = teams[0]
+	}
+	profile.CreationDate, _ = dict["CreationDate"].(time.Time)
+	profile.ExpirationDate, _ = dict["ExpirationDate"].(time.Time)
+	profile.Expired = !profile.ExpirationDate.IsZero() && time.Now().After(profile.ExpirationDate)
+
+	switch {
+	case profile.ProvisionsAllDevices:
+		profile.Type = ProvisionEnterprise
+	case len(profile.ProvisionedDevices) == 0:
+		profile.Type = ProvisionAppStore
+	case plistBool(profile.Entitlements, "get-task-allow"):
+		profile.Type = ProvisionDevelopment
+	default:
+		profile.Type = ProvisionAdHoc
+	}
+	return profile, nil
+}
+
+// ProvisionsDevice 描述文件是否允许安装到指定设备
+// App Store 描述文件签名的应用只能通过 App Store 安装，不允许安装到任何设备。
+func (p *ProvisioningProfile) ProvisionsDevice(udid string) bool {
+	if p.Type == ProvisionAppStore {
+		return false
+	}
+	if p.ProvisionsAllDevices {
+		return true
+	}
+	for _, provisioned := range p.ProvisionedDevices {
+		if strings.EqualFold(provisioned, udid) {
+			return true
+		}
+	}
+	return false
+}
+
+// readZipMachOArchs 读取压缩包中 Mach-O 文件的架构
+func readZipMachOArchs(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	header := make([]byte, machOHeaderSize)
+	n, err := io.ReadFull(rc, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return machOArchs(header[:n])
+}
+
+// machOArchs 根据 Mach-O 文件头返回架构列表，通用二进制返回每个切片的架构
+func machOArchs(header []byte) ([]string, error) {
+	if len(header) < 12 {
+		return nil, fmt.Errorf("不是 Mach-O 文件")
+	}
+
+	switch binary.BigEndian.Uint32(header) {
+	case machOFatMagic, machOFat64:
+		entrySize := 20
+		if binary.BigEndian.Uint32(header) == machOFat64 {
+			entrySize = 32
+		}
+		count := int(binary.BigEndian.Uint32(header[4:]))
+		archs := []string{}
+		for i := 0; i < count; i++ {
+			offset := 8 + i*entrySize
+			if offset+8 > len(header) {
+				break
+			}
+			archs = append(archs, machOArchName(binary.BigEndian.Uint32(header[offset:]), binary.BigEndian.Uint32(header[offset+4:])))
+		}
+		return archs, nil
+	}
+
+	// iOS 程序均为小端序
+	switch binary.LittleEndian.Uint32(header) {
+	case machOMagic32, machOMagic64:
+		return []string{machOArchName(binary.LittleEndian.Uint32(header[4:]), binary.LittleEndian.Uint32(header[8:]))}, nil
+	}
+	return nil, fmt.Errorf("不是 Mach-O 文件")
+}
+
+// machOArchName 将 CPU 类型转换为架构名称
+func machOArchName(cpuType uint32, cpuSubtype uint32) string {
+	const abi64 = 0x01000000
+	subtype := cpuSubtype &^ 0xff000000
+
+	switch cpuType {
+	case 12:
+		switch subtype {
+		case 9:
+			return "armv7"
+		case 11:
+			return "armv7s"
+		case 6:
+			return "armv6"
+		}
+		return "arm"
+	case 12 | abi64:
+		if subtype == 2 {
+			return "arm64e"
+		}
+		return "arm64"
+	case 7:
+		return "i386"
+	case 7 | abi64:
+		return "x86_64"
+	}
+	return fmt.Sprintf("cpu(%d,%d)", cpuType, subtype)
+}
@@ -0,0 +1,300 @@
+package device
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pngChunk 生成一个带 CRC 的 PNG 块
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// filterPNGRow 按指定过滤类型编码一行，是 unfilterPNGRow 的逆运算
+func filterPNGRow(filter byte, row []byte, prev []byte, bpp int) []byte {
+	out := make([]byte, len(row))
+	for i := range row {
+		var left, upLeft int
+		if i >= bpp {
+			left = int(row[i-bpp])
+			upLeft = int(prev[i-bpp])
+		}
+		up := int(prev[i])
+		switch filter {
+		case 0:
+			out[i] = row[i]
+		case 1:
+			out[i] = row[i] - byte(left)
+		case 2:
+			out[i] = row[i] - byte(up)
+		case 3:
+			out[i] = row[i] - byte((left+up)/2)
+		case 4:
+			out[i] = row[i] - paeth(left, up, upLeft)
+		}
+	}
+	return out
+}
+
+// cgbiPNG 生成 Xcode 风格的 CgBI 图片：预乘 alpha 的 BGRA、无 zlib 头的 deflate 数据
+// pixels 为每行的 BGRA 字节，第 y 行使用过滤类型 y%5。
+func cgbiPNG(t *testing.T, width int, pixels [][]byte) []byte {
+	t.Helper()
+	var raw bytes.Buffer
+	prev := make([]byte, width*4)
+	for y, row := range pixels {
+		filter := byte(y % 5)
+		raw.WriteByte(filter)
+		raw.Write(filterPNGRow(filter, row, prev, 4))
+		prev = row
+	}
+	var idat bytes.Buffer
+	w, err := flate.NewWriter(&idat, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(raw.Bytes())
+	w.Close()
+
+	ihdr := binary.BigEndian.AppendUint32(nil, uint32(width))
+	ihdr = binary.BigEndian.AppendUint32(ihdr, uint32(len(pixels)))
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+
+	data := append([]byte(nil), pngMagic...)
+	data = append(data, pngChunk("CgBI", []byte{0x50, 0x00, 0x20, 0x02})...)
+	data = append(data, pngChunk("IHDR", ihdr)...)
+	data = append(data, pngChunk("IDAT", idat.Bytes())...)
+	return append(data, pngChunk("IEND", nil)...)
+}
+
+func TestNormalizePNGCgBI(t *testing.T) {
+	// 每个像素依次为 B、G、R、A，颜色已按 alpha 预乘
+	pixels := [][]byte{
+		{0, 0, 255, 255, 0, 64, 0, 128, 10, 20, 30, 0},
+		{255, 0, 0, 255, 32, 32, 32, 64, 0, 0, 0, 0},
+		{1, 2, 3, 255, 100, 50, 25, 200, 7, 7, 7, 7},
+		{200, 150, 100, 255, 60, 0, 60, 60, 0, 128, 0, 128},
+		{9, 8, 7, 255, 12, 34, 56, 255, 90, 90, 90, 90},
+	}
+	out, err := normalizePNG(cgbiPNG(t, 3, pixels))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("转换结果不是标准 PNG: %v", err)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("图片类型 = %T", img)
+	}
+	if nrgba.Bounds() != image.Rect(0, 0, 3, 5) {
+		t.Fatalf("图片尺寸 = %v", nrgba.Bounds())
+	}
+
+	tests := []struct {
+		x, y int
+		want [4]byte // R、G、B、A
+	}{
+		{0, 0, [4]byte{255, 0, 0, 255}},
+		{1, 0, [4]byte{0, 127, 0, 128}}, // 64*255/128
+		{2, 0, [4]byte{30, 20, 10, 0}},  // 完全透明的像素不做还原
+		{0, 1, [4]byte{0, 0, 255, 255}},
+		{1, 1, [4]byte{127, 127, 127, 64}},
+		{1, 2, [4]byte{31, 63, 127, 200}},
+		{1, 3, [4]byte{255, 0, 255, 60}},
+		{2, 3, [4]byte{0, 255, 0, 128}},
+		{2, 4, [4]byte{255, 255, 255, 90}},
+	}
+	for _, tt := range tests {
+		i := nrgba.PixOffset(tt.x, tt.y)
+		var got [4]byte
+		copy(got[:], nrgba.Pix[i:i+4])
+		if got != tt.want {
+			t.Errorf("像素 (%d,%d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePNGKeepsStandardPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	out, err := normalizePNG(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, buf.Bytes()) {
+		t.Error("标准 PNG 不应被修改")
+	}
+	if _, err := normalizePNG([]byte("GIF89a")); err == nil {
+		t.Error("非 PNG 数据应返回错误")
+	}
+}
+
+func TestUnfilterPNGRow(t *testing.T) {
+	prev := []byte{10, 20, 30, 40, 50, 60, 70, 80}
+	row := []byte{5, 250, 3, 128, 200, 1, 99, 0}
+	for filter := byte(0); filter <= 4; filter++ {
+		got := filterPNGRow(filter, row, prev, 4)
+		if err := unfilterPNGRow(filter, got, prev, 4); err != nil {
+			t.Fatalf("过滤类型 %d: %v", filter, err)
+		}
+		if !bytes.Equal(got, row) {
+			t.Errorf("过滤类型 %d: got %v, want %v", filter, got, row)
+		}
+	}
+	if err := unfilterPNGRow(5, row, prev, 4); err == nil {
+		t.Error("无效的过滤类型应返回错误")
+	}
+}
+
+func TestMachOArchs(t *testing.T) {
+	thin := func(magic, cpuType, cpuSubtype uint32) []byte {
+		header := binary.LittleEndian.AppendUint32(nil, magic)
+		header = binary.LittleEndian.AppendUint32(header, cpuType)
+		return binary.LittleEndian.AppendUint32(header, cpuSubtype)
+	}
+	fat := func(magic uint32, entrySize int, archs ...[2]uint32) []byte {
+		header := binary.BigEndian.AppendUint32(nil, magic)
+		header = binary.BigEndian.AppendUint32(header, uint32(len(archs)))
+		for _, arch := range archs {
+			entry := make([]byte, entrySize)
+			binary.BigEndian.PutUint32(entry, arch[0])
+			binary.BigEndian.PutUint32(entry[4:], arch[1])
+			header = append(header, entry...)
+		}
+		return header
+	}
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   []string
+	}{
+		{"arm64", thin(machOMagic64, 0x0100000c, 0), []string{"arm64"}},
+		{"arm64e", thin(machOMagic64, 0x0100000c, 0x80000002), []string{"arm64e"}},
+		{"armv7", thin(machOMagic32, 12, 9), []string{"armv7"}},
+		{"fat", fat(machOFatMagic, 20, [2]uint32{12, 9}, [2]uint32{12, 11}, [2]uint32{0x0100000c, 0}), []string{"armv7", "armv7s", "arm64"}},
+		{"fat64", fat(machOFat64, 32, [2]uint32{0x0100000c, 0}, [2]uint32{0x0100000c, 0x80000002}), []string{"arm64", "arm64e"}},
+		{"x86_64", thin(machOMagic64, 0x01000007, 3), []string{"x86_64"}},
+	}
+	for _, tt := range tests {
+		got, err := machOArchs(tt.header)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 通用二进制的头部被截断时只返回能读到的切片
+	truncated := fat(machOFatMagic, 20, [2]uint32{12, 9}, [2]uint32{0x0100000c, 0})[:8+20+4]
+	if got, err := machOArchs(truncated); err != nil || !reflect.DeepEqual(got, []string{"armv7"}) {
+		t.Errorf("截断的通用二进制: got %v, %v", got, err)
+	}
+	for _, header := range [][]byte{nil, []byte("#!/bin/sh\necho hi\n")} {
+		if _, err := machOArchs(header); err == nil {
+			t.Errorf("machOArchs(%q) 应返回错误", header)
+		}
+	}
+}
+
+// mobileprovision 生成描述文件：XML plist 前后加上 CMS 签名数据
+func mobileprovision(devices []string, allDevices bool, getTaskAllow bool) []byte {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AppIDName</key><string>Demo</string>
+	<key>CreationDate</key><date>2024-01-01T00:00:00Z</date>
+	<key>ExpirationDate</key><date>2099-01-01T00:00:00Z</date>
+	<key>Entitlements</key><dict>
+		<key>application-identifier</key><string>ABCDE12345.com.example.demo</string>
+		<key>get-task-allow</key>`)
+	if getTaskAllow {
+		body.WriteString("<true/>")
+	} else {
+		body.WriteString("<false/>")
+	}
+	body.WriteString(`
+	</dict>
+	<key>Name</key><string>Demo Profile</string>
+	<key>TeamIdentifier</key><array><string>ABCDE12345</string></array>
+	<key>TeamName</key><string>Example &amp; Co</string>
+	<key>UUID</key><string>6f1c2a1e-0000-4000-8000-000000000001</string>
+`)
+	if allDevices {
+		body.WriteString("\t<key>ProvisionsAllDevices</key><true/>\n")
+	}
+	if len(devices) > 0 {
+		body.WriteString("\t<key>ProvisionedDevices</key><array>")
+		for _, device := range devices {
+			body.WriteString("<string>" + device + "</string>")
+		}
+		body.WriteString("</array>\n")
+	}
+	body.WriteString("</dict>\n</plist>")
+
+	data := []byte{0x30, 0x80, 0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x02, 0xa0, 0x80}
+	data = append(data, body.String()...)
+	return append(data, 0x00, 0x00, 0xa0, 0x82, 0x0d, 0x3c)
+}
+
+func TestParseProvisioningProfile(t *testing.T) {
+	const udid = "00008101-000A1B2C3D4E001E"
+	tests := []struct {
+		name      string
+		data      []byte
+		wantType  string
+		provision map[string]bool // UDID -> ProvisionsDevice 的期望结果
+	}{
+		{"development", mobileprovision([]string{udid}, false, true), ProvisionDevelopment,
+			map[string]bool{udid: true, strings.ToLower(udid): true, "other": false}},
+		{"ad-hoc", mobileprovision([]string{"other", udid}, false, false), ProvisionAdHoc,
+			map[string]bool{udid: true, "another": false}},
+		{"enterprise", mobileprovision(nil, true, false), ProvisionEnterprise,
+			map[string]bool{udid: true, "other": true}},
+		{"app-store", mobileprovision(nil, false, false), ProvisionAppStore,
+			map[string]bool{udid: false, "other": false}},
+	}
+	for _, tt := range tests {
+		profile, err := parseProvisioningProfile(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if profile.Type != tt.wantType {
+			t.Errorf("%s: Type = %q, want %q", tt.name, profile.Type, tt.wantType)
+		}
+		if profile.Name != "Demo Profile" || profile.TeamID != "ABCDE12345" || profile.TeamName != "Example & Co" {
+			t.Errorf("%s: 描述文件信息 = %+v", tt.name, profile)
+		}
+		if profile.Expired || profile.ExpirationDate.Year() != 2099 {
+			t.Errorf("%s: ExpirationDate = %v, Expired = %v", tt.name, profile.ExpirationDate, profile.Expired)
+		}
+		for device, want := range tt.provision {
+			if got := profile.ProvisionsDevice(device); got != want {
+				t.Errorf("%s: ProvisionsDevice(%q) = %v, want %v", tt.name, device, got, want)
+			}
+		}
+	}
+
+	if _, err := parseProvisioningProfile([]byte("no plist here")); err == nil {
+		t.Error("缺少 plist 的描述文件应返回错误")
+	}
+}
@@ -0,0 +1,270 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 越狱检测结论
+const (
+	JailbreakVerdictJailbroken  = "jailbroken"   // 已越狱
+	JailbreakVerdictLikely      = "likely"       // 可能已越狱
+	JailbreakVerdictNotDetected = "not_detected" // 未发现越狱迹象
+	JailbreakVerdictUnknown     = "unknown"      // 无法检测
+)
+
+// afc2ProbeTimeout 挂载 afc2 的超时时间
+const afc2ProbeTimeout = 10 * time.Second
+
+// JailbreakEvidence 一条越狱检测证据
+type JailbreakEvidence struct {
+	Check  string  `json:"check"`  // 检查项: installed_app, afc2_service, root_path
+	Found  bool    `json:"found"`  // 是否发现越狱迹象
+	Detail string  `json:"detail"` // 详细说明
+	Weight float64 `json:"weight"` // 该证据的可信度 (0-1)
+}
+
+// JailbreakReport 越狱检测结果
+type JailbreakReport struct {
+	Verdict    string              `json:"verdict"`    // 结论
+	Confidence float64             `json:"confidence"` // 结论可信度 (0-1)
+	Evidence   []JailbreakEvidence `json:"evidence"`   // 检测证据
+	Skipped    []string            `json:"skipped"`    // 因工具缺失等原因跳过的检查
+}
+
+// jailbreakApp 已知的越狱相关应用
+type jailbreakApp struct {
+	bundleID string
+	name     string
+	weight   float64
+}
+
+// knownJailbreakApps 越狱后才能安装的应用，weight 表示发现该应用时越狱的可能性
+var knownJailbreakApps = []jailbreakApp{
+	{"com.saurik.Cydia", "Cydia", 0.95},
+	{"org.coolstar.SileoStore", "Sileo", 0.95},
+	{"org.coolstar.sileo", "Sileo", 0.95},
+	{"xyz.willy.Zebra", "Zebra", 0.95},
+	{"me.apptapp.installer", "Installer", 0.9},
+	{"com.tigisoftware.Filza", "Filza", 0.85},
+	{"org.coolstar.electra1141", "Electra", 0.9},
+	{"science.xnu.undecimus", "unc0ver", 0.9},
+	{"com.electrateam.chimera", "Chimera", 0.9},
+	{"org.coolstar.odyssey", "Odyssey", 0.9},
+	{"com.opa334.Dopamine", "Dopamine", 0.9},
+	{"com.samiiau.loader", "checkra1n loader", 0.9},
+	{"kr.xsf1re.Taurine", "Taurine", 0.9},
+	{"com.opa334.TrollStore", "TrollStore", 0.6},
+}
+
+// jailbreakRootPaths 通过 afc2 访问根文件系统时检查的越狱特征路径
+var jailbreakRootPaths = []string{
+	"Applications/Cydia.app",
+	"Applications/Sileo.app",
+	"var/jb",
+	"var/lib/apt",
+	"var/lib/cydia",
+	".installed_dopamine",
+	".bootstrapped",
+	"usr/lib/TweakInject",
+	"Library/MobileSubstrate/MobileSubstrate.dylib",
+}
+
+// DetectJailbreak 检测设备是否越狱
+// 检查已安装应用中是否有越狱应用，并尝试访问只有越狱设备才提供的 afc2 服务。
+func DetectJailbreak(udid string) (JailbreakReport, error) {
+	report := JailbreakReport{
+		Evidence: []JailbreakEvidence{},
+		Skipped:  []string{},
+	}
+
+	if !IsDeviceConnected(udid) {
+		return report, ErrDeviceNotConnected
+	}
+
+	checksRun := 0
+
+	// 检查1: 已安装的越狱应用
+	bundleIDs, err := listInstalledBundleIDs(udid)
+	if err != nil {
+		fmt.Printf("获取已安装应用失败: %v\n", err)
+		report.Skipped = append(report.Skipped, "installed_app: "+err.Error())
+	} else {
+		checksRun++
+		installed := make(map[string]bool, len(bundleIDs))
+		for _, id := range bundleIDs {
+			installed[strings.ToLower(id)] = true
+		}
+		for _, app := range knownJailbreakApps {
+			if installed[strings.ToLower(app.bundleID)] {
+				report.Evidence = append(report.Evidence, JailbreakEvidence{
+					Check:  "installed_app",
+					Found:  true,
+					Detail: fmt.Sprintf("已安装 %s (%s)", app.name, app.bundleID),
+					Weight: app.weight,
+				})
+			}
+		}
+		if len(report.Evidence) == 0 {
+			report.Evidence = append(report.Evidence, JailbreakEvidence{
+				Check:  "installed_app",
+				Detail: fmt.Sprintf("在 %d 个已安装应用中未发现越狱应用", len(bundleIDs)),
+			})
+		}
+	}
+
+	// 检查2: afc2 服务
+	afc2Evidence, err := probeAFC2(udid)
+	if err != nil {
+		fmt.Printf("afc2 检查跳过: %v\n", err)
+		report.Skipped = append(report.Skipped, "afc2_service: "+err.Error())
+	} else {
+		checksRun++
+		report.Evidence = append(report.Evidence, afc2Evidence...)
+	}
+
+	report.Verdict, report.Confidence = jailbreakVerdict(report.Evidence, checksRun)
+	return report, nil
+}
+
+// jailbreakVerdict 根据证据计算结论和可信度
+// 多条正面证据按 1-∏(1-w) 合并；没有正面证据时，可信度取决于实际完成了多少项检查。
+func jailbreakVerdict(evidence []JailbreakEvidence, checksRun int) (string, float64) {
+	if checksRun == 0 {
+		return JailbreakVerdictUnknown, 0
+	}
+
+	notFound := 1.0
+	positive := false
+	for _, e := range evidence {
+		if e.Found {
+			positive = true
+			notFound *= 1 - e.Weight
+		}
+	}
+
+	if !positive {
+		// 两项检查都完成时更有把握
+		if checksRun >= 2 {
+			return JailbreakVerdictNotDetected, 0.9
+		}
+		return JailbreakVerdictNotDetected, 0.6
+	}
+
+	// 四舍五入到两位小数，截断会让 0.9099999… 这样的浮点误差变成 0.90
+	confidence := math.Round((1-notFound)*100) / 100
+	if confidence >= 0.9 {
+		return JailbreakVerdictJailbroken, confidence
+	}
+	return JailbreakVerdictLikely, confidence
+}
+
+// listInstalledBundleIDs 获取设备上全部应用（含系统应用）的 Bundle ID
+func listInstalledBundleIDs(udid string) ([]string, error) {
+	cmd := exec.Command("ideviceinstaller", "-u", udid, "-l", "-o", "list_all")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("ideviceinstaller", "获取应用列表失败", err, stderr.String())
+	}
+
+	// 输出格式: CFBundleIdentifier, "版本", "名称"
+	var ids []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "CFBundleIdentifier") || strings.HasPrefix(line, "Total:") {
+			continue
+		}
+		id := strings.TrimSpace(strings.SplitN(line, ",", 2)[0])
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// probeAFC2 通过 ifuse --root 尝试挂载 afc2 服务
+// afc2 只在越狱设备上存在；挂载成功后再检查根文件系统中的越狱特征路径。
+func probeAFC2(udid string) ([]JailbreakEvidence, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("Windows 下不支持 ifuse")
+	}
+	if _, err := exec.LookPath("ifuse"); err != nil {
+		return nil, &ErrToolMissing{Tool: "ifuse"}
+	}
+
+	mountPoint, err := os.MkdirTemp("", "myitools-afc2-")
+	if err != nil {
+		return nil, fmt.Errorf("创建挂载目录失败: %v", err)
+	}
+	defer os.Remove(mountPoint)
+
+	ctx, cancel := context.WithTimeout(context.Background(), afc2ProbeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "ifuse", mountPoint, "-u", udid, "--root").CombinedOutput()
+	if err != nil {
+		// 只有明确报告 afc2 服务不存在时才算作检查完成，
+		// FUSE 未安装、设备锁定或超时等失败都视为跳过
+		if afc2Missing(string(output)) {
+			return []JailbreakEvidence{{
+				Check:  "afc2_service",
+				Detail: "afc2 服务不可用: " + strings.TrimSpace(string(output)),
+			}}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("挂载 afc2 超时: %v", ctx.Err())
+		}
+		return nil, commandError("ifuse", "挂载 afc2 失败", err, string(output))
+	}
+	defer unmountFuse(mountPoint)
+
+	evidence := []JailbreakEvidence{{
+		Check:  "afc2_service",
+		Found:  true,
+		Detail: "设备提供 afc2 服务，可以访问根文件系统",
+		Weight: 0.95,
+	}}
+	for _, path := range jailbreakRootPaths {
+		if _, err := os.Lstat(filepath.Join(mountPoint, path)); err == nil {
+			evidence = append(evidence, JailbreakEvidence{
+				Check:  "root_path",
+				Found:  true,
+				Detail: "存在越狱特征路径 /" + path,
+				Weight: 0.8,
+			})
+		}
+	}
+	return evidence, nil
+}
+
+// afc2Missing ifuse 的输出是否表示设备上没有 afc2 服务
+// 例如 "Failed to start AFC service 'com.apple.afc2' on the device."
+func afc2Missing(output string) bool {
+	lower := strings.ToLower(output)
+	return strings.Contains(lower, "failed to start afc service") ||
+		strings.Contains(lower, "invalidservice") ||
+		strings.Contains(lower, "invalid service")
+}
+
+// unmountFuse 卸载 FUSE 挂载点
+func unmountFuse(mountPoint string) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "linux" {
+		cmd = exec.Command("fusermount", "-u", mountPoint)
+	} else {
+		cmd = exec.Command("umount", mountPoint)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf("卸载 %s 失败: %v - %s\n", mountPoint, err, string(output))
+	}
+}
@@ -0,0 +1,37 @@
+package device
+
+import "testing"
+
+func TestJailbreakVerdict(t *testing.T) {
+	found := func(weight float64) JailbreakEvidence {
+		return JailbreakEvidence{Check: "installed_app", Found: true, Weight: weight}
+	}
+	notFound := JailbreakEvidence{Check: "afc2_service", Found: false, Weight: 0.95}
+
+	tests := []struct {
+		name           string
+		evidence       []JailbreakEvidence
+		checksRun      int
+		wantVerdict    string
+		wantConfidence float64
+	}{
+		{"没有完成任何检查", nil, 0, JailbreakVerdictUnknown, 0},
+		{"没有完成检查时忽略证据", []JailbreakEvidence{found(0.95)}, 0, JailbreakVerdictUnknown, 0},
+		{"只完成一项检查", []JailbreakEvidence{notFound}, 1, JailbreakVerdictNotDetected, 0.6},
+		{"完成两项检查", []JailbreakEvidence{notFound}, 2, JailbreakVerdictNotDetected, 0.9},
+		{"完成三项检查", nil, 3, JailbreakVerdictNotDetected, 0.9},
+		{"一条强证据", []JailbreakEvidence{found(0.95), notFound}, 2, JailbreakVerdictJailbroken, 0.95},
+		{"刚好达到阈值", []JailbreakEvidence{found(0.9)}, 1, JailbreakVerdictJailbroken, 0.9},
+		{"一条弱证据", []JailbreakEvidence{found(0.8)}, 2, JailbreakVerdictLikely, 0.8},
+		{"两条弱证据合并", []JailbreakEvidence{found(0.8), found(0.5)}, 2, JailbreakVerdictJailbroken, 0.9},
+		{"合并结果不被截断", []JailbreakEvidence{found(0.7), found(0.7)}, 2, JailbreakVerdictJailbroken, 0.91},
+		{"三条证据合并", []JailbreakEvidence{found(0.85), found(0.8), found(0.95)}, 3, JailbreakVerdictJailbroken, 1},
+		{"未发现的证据不参与合并", []JailbreakEvidence{found(0.5), notFound}, 2, JailbreakVerdictLikely, 0.5},
+	}
+	for _, tt := range tests {
+		verdict, confidence := jailbreakVerdict(tt.evidence, tt.checksRun)
+		if verdict != tt.wantVerdict || confidence != tt.wantConfidence {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", tt.name, verdict, confidence, tt.wantVerdict, tt.wantConfidence)
+		}
+	}
+}
@@ -29,6 +29,8 @@ func GetMockDeviceInfo(udid string) map[string]string {
 		"Serial":       "MPU93 CH/A",
 		"UDID":         "352340763085655",
 		"Capacity":     "128.0 GB",
+		"Used Space":   "70.4 GB",
+		"Free Space":   "48.8 GB",
 		"Battery":      "85%",
 		"WiFi Address": "aa:bb:cc:dd:ee:ff",
 		
@@ -87,14 +89,141 @@ func GetMockDeviceInfo(udid string) map[string]string {
 	return mockInfo
 }
 
+// GetMockStorageInfo 获取模拟存储空间信息
+func GetMockStorageInfo(udid string) StorageInfo {
+	storage := parseStorageInfo(map[string]string{
+		"TotalDiskCapacity":      "128000000000",
+		"TotalDataCapacity":      "119621484544",
+		"TotalDataAvailable":     "52381941760",
+		"TotalSystemCapacity":    "8378515456",
+		"TotalSystemAvailable":   "2146435072",
+		"PhotoUsage":             "21474836480",
+		"CameraUsage":            "3221225472",
+		"MobileApplicationUsage": "32212254720",
+		"CalendarUsage":          "52428800",
+		"NotesUsage":             "104857600",
+		"VoicemailUsage":         "0",
+		"MediaCacheUsage":        "1073741824",
+	})
+	storage.Source = "mock"
+	return storage
+}
+
+// GetMockBatteryInfo 获取模拟电池信息
+func GetMockBatteryInfo(udid string) BatteryInfo {
+	return BatteryInfo{
+		Level:                 49,
+		CurrentCapacity:       1580,
+		MaxCapacity:           3227,
+		DesignCapacity:        3227,
+		NominalChargeCapacity: 3227,
+		HealthPercent:         100,
+		CycleCount:            177,
+		Temperature:           30.5,
+		Voltage:               3850,
+		Amperage:              1200,
+		IsCharging:            true,
+		ExternalConnected:     true,
+		ExternalChargeCapable: true,
+		AdapterName:           "20W USB-C Power Adapter",
+		AdapterManufacturer:   "Apple Inc.",
+		AdapterWatts:          20,
+		Serial:                "F5D2245A1Q2PQ6WAK",
+		ManufactureDate:       "2022-09-12",
+		Source:                "mock",
+	}
+}
+
+// GetMockVerificationReport 获取模拟验机报告
+func GetMockVerificationReport(udid string) VerificationReport {
+	battery := GetMockBatteryInfo(udid)
+	report := buildVerificationReport(verificationInput{
+		udid: "00008110-001238E23E614015",
+		info: map[string]string{
+			"DeviceName":                            "iPhone",
+			"ProductType":                           "iPhone14,7",
+			"HardwareModel":                         "D27AP",
+			"ProductVersion":                        "16.0.2",
+			"SerialNumber":                          "H6JPCQ9W6W",
+			"InternationalMobileEquipmentIdentity":  "352340763085655",
+			"InternationalMobileEquipmentIdentity2": "352340763157868",
+			"UniqueChipID":                          "5129093941116949",
+			"WiFiAddress":                           "b8:14:4d:43:75:6d",
+			"BluetoothAddress":                      "b8:14:4d:43:75:6e",
+			"MLBSerialNumber":                       "F3Y2414OSY31JRQA",
+			"ModelNumber":                           "MPU93",
+			"RegionInfo":                            "CH/A",
+		},
+		platform: map[string]any{
+			"IOPlatformSerialNumber": "H6JPCQ9W6W",
+			"mlb-serial-number":      []byte("F3Y2414OSY31JRQA\x00"),
+		},
+		battery: &battery,
+		sensors: []Identifier{
+			{Key: "pearl-cam", Label: "红外摄像头", Value: "HNQ23853VSV15F7DK", Source: "ioreg"},
+			{Key: "prox", Label: "距离传感器", Value: "FWP22475B9KUQ948DL", Source: "ioreg"},
+			{Key: "vibrator", Label: "振动器", Value: "GH92345331G1FVDAR", Source: "ioreg"},
+		},
+	})
+	return report
+}
+
+// GetMockApps 获取模拟应用列表
+func GetMockApps(udid string) []AppInfo {
+	return []AppInfo{
+		{
+			BundleID:         "com.tencent.xin",
+			Name:             "微信",
+			Version:          "8.0.50",
+			Build:            "8.0.50.34",
+			Type:             AppTypeUser,
+			SignerIdentity:   "Apple iPhone OS Application Signing",
+			StaticDiskUsage:  712310784,
+			DynamicDiskUsage: 5368709120,
+			Path:             "/private/var/containers/Bundle/Application/5B1E2C3A/WeChat.app",
+			Container:        "/private/var/mobile/Containers/Data/Application/9D2F4E1B",
+			Entitlements: AppEntitlements{
+				PushNotifications: "production",
+				AppGroups:         []string{"group.com.tencent.xin"},
+				TeamID:            "88L2Q4487U",
+			},
+			MinimumOS: "12.0",
+		},
+		{
+			BundleID:         "com.example.devapp",
+			Name:             "DevApp",
+			Version:          "1.0",
+			Build:            "42",
+			Type:             AppTypeUser,
+			SignerIdentity:   "Apple Development: dev@example.com (ABCDE12345)",
+			StaticDiskUsage:  25165824,
+			DynamicDiskUsage: 1048576,
+			Entitlements: AppEntitlements{
+				Debuggable:        true,
+				PushNotifications: "development",
+				TeamID:            "ABCDE12345",
+			},
+			MinimumOS:   "15.0",
+			FileSharing: true,
+		},
+		{
+			BundleID: "com.apple.mobilesafari",
+			Name:     "Safari浏览器",
+			Version:  "16.0",
+			Build:    "8614.1.25.9.10",
+			Type:     AppTypeSystem,
+		},
+	}
+}
+
 // UseMockData 是否使用模拟数据的标志
 var UseMockData = false
 
 // ListDevicesWithMock 列出设备（支持模拟数据）
-func ListDevicesWithMock() []Device {
+func ListDevicesWithMock() ([]Device, error) {
 	if UseMockData {
 		fmt.Println("使用模拟设备数据")
-		return GetMockDevices()
+		return GetMockDevices(), nil
 	}
 	return ListDevices()
 }
@@ -106,4 +235,40 @@ func GetDeviceInfoWithMock(udid string) (map[string]string, error) {
 		return GetMockDeviceInfo(udid), nil
 	}
 	return GetDeviceInfo(udid)
-}
\ No newline at end of file
+}
+
+// GetStorageInfoWithMock 获取存储空间信息（支持模拟数据）
+func GetStorageInfoWithMock(udid string) (StorageInfo, error) {
+	if UseMockData {
+		fmt.Println("使用模拟存储空间信息")
+		return GetMockStorageInfo(udid), nil
+	}
+	return GetStorageInfo(udid)
+}
+
+// GetBatteryInfoWithMock 获取电池信息（支持模拟数据）
+func GetBatteryInfoWithMock(udid string) (BatteryInfo, error) {
+	if UseMockData {
+		fmt.Println("使用模拟电池信息")
+		return GetMockBatteryInfo(udid), nil
+	}
+	return GetBatteryInfo(udid)
+}
+
+// VerifyDeviceWithMock 生成验机报告（支持模拟数据）
+func VerifyDeviceWithMock(udid string) (VerificationReport, error) {
+	if UseMockData {
+		fmt.Println("使用模拟验机报告")
+		return GetMockVerificationReport(udid), nil
+	}
+	return VerifyDevice(udid)
+}
+
+// ListAppsWithMock 获取应用列表（支持模拟数据）
+func ListAppsWithMock(udid string, filter AppFilter) ([]AppInfo, error) {
+	if UseMockData {
+		fmt.Println("使用模拟应用列表")
+		return filterApps(GetMockApps(udid), filter), nil
+	}
+	return ListApps(udid, filter)
+}
@@ -0,0 +1,123 @@
+package device
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// modelsJSON 设备型号数据，新增机型时只需修改 data/models.json
+//
+//go:embed data/models.json
+var modelsJSON []byte
+
+// ModelInfo 机型信息
+type ModelInfo struct {
+	ProductTypes   []string `json:"product_types"`   // ProductType，例如 iPhone14,7
+	HardwareModels []string `json:"hardware_models"` // HardwareModel，例如 D27AP
+	Name           string   `json:"name"`            // 营销名称
+	SoC            string   `json:"soc"`             // 芯片
+	ReleaseDate    string   `json:"release_date"`    // 发布日期
+	Resolution     string   `json:"resolution"`      // 屏幕分辨率
+	Storage        []string `json:"storage"`         // 可选容量
+}
+
+// modelCatalog 机型目录索引
+type modelCatalog struct {
+	models          []ModelInfo
+	byProductType   map[string]int
+	byHardwareModel map[string]int
+}
+
+var (
+	catalogOnce sync.Once
+	catalog     *modelCatalog
+)
+
+// loadModelCatalog 解析内嵌的机型数据并建立索引
+// 机型数据随程序编译，格式错误属于编程错误，由 models_test.go 保证不会发布
+func loadModelCatalog() *modelCatalog {
+	catalogOnce.Do(func() {
+		c, err := parseModelCatalog(modelsJSON)
+		if err != nil {
+			panic(fmt.Sprintf("内嵌的机型数据无效: %v", err))
+		}
+		catalog = c
+	})
+	return catalog
+}
+
+// parseModelCatalog 解析并校验机型数据
+// 每个机型必须有 ProductType 和名称，ProductType 与 HardwareModel 不能重复。
+func parseModelCatalog(data []byte) (*modelCatalog, error) {
+	var file struct {
+		Models []ModelInfo `json:"models"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析机型数据失败: %v", err)
+	}
+
+	c := &modelCatalog{
+		models:          file.Models,
+		byProductType:   map[string]int{},
+		byHardwareModel: map[string]int{},
+	}
+	for i, model := range file.Models {
+		if model.Name == "" || len(model.ProductTypes) == 0 {
+			return nil, fmt.Errorf("第 %d 个机型缺少名称或 ProductType", i+1)
+		}
+		for _, productType := range model.ProductTypes {
+			key := strings.ToLower(productType)
+			if _, exists := c.byProductType[key]; exists {
+				return nil, fmt.Errorf("ProductType 重复: %s", productType)
+			}
+			c.byProductType[key] = i
+		}
+		for _, hardwareModel := range model.HardwareModels {
+			key := strings.ToLower(hardwareModel)
+			if _, exists := c.byHardwareModel[key]; exists {
+				return nil, fmt.Errorf("HardwareModel 重复: %s", hardwareModel)
+			}
+			c.byHardwareModel[key] = i
+		}
+	}
+	return c, nil
+}
+
+// LookupModel 根据 ProductType 查询机型信息
+func LookupModel(productType string) (ModelInfo, bool) {
+	c := loadModelCatalog()
+	i, ok := c.byProductType[strings.ToLower(strings.TrimSpace(productType))]
+	if !ok {
+		return ModelInfo{}, false
+	}
+	return c.models[i], true
+}
+
+// LookupModelByHardware 根据 HardwareModel 查询机型信息
+func LookupModelByHardware(hardwareModel string) (ModelInfo, bool) {
+	c := loadModelCatalog()
+	i, ok := c.byHardwareModel[strings.ToLower(strings.TrimSpace(hardwareModel))]
+	if !ok {
+		return ModelInfo{}, false
+	}
+	return c.models[i], true
+}
+
+// ListModels 返回全部机型信息
+func ListModels() []ModelInfo {
+	c := loadModelCatalog()
+	models := make([]ModelInfo, len(c.models))
+	copy(models, c.models)
+	return models
+}
+
+// marketingName 返回 ProductType 对应的营销名称，未知机型返回原始值
+func marketingName(productType string) string {
+	if model, ok := LookupModel(productType); ok {
+		return model.Name
+	}
+	return productType
+}
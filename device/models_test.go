@@ -0,0 +1,111 @@
+package device
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	productTypePattern   = regexp.MustCompile(`^(iPhone|iPad|iPod)\d+,\d+$`)
+	hardwareModelPattern = regexp.MustCompile(`^[A-Z]\d+[a-z]?AP$`)
+	resolutionPattern    = regexp.MustCompile(`^\d+ x \d+$`)
+	storagePattern       = regexp.MustCompile(`^\d+(GB|TB)$`)
+)
+
+func TestModelCatalogEntries(t *testing.T) {
+	c, err := parseModelCatalog(modelsJSON)
+	if err != nil {
+		t.Fatalf("内嵌的机型数据无效: %v", err)
+	}
+	if len(c.models) == 0 {
+		t.Fatal("机型数据为空")
+	}
+
+	names := map[string]bool{}
+	for _, model := range c.models {
+		if model.Name == "" {
+			t.Errorf("%v: 缺少名称", model.ProductTypes)
+		}
+		if names[model.Name] {
+			t.Errorf("%s: 名称重复", model.Name)
+		}
+		names[model.Name] = true
+
+		if model.SoC == "" {
+			t.Errorf("%s: 缺少芯片", model.Name)
+		}
+		if _, err := time.Parse("2006-01-02", model.ReleaseDate); err != nil {
+			t.Errorf("%s: 发布日期格式错误 %q", model.Name, model.ReleaseDate)
+		}
+		if !resolutionPattern.MatchString(model.Resolution) {
+			t.Errorf("%s: 分辨率格式错误 %q", model.Name, model.Resolution)
+		}
+		if len(model.Storage) == 0 {
+			t.Errorf("%s: 缺少容量", model.Name)
+		}
+		for _, storage := range model.Storage {
+			if !storagePattern.MatchString(storage) {
+				t.Errorf("%s: 容量格式错误 %q", model.Name, storage)
+			}
+		}
+
+		for _, productType := range model.ProductTypes {
+			if !productTypePattern.MatchString(productType) {
+				t.Errorf("%s: ProductType 格式错误 %q", model.Name, productType)
+			}
+			// 设备类型必须是 IPA 兼容性检查认识的类型，名称也应与之对应
+			family := deviceFamilyOf(productType)
+			if family == 0 {
+				t.Errorf("%s: 未知的设备类型 %q", model.Name, productType)
+			}
+			if family == DeviceFamilyIPad && !strings.HasPrefix(model.Name, "iPad") ||
+				family == DeviceFamilyIPhone && !strings.HasPrefix(model.Name, "iP") {
+				t.Errorf("%s: 名称与 ProductType %q 的设备类型不符", model.Name, productType)
+			}
+			if found, ok := LookupModel(productType); !ok || found.Name != model.Name {
+				t.Errorf("LookupModel(%q) = %q, %v", productType, found.Name, ok)
+			}
+		}
+
+		for _, hardwareModel := range model.HardwareModels {
+			if !hardwareModelPattern.MatchString(hardwareModel) {
+				t.Errorf("%s: HardwareModel 格式错误 %q", model.Name, hardwareModel)
+			}
+			if found, ok := LookupModelByHardware(hardwareModel); !ok || found.Name != model.Name {
+				t.Errorf("LookupModelByHardware(%q) = %q, %v", hardwareModel, found.Name, ok)
+			}
+		}
+
+	}
+}
+
+func TestParseModelCatalogRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"格式错误":             `{"models": [`,
+		"缺少名称":             `{"models": [{"product_types": ["iPhone14,2"]}]}`,
+		"缺少 ProductType":   `{"models": [{"name": "iPhone 13 Pro"}]}`,
+		"ProductType 重复":   `{"models": [{"name": "A", "product_types": ["iPhone14,2"]}, {"name": "B", "product_types": ["iphone14,2"]}]}`,
+		"HardwareModel 重复": `{"models": [{"name": "A", "product_types": ["iPhone14,2"], "hardware_models": ["D63AP"]}, {"name": "B", "product_types": ["iPhone14,3"], "hardware_models": ["D63AP"]}]}`,
+	}
+	for name, data := range tests {
+		if _, err := parseModelCatalog([]byte(data)); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
+
+func TestLookupModelIPhone17Family(t *testing.T) {
+	tests := map[string]string{
+		"iPhone18,1": "iPhone 17 Pro",
+		"iPhone18,2": "iPhone 17 Pro Max",
+		"iPhone18,3": "iPhone 17",
+		"iPhone18,4": "iPhone Air",
+	}
+	for productType, want := range tests {
+		if model, ok := LookupModel(productType); !ok || model.Name != want {
+			t.Errorf("LookupModel(%q) = %q, %v, want %q", productType, model.Name, ok, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// backupPasswordEnv idevicebackup2 读取备份密码的环境变量
+// 通过环境变量传递密码，避免密码出现在命令行参数中被 ps 等工具看到
+const backupPasswordEnv = "BACKUP_PASSWORD"
+
+// passwordMask 日志中替换密码的占位符
+const passwordMask = "******"
+
+// passwordCommandTimeout 交互式密码命令的最长运行时间
+const passwordCommandTimeout = 2 * time.Minute
+
+// secret 内存中的密码
+// 使用字节切片保存，用完后调用 Wipe 清零，避免密码在内存中长时间驻留
+type secret struct {
+	b []byte
+}
+
+// newSecret 创建密码对象
+func newSecret(password string) *secret {
+	return &secret{b: []byte(password)}
+}
+
+// Empty 密码是否为空
+func (s *secret) Empty() bool {
+	return s == nil || len(s.b) == 0
+}
+
+// String 实现 fmt.Stringer，防止密码被误打印到日志
+func (s *secret) String() string {
+	return passwordMask
+}
+
+// WriteLine 将密码作为一行写入交互式提示
+func (s *secret) WriteLine(w io.Writer) error {
+	line := make([]byte, 0, len(s.b)+1)
+	line = append(line, s.b...)
+	line = append(line, '\n')
+	_, err := w.Write(line)
+	wipeBytes(line)
+	return err
+}
+
+// Wipe 清零密码内容
+func (s *secret) Wipe() {
+	if s == nil {
+		return
+	}
+	wipeBytes(s.b)
+	s.b = nil
+}
+
+// wipeBytes 将字节切片清零
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// setPasswordEnv 通过环境变量把备份密码传给 idevicebackup2
+func setPasswordEnv(cmd *exec.Cmd, password *secret) {
+	env := os.Environ()
+	// 去掉继承来的同名变量，避免误用其他密码
+	filtered := env[:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, backupPasswordEnv+"=") {
+			filtered = append(filtered, kv)
+		}
+	}
+	if !password.Empty() {
+		filtered = append(filtered, backupPasswordEnv+"="+string(password.b))
+	}
+	cmd.Env = filtered
+}
+
+// maskPasswords 将文本中出现的密码替换为占位符，用于日志输出
+func maskPasswords(text string, passwords ...*secret) string {
+	for _, p := range passwords {
+		if p.Empty() {
+			continue
+		}
+		text = strings.ReplaceAll(text, string(p.b), passwordMask)
+	}
+	return text
+}
+
+// runWithPasswordPrompts 运行交互式命令，每检测到一次密码提示就依次输入一个密码
+// 提示多于提供的密码时关闭标准输入，让命令自行失败，避免一直等待。
+// 返回隐藏了密码的命令输出。
+func runWithPasswordPrompts(name string, args []string, answers ...*secret) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("创建输入管道失败: %v", err)
+	}
+
+	// 标准输出和错误输出合并读取，提示可能出现在任意一个里
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	fmt.Printf("执行命令: %s %s\n", name, strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("启动命令失败: %v", err)
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		waitErr <- err
+	}()
+
+	var output strings.Builder
+	var pending strings.Builder
+	next := 0
+	buf := make([]byte, 1024)
+	for {
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			chunk := maskPasswords(string(buf[:n]), answers...)
+			fmt.Printf("命令输出: %s", chunk)
+			output.WriteString(chunk)
+			pending.WriteString(chunk)
+
+			if isPasswordPrompt(pending.String()) {
+				pending.Reset()
+				if next < len(answers) {
+					if err := answers[next].WriteLine(stdin); err != nil {
+						fmt.Printf("输入密码失败: %v\n", err)
+					}
+					next++
+				} else {
+					stdin.Close()
+				}
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	stdin.Close()
+
+	err = <-waitErr
+	if ctx.Err() == context.DeadlineExceeded {
+		return output.String(), fmt.Errorf("命令执行超时")
+	}
+	return output.String(), err
+}
+
+// isPasswordPrompt 判断输出的最后一行是否为密码输入提示
+func isPasswordPrompt(text string) bool {
+	if idx := strings.LastIndex(text, "\n"); idx != -1 {
+		text = text[idx+1:]
+	}
+	text = strings.ToLower(strings.TrimSpace(text))
+	return strings.Contains(text, "password") && strings.HasSuffix(text, ":")
+}
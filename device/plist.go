@@ -0,0 +1,450 @@
+package device
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// parseXMLPlist 解析 XML 格式的 plist
+// dict 解析为 map[string]any，array 解析为 []any，integer 为 int64，real 为 float64，
+// true/false 为 bool，date 为 time.Time，data 为 []byte，其余为 string。
+func parseXMLPlist(data []byte) (any, error) {
+	start := bytes.Index(data, []byte("<plist"))
+	if start == -1 {
+		return nil, errors.New("不是有效的 plist 数据")
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data[start:]))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("解析 plist 失败: %v", err)
+		}
+		if el, ok := token.(xml.StartElement); ok && el.Name.Local != "plist" {
+			return decodePlistValue(decoder, el)
+		}
+	}
+}
+
+// decodePlistValue 解析一个 plist 值，el 为该值的开始标签
+func decodePlistValue(decoder *xml.Decoder, el xml.StartElement) (any, error) {
+	switch el.Name.Local {
+	case "dict":
+		dict := map[string]any{}
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := []any{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return el.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &el); err != nil && err != io.EOF {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+
+	switch el.Name.Local {
+	case "integer":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, nil
+		}
+		// 超出 int64 范围的无符号整数
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的整数: %s", text)
+		}
+		return int64(n), nil
+	case "real":
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的浮点数: %s", text)
+		}
+		return f, nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, fmt.Errorf("无效的日期: %s", text)
+		}
+		return t, nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\n' || r == '\t' || r == '\r' {
+				return -1
+			}
+			return r
+		}, text)
+		b, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 data 字段: %v", err)
+		}
+		return b, nil
+	}
+	return text, nil
+}
+
+// plistDict 从 plist 值中取出字典，不是字典时返回 nil
+func plistDict(v any, keys ...string) map[string]any {
+	for _, key := range keys {
+		dict, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = dict[key]
+	}
+	dict, _ := v.(map[string]any)
+	return dict
+}
+
+// plistString 从字典中读取字符串，数字等类型会转换为字符串
+func plistString(dict map[string]any, key string) string {
+	switch v := dict[key].(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// plistInt 从字典中读取整数
+func plistInt(dict map[string]any, key string) (int64, bool) {
+	switch v := dict[key].(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// plistBool 从字典中读取布尔值
+func plistBool(dict map[string]any, key string) bool {
+	switch v := dict[key].(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case string:
+		return v == "true" || v == "1"
+	}
+	return false
+}
+
+// plistStrings 从字典中读取字符串数组
+func plistStrings(dict map[string]any, key string) []string {
+	items, _ := dict[key].([]any)
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// parsePlist 解析 XML 或二进制格式的 plist
+func parsePlist(data []byte) (any, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+// plistEpoch 二进制 plist 中日期的起始时间
+var plistEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// binaryPlist 二进制 plist 解析状态
+type binaryPlist struct {
+	data     []byte
+	offsets  []uint64
+	refSize  int
+	visiting map[uint64]bool
+	// decoded 已解析的对象，多处引用同一对象时只解析一次，避免恶意构造的文件让解析次数指数增长
+	decoded map[uint64]any
+}
+
+// parseBinaryPlist 解析 bplist00 格式的 plist，返回值类型与 parseXMLPlist 相同
+func parseBinaryPlist(data []byte) (any, error) {
+	if len(data) < 8+32 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return nil, errors.New("不是有效的二进制 plist")
+	}
+
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 ||
+		numObjects == 0 || topObject >= numObjects ||
+		tableOffset >= uint64(len(data)) || numObjects > (uint64(len(data))-tableOffset)/uint64(offsetSize) {
+		return nil, errors.New("二进制 plist 格式错误")
+	}
+
+	p := &binaryPlist{data: data, refSize: refSize, visiting: map[uint64]bool{}, decoded: map[uint64]any{}}
+	p.offsets = make([]uint64, numObjects)
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = readSizedUint(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(topObject)
+}
+
+// readSizedUint 读取大端序的无符号整数
+func readSizedUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// object 解析第 ref 个对象，结果按 ref 缓存
+func (p *binaryPlist) object(ref uint64) (any, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("无效的对象引用: %d", ref)
+	}
+	if value, ok := p.decoded[ref]; ok {
+		return value, nil
+	}
+	if p.visiting[ref] {
+		return nil, errors.New("二进制 plist 中存在循环引用")
+	}
+	p.visiting[ref] = true
+	defer delete(p.visiting, ref)
+
+	value, err := p.decode(ref)
+	if err != nil {
+		return nil, err
+	}
+	p.decoded[ref] = value
+	return value, nil
+}
+
+// decode 解析第 ref 个对象的内容
+func (p *binaryPlist) decode(ref uint64) (any, error) {
+	offset := p.offsets[ref]
+	if offset >= uint64(len(p.data)) {
+		return nil, errors.New("二进制 plist 对象越界")
+	}
+	marker := p.data[offset]
+	kind, info := marker>>4, int(marker&0x0F)
+	pos := offset + 1
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		b, err := p.bytes(pos, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		// 8 字节及以下为有符号整数，16 字节整数只取低 8 字节
+		if len(b) > 8 {
+			b = b[len(b)-8:]
+		}
+		return int64(readSizedUint(b)), nil
+	case 0x2:
+		b, err := p.bytes(pos, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, errors.New("无效的浮点数长度")
+	case 0x3:
+		b, err := p.bytes(pos, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return plistEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	case 0x4, 0x5, 0x6:
+		count, start, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		size := count
+		if kind == 0x6 {
+			size = count * 2
+		}
+		b, err := p.bytes(start, size)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case 0x4:
+			return append([]byte(nil), b...), nil
+		case 0x5:
+			return string(b), nil
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x8:
+		b, err := p.bytes(pos, info+1)
+		if err != nil {
+			return nil, err
+		}
+		return int64(readSizedUint(b)), nil
+	case 0xA, 0xC:
+		count, start, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := p.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, 0, count)
+		for _, r := range refs {
+			value, err := p.object(r)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case 0xD:
+		count, start, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := p.refs(start, count*2)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, count)
+		for i := 0; i < count; i++ {
+			key, err := p.object(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, errors.New("字典的键不是字符串")
+			}
+			value, err := p.object(refs[count+i])
+			if err != nil {
+				return nil, err
+			}
+			dict[keyString] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("不支持的二进制 plist 类型: 0x%X", marker)
+}
+
+// length 读取对象的长度，info 为 0xF 时长度保存在其后的整数对象中
+func (p *binaryPlist) length(info int, pos uint64) (int, uint64, error) {
+	if info != 0xF {
+		return info, pos, nil
+	}
+	if pos >= uint64(len(p.data)) || p.data[pos]>>4 != 0x1 {
+		return 0, 0, errors.New("无效的对象长度")
+	}
+	size := 1 << (p.data[pos] & 0x0F)
+	b, err := p.bytes(pos+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := readSizedUint(b)
+	if n > uint64(len(p.data)) {
+		return 0, 0, errors.New("对象长度超出范围")
+	}
+	return int(n), pos + 1 + uint64(size), nil
+}
+
+// refs 读取 count 个对象引用
+func (p *binaryPlist) refs(pos uint64, count int) ([]uint64, error) {
+	b, err := p.bytes(pos, count*p.refSize)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readSizedUint(b[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
+
+// bytes 读取 size 个字节并检查越界
+func (p *binaryPlist) bytes(pos uint64, size int) ([]byte, error) {
+	if size < 0 || pos > uint64(len(p.data)) || uint64(size) > uint64(len(p.data))-pos {
+		return nil, errors.New("二进制 plist 对象越界")
+	}
+	return p.data[pos : pos+uint64(size)], nil
+}
@@ -0,0 +1,114 @@
+package device
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func loadPlistFixture(t *testing.T, name string) any {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := parsePlist(data)
+	if err != nil {
+		t.Fatalf("parsePlist(%s): %v", name, err)
+	}
+	return root
+}
+
+func TestParseIdeviceinfoPlist(t *testing.T) {
+	info := plistDict(loadPlistFixture(t, "ideviceinfo.plist"))
+	if info == nil {
+		t.Fatal("ideviceinfo.plist: 根节点不是字典")
+	}
+	if got := plistString(info, "DeviceName"); got != "张三的 iPhone & Co" {
+		t.Errorf("ideviceinfo.plist DeviceName = %q", got)
+	}
+	if got, _ := plistInt(info, "UniqueChipID"); got != 6277453109637166 {
+		t.Errorf("ideviceinfo.plist UniqueChipID = %d", got)
+	}
+	if got := info["TimeIntervalSince1970"]; got != 1718000000.5 {
+		t.Errorf("ideviceinfo.plist TimeIntervalSince1970 = %v", got)
+	}
+	if got := info["ProximitySensorCalibration"]; !bytes.Equal(got.([]byte), []byte{0, 0, 0, 0, 0x7f, 0, 0, 0, 1, 0, 0, 0, 0x41, 0, 0, 0}) {
+		t.Errorf("ideviceinfo.plist ProximitySensorCalibration = %v", got)
+	}
+	if !plistBool(info, "UseRaptorCerts") || plistBool(info, "PasswordProtected") {
+		t.Error("ideviceinfo.plist 布尔值解析错误")
+	}
+	if got := info["SupportedDeviceFamilies"]; !reflect.DeepEqual(got, []any{int64(1)}) {
+		t.Errorf("ideviceinfo.plist SupportedDeviceFamilies = %#v", got)
+	}
+}
+
+func TestParseBatteryRegistryPlist(t *testing.T) {
+	registry := plistDict(loadPlistFixture(t, "battery_ioregentry.plist"), "IORegistry")
+	if registry == nil {
+		t.Fatal("battery_ioregentry.plist: 缺少 IORegistry")
+	}
+	if got := registry["UpdateTime"]; got != time.Date(2024, 6, 10, 8, 30, 0, 0, time.UTC) {
+		t.Errorf("battery_ioregentry.plist UpdateTime = %v", got)
+	}
+
+	got := parseBatteryRegistry(registry)
+	want := BatteryInfo{
+		Level:                 79,
+		CurrentCapacity:       2456,
+		MaxCapacity:           3095,
+		DesignCapacity:        3227,
+		NominalChargeCapacity: 2945,
+		HealthPercent:         percentOf(2945, 3227),
+		CycleCount:            412,
+		Temperature:           30.45,
+		Voltage:               4120,
+		Amperage:              -523,
+		IsCharging:            true,
+		ExternalConnected:     true,
+		ExternalChargeCapable: true,
+		AdapterName:           "20W USB-C Power Adapter",
+		AdapterManufacturer:   "Apple Inc.",
+		AdapterWatts:          20,
+		AdapterDescription:    "pd charger",
+		Serial:                "F5D1234567ABCDEFG",
+		ManufactureDate:       "2021-10-04",
+	}
+	if got != want {
+		t.Errorf("battery_ioregentry.plist:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseXMLPlistEdgeCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{"前缀输出", "WARNING: something\n<plist version=\"1.0\"><string>ok</string></plist>", "ok"},
+		{"空字典", "<plist><dict/></plist>", map[string]any{}},
+		{"空数组", "<plist><array></array></plist>", []any{}},
+		{"超出 int64 的整数", "<plist><integer>18446744073709551615</integer></plist>", int64(-1)},
+		{"负数", "<plist><integer>-17</integer></plist>", int64(-17)},
+	}
+	for _, tt := range tests {
+		got, err := parseXMLPlist([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "not a plist", "<plist><integer>abc</integer></plist>", "<plist><dict><key>a</key>"} {
+		if _, err := parseXMLPlist([]byte(input)); err == nil {
+			t.Errorf("parseXMLPlist(%q) 应返回错误", input)
+		}
+	}
+}
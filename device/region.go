@@ -0,0 +1,150 @@
+package device
+
+import "strings"
+
+// 销售类型，由型号首字母决定
+const (
+	SaleTypeRetail       = "retail"       // M 开头：零售机
+	SaleTypeReplacement  = "replacement"  // N 开头：官换机
+	SaleTypeRefurbished  = "refurbished"  // F 开头：官翻机
+	SaleTypePersonalized = "personalized" // P 开头：刻字机
+	SaleTypeDemo         = "demo"         // 3 开头：演示机
+	SaleTypeUnknown      = "unknown"
+)
+
+// saleTypeNames 销售类型的中文名称
+var saleTypeNames = map[string]string{
+	SaleTypeRetail:       "零售机",
+	SaleTypeReplacement:  "官换机",
+	SaleTypeRefurbished:  "官翻机",
+	SaleTypePersonalized: "刻字机",
+	SaleTypeDemo:         "演示机",
+	SaleTypeUnknown:      "未知",
+}
+
+// regionNames RegionInfo 与销售国家/地区的对应关系
+var regionNames = map[string]string{
+	"CH/A": "中国大陆",
+	"ZA/A": "新加坡",
+	"ZP/A": "中国香港/中国澳门",
+	"TA/A": "中国台湾",
+	"LL/A": "美国",
+	"C/A":  "加拿大",
+	"CL/A": "加拿大",
+	"E/A":  "墨西哥",
+	"BR/A": "巴西",
+	"LZ/A": "智利/秘鲁/巴拉圭",
+	"LE/A": "阿根廷",
+	"J/A":  "日本",
+	"KH/A": "韩国",
+	"X/A":  "澳大利亚/新西兰",
+	"B/A":  "英国/爱尔兰",
+	"ZD/A": "欧洲（德国/法国/荷兰/比利时等）",
+	"FD/A": "奥地利/瑞士/列支敦士登",
+	"DN/A": "德国/奥地利/荷兰",
+	"T/A":  "意大利",
+	"TY/A": "意大利",
+	"Y/A":  "西班牙",
+	"PO/A": "葡萄牙",
+	"PL/A": "波兰",
+	"CZ/A": "捷克",
+	"KN/A": "丹麦/挪威",
+	"SS/A": "瑞典",
+	"RU/A": "俄罗斯",
+	"RK/A": "哈萨克斯坦",
+	"TU/A": "土耳其",
+	"AE/A": "阿联酋",
+	"AB/A": "沙特阿拉伯/阿联酋/卡塔尔",
+	"HB/A": "以色列",
+	"SO/A": "南非",
+	"HN/A": "印度",
+	"ID/A": "印度尼西亚",
+	"TH/A": "泰国",
+	"MY/A": "马来西亚",
+	"PP/A": "菲律宾",
+	"VN/A": "越南",
+}
+
+// SalesInfo 销售信息解码结果
+type SalesInfo struct {
+	ModelNumber  string `json:"model_number"`   // 型号，例如 MPU93
+	Region       string `json:"region"`         // 地区代码，例如 CH/A
+	RegionName   string `json:"region_name"`    // 地区名称
+	SaleType     string `json:"sale_type"`      // 销售类型
+	SaleTypeName string `json:"sale_type_name"` // 销售类型名称
+	SalesModel   string `json:"sales_model"`    // 完整销售型号，例如 MPU93CH/A
+}
+
+// DecodeSalesInfo 解码 ModelNumber 和 RegionInfo
+// ModelNumber 可能已经带有地区后缀（例如 MPU93CH/A），此时 regionInfo 可以为空。
+func DecodeSalesInfo(modelNumber string, regionInfo string) SalesInfo {
+	modelNumber = strings.ToUpper(strings.TrimSpace(modelNumber))
+	regionInfo = strings.ToUpper(strings.TrimSpace(regionInfo))
+
+	// 从带地区后缀的型号中拆出地区
+	if strings.Contains(modelNumber, "/") {
+		base, region := splitModelRegion(modelNumber)
+		modelNumber = base
+		if regionInfo == "" {
+			regionInfo = region
+		}
+	}
+
+	info := SalesInfo{
+		ModelNumber: modelNumber,
+		Region:      regionInfo,
+		RegionName:  regionName(regionInfo),
+		SaleType:    saleTypeOf(modelNumber),
+	}
+	info.SaleTypeName = saleTypeNames[info.SaleType]
+	info.SalesModel = modelNumber + regionInfo
+	return info
+}
+
+// RegionDisplay 返回适合显示的地区，例如 CH/A(中国大陆)
+func (s SalesInfo) RegionDisplay() string {
+	if s.Region == "" {
+		return "未知"
+	}
+	if s.RegionName == "" {
+		return s.Region
+	}
+	return s.Region + "(" + s.RegionName + ")"
+}
+
+// splitModelRegion 将 MPU93CH/A 拆分为 MPU93 和 CH/A
+// 型号固定为首字母加4位字母数字，其后为地区代码。
+func splitModelRegion(salesModel string) (string, string) {
+	if len(salesModel) <= 5 {
+		return salesModel, ""
+	}
+	return salesModel[:5], salesModel[5:]
+}
+
+// saleTypeOf 根据型号首字母判断销售类型
+func saleTypeOf(modelNumber string) string {
+	if modelNumber == "" {
+		return SaleTypeUnknown
+	}
+	switch modelNumber[0] {
+	case 'M':
+		return SaleTypeRetail
+	case 'N':
+		return SaleTypeReplacement
+	case 'F':
+		return SaleTypeRefurbished
+	case 'P':
+		return SaleTypePersonalized
+	case '3':
+		return SaleTypeDemo
+	}
+	return SaleTypeUnknown
+}
+
+// regionName 返回地区代码对应的名称，未知地区返回空字符串
+func regionName(region string) string {
+	if name, ok := regionNames[region]; ok {
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package device
+
+import "testing"
+
+func TestDecodeSalesInfo(t *testing.T) {
+	tests := []struct {
+		modelNumber string
+		regionInfo  string
+		want        SalesInfo
+	}{
+		{"MLPF2", "CH/A", SalesInfo{"MLPF2", "CH/A", "中国大陆", SaleTypeRetail, "零售机", "MLPF2CH/A"}},
+		{"NQ9R3", "LL/A", SalesInfo{"NQ9R3", "LL/A", "美国", SaleTypeReplacement, "官换机", "NQ9R3LL/A"}},
+		{"FN9Q2", "J/A", SalesInfo{"FN9Q2", "J/A", "日本", SaleTypeRefurbished, "官翻机", "FN9Q2J/A"}},
+		{"PQ2F3", "ZP/A", SalesInfo{"PQ2F3", "ZP/A", "中国香港/中国澳门", SaleTypePersonalized, "刻字机", "PQ2F3ZP/A"}},
+		{"3H123", "B/A", SalesInfo{"3H123", "B/A", "英国/爱尔兰", SaleTypeDemo, "演示机", "3H123B/A"}},
+		// 型号自带地区后缀
+		{"MPU93CH/A", "", SalesInfo{"MPU93", "CH/A", "中国大陆", SaleTypeRetail, "零售机", "MPU93CH/A"}},
+		{"MPU93ZA/A", "", SalesInfo{"MPU93", "ZA/A", "新加坡", SaleTypeRetail, "零售机", "MPU93ZA/A"}},
+		// 两者都有地区时以 RegionInfo 为准
+		{"MPU93LL/A", "CH/A", SalesInfo{"MPU93", "CH/A", "中国大陆", SaleTypeRetail, "零售机", "MPU93CH/A"}},
+		// 大小写和空白
+		{" mlpf2 ", " ch/a ", SalesInfo{"MLPF2", "CH/A", "中国大陆", SaleTypeRetail, "零售机", "MLPF2CH/A"}},
+		// 未知地区和销售类型
+		{"MLPF2", "QQ/A", SalesInfo{"MLPF2", "QQ/A", "", SaleTypeRetail, "零售机", "MLPF2QQ/A"}},
+		{"XLPF2", "CH/A", SalesInfo{"XLPF2", "CH/A", "中国大陆", SaleTypeUnknown, "未知", "XLPF2CH/A"}},
+		{"", "", SalesInfo{"", "", "", SaleTypeUnknown, "未知", ""}},
+	}
+	for _, tt := range tests {
+		if got := DecodeSalesInfo(tt.modelNumber, tt.regionInfo); got != tt.want {
+			t.Errorf("DecodeSalesInfo(%q, %q)\n got %+v\nwant %+v", tt.modelNumber, tt.regionInfo, got, tt.want)
+		}
+	}
+}
+
+func TestSalesInfoRegionDisplay(t *testing.T) {
+	tests := []struct {
+		info SalesInfo
+		want string
+	}{
+		{SalesInfo{Region: "CH/A", RegionName: "中国大陆"}, "CH/A(中国大陆)"},
+		{SalesInfo{Region: "QQ/A"}, "QQ/A"},
+		{SalesInfo{}, "未知"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.RegionDisplay(); got != tt.want {
+			t.Errorf("RegionDisplay(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}
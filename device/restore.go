@@ -0,0 +1,256 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RestoreCompatibility 备份与目标设备的兼容性检查结果
+type RestoreCompatibility struct {
+	SourceUDID        string   `json:"source_udid"`         // 备份来源设备UDID
+	TargetUDID        string   `json:"target_udid"`         // 目标设备UDID
+	SourceProductType string   `json:"source_product_type"` // 备份来源设备型号
+	TargetProductType string   `json:"target_product_type"` // 目标设备型号
+	SourceIOSVersion  string   `json:"source_ios_version"`  // 备份时的iOS版本
+	TargetIOSVersion  string   `json:"target_ios_version"`  // 目标设备的iOS版本
+	IsMigration       bool     `json:"is_migration"`        // 是否为跨设备迁移
+	IsDowngrade       bool     `json:"is_downgrade"`        // 备份iOS版本是否高于目标设备
+	Compatible        bool     `json:"compatible"`          // 是否可以恢复
+	Warnings          []string `json:"warnings"`            // 警告信息
+	Errors            []string `json:"errors"`              // 阻止恢复的问题
+}
+
+// CheckRestoreCompatibility 检查备份能否恢复到目标设备
+func CheckRestoreCompatibility(udid string, backupPath string) (RestoreCompatibility, error) {
+	result := RestoreCompatibility{
+		TargetUDID: udid,
+		Warnings:   []string{},
+		Errors:     []string{},
+	}
+
+	if !IsDeviceConnected(udid) {
+		return result, ErrDeviceNotConnected
+	}
+
+	infoPath := filepath.Join(backupPath, "Info.plist")
+	if _, err := os.Stat(infoPath); os.IsNotExist(err) {
+		return result, ErrBackupCorrupt.WithDetail("Info.plist文件不存在", err)
+	}
+
+	// 读取备份中的设备信息
+	cmd := exec.Command("plutil", "-p", infoPath)
+	output, err := cmd.Output()
+	if err != nil {
+		return result, commandError("plutil", "读取Info.plist失败", err, "")
+	}
+	outputStr := string(output)
+	result.SourceUDID = plistStringValue(outputStr, "Target Identifier")
+	if result.SourceUDID == "" {
+		result.SourceUDID = plistStringValue(outputStr, "Unique Identifier")
+	}
+	result.SourceProductType = plistStringValue(outputStr, "Product Type")
+	result.SourceIOSVersion = plistStringValue(outputStr, "Product Version")
+
+	// 读取目标设备信息
+	result.TargetProductType = getDeviceValue(udid, "ProductType")
+	result.TargetIOSVersion = getDeviceValue(udid, "ProductVersion")
+
+	result.IsMigration = result.SourceUDID != "" && !strings.EqualFold(result.SourceUDID, udid)
+	if result.IsMigration {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("此备份来自其他设备 (%s)，将迁移到当前设备", result.SourceUDID))
+	}
+
+	// 检查设备类型是否兼容
+	if result.SourceProductType != "" && result.TargetProductType != "" {
+		sourceFamily := productFamily(result.SourceProductType)
+		targetFamily := productFamily(result.TargetProductType)
+		if sourceFamily != targetFamily {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("设备类型不兼容: 备份来自 %s，目标设备为 %s", result.SourceProductType, result.TargetProductType))
+		} else if result.SourceProductType != result.TargetProductType {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("跨机型恢复: %s -> %s，部分设置可能无法恢复", result.SourceProductType, result.TargetProductType))
+		}
+	} else {
+		result.Warnings = append(result.Warnings, "无法确定设备型号，跳过型号检查")
+	}
+
+	// 检查iOS版本，备份版本高于设备版本时给出警告
+	if result.SourceIOSVersion != "" && result.TargetIOSVersion != "" {
+		if compareVersions(result.SourceIOSVersion, result.TargetIOSVersion) > 0 {
+			result.IsDowngrade = true
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("备份的iOS版本 (%s) 高于设备的iOS版本 (%s)，恢复可能失败，建议先升级设备系统", result.SourceIOSVersion, result.TargetIOSVersion))
+		}
+	} else {
+		result.Warnings = append(result.Warnings, "无法确定iOS版本，跳过版本检查")
+	}
+
+	result.Compatible = len(result.Errors) == 0
+
+	fmt.Printf("恢复兼容性检查: %+v\n", result)
+	return result, nil
+}
+
+// BackupSourceUDID 从备份的 Info.plist 读取备份来源设备的 UDID
+// 直接解析 plist，不依赖 plutil。
+func BackupSourceUDID(backupPath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(backupPath, "Info.plist"))
+	if err != nil {
+		return "", ErrBackupCorrupt.WithDetail("读取Info.plist失败", err)
+	}
+	root, err := parsePlist(data)
+	if err != nil {
+		return "", ErrBackupCorrupt.WithDetail("解析Info.plist失败", err)
+	}
+	info := plistDict(root)
+	if udid := plistString(info, "Target Identifier"); udid != "" {
+		return udid, nil
+	}
+	if udid := plistString(info, "Unique Identifier"); udid != "" {
+		return udid, nil
+	}
+	return "", ErrBackupCorrupt.WithDetail("Info.plist中缺少设备标识", nil)
+}
+
+// stageBackupForRestore 将备份暂存到以 sourceUDID 命名的目录下
+// idevicebackup2 从 <目录>/<--source 指定的UDID> 读取备份，而 CreateBackup 生成的目录名带有时间戳。
+// 优先使用符号链接，无法创建时复制一份，任何情况下都不移动原备份目录。
+// 返回暂存根目录和清理函数。
+func stageBackupForRestore(sourceUDID string, backupPath string) (string, func(), error) {
+	backupPath, err := filepath.Abs(backupPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析备份路径失败: %v", err)
+	}
+
+	// 备份目录本身已以UDID命名，无需暂存
+	if filepath.Base(backupPath) == sourceUDID {
+		return filepath.Dir(backupPath), func() {}, nil
+	}
+
+	stagingDir := filepath.Join(filepath.Dir(backupPath), fmt.Sprintf(".restore_%s_%d", sourceUDID, time.Now().Unix()))
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	stagedPath := filepath.Join(stagingDir, sourceUDID)
+	cleanup := func() {
+		if err := os.RemoveAll(stagingDir); err != nil {
+			fmt.Printf("清理暂存目录失败: %v\n", err)
+		}
+	}
+
+	// 优先使用符号链接
+	if err := os.Symlink(backupPath, stagedPath); err == nil {
+		fmt.Printf("备份已通过符号链接暂存: %s -> %s\n", stagedPath, backupPath)
+		return stagingDir, cleanup, nil
+	} else {
+		fmt.Printf("创建符号链接失败，改为复制备份: %v\n", err)
+	}
+
+	// 无法创建符号链接时（例如 Windows 无权限）复制备份，暂存目录在恢复后删除
+	if err := copyDir(backupPath, stagedPath); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("暂存备份目录失败: %v", err)
+	}
+	fmt.Printf("备份已复制到暂存目录: %s\n", stagedPath)
+	return stagingDir, cleanup, nil
+}
+
+// copyDir 递归复制目录
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// getDeviceValue 获取设备的单个属性值
+func getDeviceValue(udid string, key string) string {
+	value, err := queryDeviceValue(udid, key)
+	if err != nil {
+		fmt.Printf("获取设备属性 %s 失败: %v\n", key, err)
+		return ""
+	}
+	return value
+}
+
+// queryDeviceValue 读取设备属性，失败时返回可识别的设备错误（例如设备已锁定）
+func queryDeviceValue(udid string, key string) (string, error) {
+	cmd := exec.Command("ideviceinfo", "-u", udid, "-k", key)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", commandError("ideviceinfo", "获取设备属性 "+key+" 失败", err, stderr.String())
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// plistStringValue 从 plutil -p 的输出中提取字符串值
+func plistStringValue(output string, key string) string {
+	marker := "\"" + key + "\" => "
+	idx := strings.Index(output, marker)
+	if idx == -1 {
+		return ""
+	}
+	return extractQuotedValue(output[idx+len(marker):])
+}
+
+// productFamily 返回设备型号所属的产品系列，例如 iPhone14,2 -> iPhone
+func productFamily(productType string) string {
+	return strings.TrimRight(strings.SplitN(productType, ",", 2)[0], "0123456789")
+}
+
+// compareVersions 比较两个版本号，a>b 返回1，a<b 返回-1，相等返回0
+func compareVersions(a string, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x > y {
+			return 1
+		}
+		if x < y {
+			return -1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupSourceUDID(t *testing.T) {
+	tests := []struct {
+		name  string
+		plist string
+		want  string
+	}{
+		{"Target Identifier", `<plist><dict><key>Target Identifier</key><string>00008101-SOURCE</string><key>Unique Identifier</key><string>OTHER</string></dict></plist>`, "00008101-SOURCE"},
+		{"Unique Identifier", `<plist><dict><key>Unique Identifier</key><string>abcdef0123456789</string></dict></plist>`, "abcdef0123456789"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Info.plist"), []byte(tt.plist), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := BackupSourceUDID(dir); err != nil || got != tt.want {
+			t.Errorf("%s: BackupSourceUDID = %q, %v", tt.name, got, err)
+		}
+	}
+
+	dir := t.TempDir()
+	if _, err := BackupSourceUDID(dir); ErrorCodeOf(err) != CodeBackupCorrupt {
+		t.Errorf("缺少 Info.plist: err = %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "Info.plist"), []byte("<plist><dict/></plist>"), 0644)
+	if _, err := BackupSourceUDID(dir); ErrorCodeOf(err) != CodeBackupCorrupt {
+		t.Errorf("缺少设备标识: err = %v", err)
+	}
+}
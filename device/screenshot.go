@@ -0,0 +1,151 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/tiff"
+)
+
+// 连拍参数
+const (
+	MinScreenshotInterval = 500 * time.Millisecond
+	MaxBurstCount         = 500
+)
+
+var (
+	pngMagic  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	tiffMagic = [][]byte{{'I', 'I', 0x2a, 0x00}, {'M', 'M', 0x00, 0x2a}}
+)
+
+// Screenshot 一张设备截图
+type Screenshot struct {
+	UDID   string    `json:"udid"`
+	Time   time.Time `json:"time"`
+	Format string    `json:"format"` // 始终为 png，旧版 iOS 返回的 TIFF 会转换为 PNG
+	Data   []byte    `json:"data"`
+	Path   string    `json:"path"` // 保存后的路径，未保存时为空
+}
+
+// TakeScreenshot 通过 idevicescreenshot 截取设备屏幕
+// 需要设备已挂载开发者磁盘镜像，未挂载时返回 ErrDeveloperImageNotMounted。
+func TakeScreenshot(udid string) (Screenshot, error) {
+	tmp, err := os.CreateTemp("", "myitools-screenshot-*")
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	shot := Screenshot{UDID: udid, Time: time.Now()}
+	output, err := exec.Command("idevicescreenshot", "-u", udid, tmpPath).CombinedOutput()
+	if err != nil {
+		return shot, commandError("idevicescreenshot", "截图失败", err, string(output))
+	}
+
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return shot, fmt.Errorf("读取截图失败: %v", err)
+	}
+	switch imageFormat(data) {
+	case "png":
+	case "tiff":
+		if data, err = tiffToPNG(data); err != nil {
+			return shot, err
+		}
+	default:
+		return shot, fmt.Errorf("无法识别的截图格式: %s", strings.TrimSpace(string(output)))
+	}
+	shot.Data = data
+	shot.Format = "png"
+	return shot, nil
+}
+
+// tiffToPNG 将旧版 iOS 返回的 TIFF 截图转换为 PNG
+func tiffToPNG(data []byte) ([]byte, error) {
+	img, err := tiff.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码 TIFF 截图失败: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("转换截图为 PNG 失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveScreenshot 将截图以带时间戳的文件名保存到目录中，返回保存路径
+func SaveScreenshot(shot *Screenshot, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建截图目录失败: %v", err)
+	}
+
+	name := fmt.Sprintf("screenshot_%s_%s.%s", shortUDID(shot.UDID), shot.Time.Format("20060102_150405.000"), shot.Format)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, shot.Data, 0644); err != nil {
+		return "", fmt.Errorf("保存截图失败: %v", err)
+	}
+	shot.Path = path
+	return path, nil
+}
+
+// CaptureBurst 按固定间隔连续截图并保存到目录
+// 每张截图保存后调用 onShot；ctx 取消时停止并返回已完成的截图。
+func CaptureBurst(ctx context.Context, udid string, count int, interval time.Duration, dir string, onShot func(Screenshot)) ([]Screenshot, error) {
+	if count <= 0 || count > MaxBurstCount {
+		return nil, fmt.Errorf("连拍张数必须在 1 到 %d 之间", MaxBurstCount)
+	}
+	if interval < MinScreenshotInterval {
+		interval = MinScreenshotInterval
+	}
+
+	var shots []Screenshot
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return shots, nil
+			case <-ticker.C:
+			}
+		}
+
+		shot, err := TakeScreenshot(udid)
+		if err != nil {
+			return shots, err
+		}
+		if _, err := SaveScreenshot(&shot, dir); err != nil {
+			return shots, err
+		}
+		// 已保存到磁盘，不再保留图片数据
+		shot.Data = nil
+		shots = append(shots, shot)
+		if onShot != nil {
+			onShot(shot)
+		}
+	}
+	return shots, nil
+}
+
+// imageFormat 根据文件头判断图片格式
+func imageFormat(data []byte) string {
+	if bytes.HasPrefix(data, pngMagic) {
+		return "png"
+	}
+	for _, magic := range tiffMagic {
+		if bytes.HasPrefix(data, magic) {
+			return "tiff"
+		}
+	}
+	return ""
+}
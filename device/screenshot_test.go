@@ -0,0 +1,41 @@
+package device
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func TestTIFFScreenshotConvertedToPNG(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	src.Set(1, 2, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	if imageFormat(buf.Bytes()) != "tiff" {
+		t.Fatal("未识别为 TIFF")
+	}
+
+	data, err := tiffToPNG(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imageFormat(data) != "png" {
+		t.Fatal("转换结果不是 PNG")
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != src.Bounds() {
+		t.Errorf("尺寸 %v, want %v", img.Bounds(), src.Bounds())
+	}
+	if r, g, b, _ := img.At(1, 2).RGBA(); r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+		t.Errorf("像素 (1,2) = %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
@@ -0,0 +1,175 @@
+package device
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 序列号格式
+const (
+	SerialFormat11         = "11"         // 2010 年以前的 11 位序列号
+	SerialFormat12         = "12"         // 2010-2021 年的 12 位序列号
+	SerialFormatRandomized = "randomized" // 2021 年起的 10 位随机序列号
+	SerialFormatUnknown    = "unknown"
+)
+
+// SerialInfo 序列号解码结果
+// 随机序列号不包含任何可推算的信息，此时 Derivable 为 false，其余字段为空。
+type SerialInfo struct {
+	Serial         string `json:"serial"`          // 序列号（已转为大写）
+	Format         string `json:"format"`          // 序列号格式
+	Derivable      bool   `json:"derivable"`       // 是否可以推算出生产信息
+	FactoryCode    string `json:"factory_code"`    // 工厂代码
+	Factory        string `json:"factory"`         // 工厂名称，未知时为空
+	Year           int    `json:"year"`            // 生产年份
+	AlternateYear  int    `json:"alternate_year"`  // 年份代码每十年循环一次，可能的另一个年份，没有时为 0
+	Half           int    `json:"half"`            // 上半年为 1，下半年为 2，11 位序列号为 0
+	Week           int    `json:"week"`            // 一年中的第几周
+	ProductionDate string `json:"production_date"` // 所在周的第一天，格式 2006-01-02
+	UniqueID       string `json:"unique_id"`       // 同一周内的流水号
+	ModelCode      string `json:"model_code"`      // 机型配置代码
+	Note           string `json:"note"`            // 说明
+}
+
+// serialYearCodes 12 位序列号第 4 位的年份代码，每个年份分上下半年两个代码
+const serialYearCodes = "CDFGHJKLMNPQRSTVWXYZ"
+
+// serialWeekCodes 12 位序列号第 5 位的周代码，表示半年内的第几周
+const serialWeekCodes = "123456789CDFGHJKLMNPQRTVWXY"
+
+// serialFactories 常见的工厂代码，优先匹配两位，其次匹配一位
+var serialFactories = map[string]string{
+	"C0": "Quanta（中国）",
+	"C3": "富士康（中国深圳）",
+	"C7": "Pentragon（中国上海）",
+	"CK": "科克（爱尔兰）",
+	"DL": "富士康（中国）",
+	"DM": "富士康（中国）",
+	"DN": "富士康（中国成都）",
+	"DX": "富士康（中国）",
+	"F1": "富士康（中国郑州）",
+	"F2": "富士康（中国郑州）",
+	"F4": "富士康（中国郑州）",
+	"F7": "富士康（中国）",
+	"F9": "富士康（中国郑州）",
+	"FC": "Fountain（美国科罗拉多）",
+	"FK": "富士康（中国郑州）",
+	"G6": "和硕（中国）",
+	"GQ": "和硕（中国）",
+	"H4": "富士康（中国）",
+	"MB": "马来西亚",
+	"PT": "韩国",
+	"RM": "翻新机",
+	"RN": "墨西哥",
+	"SG": "新加坡",
+	"W8": "中国上海",
+	"YM": "富士康（中国）",
+	"1C": "中国",
+	"4H": "中国",
+	"7J": "富士康（中国）",
+	"F":  "弗里蒙特（美国）",
+}
+
+// DecodeSerial 解码苹果序列号
+// 11 位格式为 PPYWWSSSCCC，12 位格式为 PPPYWSSSCCCC（P 工厂、Y 年份、W 周、S 流水号、C 机型代码）。
+// 2021 年起的 10 位序列号是随机生成的，无法推算任何信息。
+func DecodeSerial(serial string) SerialInfo {
+	serial = strings.ToUpper(strings.TrimSpace(serial))
+	info := SerialInfo{Serial: serial, Format: SerialFormatUnknown}
+
+	switch len(serial) {
+	case 12:
+		info.Format = SerialFormat12
+		decodeSerial12(&info)
+	case 11:
+		info.Format = SerialFormat11
+		decodeSerial11(&info)
+	case 10:
+		info.Format = SerialFormatRandomized
+		info.Note = "随机序列号，无法推算生产信息"
+	default:
+		info.Note = fmt.Sprintf("无法识别的序列号长度: %d", len(serial))
+	}
+	return info
+}
+
+// decodeSerial12 解码 12 位序列号
+func decodeSerial12(info *SerialInfo) {
+	serial := info.Serial
+	info.FactoryCode = serial[:3]
+	info.Factory = serialFactory(info.FactoryCode)
+	info.UniqueID = serial[5:8]
+	info.ModelCode = serial[8:]
+
+	yearIndex := strings.IndexByte(serialYearCodes, serial[3])
+	weekIndex := strings.IndexByte(serialWeekCodes, serial[4])
+	if yearIndex == -1 || weekIndex == -1 {
+		info.Note = "年份或周代码无效"
+		return
+	}
+
+	info.Derivable = true
+	info.Year = 2010 + yearIndex/2
+	info.Half = yearIndex%2 + 1
+	info.Week = weekIndex + 1 + (info.Half-1)*26
+	// 代码表覆盖 2010-2019 年，2020 年起重新使用 C、D 等代码
+	if info.Year <= 2011 {
+		info.AlternateYear = info.Year + 10
+	}
+	info.ProductionDate = weekStart(info.Year, info.Week)
+}
+
+// decodeSerial11 解码 11 位序列号
+func decodeSerial11(info *SerialInfo) {
+	serial := info.Serial
+	info.FactoryCode = serial[:2]
+	info.Factory = serialFactory(info.FactoryCode)
+	info.UniqueID = serial[5:8]
+	info.ModelCode = serial[8:]
+
+	year, err1 := strconv.Atoi(serial[2:3])
+	week, err2 := strconv.Atoi(serial[3:5])
+	if err1 != nil || err2 != nil || week < 1 || week > 53 {
+		info.Note = "年份或周代码无效"
+		return
+	}
+
+	info.Derivable = true
+	// 11 位序列号大约使用到 2012 年，0-2 视为 2010 年代
+	info.Year = 2000 + year
+	if year <= 2 {
+		info.Year += 10
+	}
+	info.Week = week
+	info.ProductionDate = weekStart(info.Year, info.Week)
+}
+
+// serialFactory 返回工厂代码对应的名称
+func serialFactory(code string) string {
+	if name, ok := serialFactories[code[:2]]; ok {
+		return name
+	}
+	return serialFactories[code[:1]]
+}
+
+// weekStart 返回某年第 week 周的第一天
+func weekStart(year int, week int) string {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, (week-1)*7).Format("2006-01-02")
+}
+
+// ProductionDateDisplay 返回适合显示的生产日期，例如 2019年第23周(2019-06-04)
+func (s SerialInfo) ProductionDateDisplay() string {
+	if !s.Derivable {
+		if s.Format == SerialFormatRandomized {
+			return "无法推算(随机序列号)"
+		}
+		return "未知"
+	}
+	display := fmt.Sprintf("%d年第%d周(%s)", s.Year, s.Week, s.ProductionDate)
+	if s.AlternateYear != 0 {
+		display += fmt.Sprintf(" 或 %d年", s.AlternateYear)
+	}
+	return display
+}
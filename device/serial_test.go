@@ -0,0 +1,101 @@
+package device
+
+import "testing"
+
+func TestDecodeSerial(t *testing.T) {
+	tests := []struct {
+		serial string
+		want   SerialInfo
+	}{
+		// 12 位: X = 2018 下半年，K = 第 16 周，下半年加 26 周
+		{"F2LXK0ABCD12", SerialInfo{
+			Serial: "F2LXK0ABCD12", Format: SerialFormat12, Derivable: true,
+			FactoryCode: "F2L", Factory: "富士康（中国郑州）",
+			Year: 2018, Half: 2, Week: 42, ProductionDate: "2018-10-15",
+			UniqueID: "0AB", ModelCode: "CD12",
+		}},
+		// C = 2010 上半年，代码在 2020 年重新使用
+		{"DNPCJ123ABCD", SerialInfo{
+			Serial: "DNPCJ123ABCD", Format: SerialFormat12, Derivable: true,
+			FactoryCode: "DNP", Factory: "富士康（中国成都）",
+			Year: 2010, AlternateYear: 2020, Half: 1, Week: 15, ProductionDate: "2010-04-09",
+			UniqueID: "123", ModelCode: "ABCD",
+		}},
+		// G = 2011 下半年，也可能是 2021 年
+		{"C39GH456N0XY", SerialInfo{
+			Serial: "C39GH456N0XY", Format: SerialFormat12, Derivable: true,
+			FactoryCode: "C39", Factory: "富士康（中国深圳）",
+			Year: 2011, AlternateYear: 2021, Half: 2, Week: 40, ProductionDate: "2011-10-01",
+			UniqueID: "456", ModelCode: "N0XY",
+		}},
+		// H = 2012 上半年，不再有歧义
+		{"FK1H1789ABCD", SerialInfo{
+			Serial: "FK1H1789ABCD", Format: SerialFormat12, Derivable: true,
+			FactoryCode: "FK1", Factory: "富士康（中国郑州）",
+			Year: 2012, Half: 1, Week: 1, ProductionDate: "2012-01-01",
+			UniqueID: "789", ModelCode: "ABCD",
+		}},
+		// Z = 2019 下半年，Y 为半年中的最后一周
+		{"G6TZY000ABCD", SerialInfo{
+			Serial: "G6TZY000ABCD", Format: SerialFormat12, Derivable: true,
+			FactoryCode: "G6T", Factory: "和硕（中国）",
+			Year: 2019, Half: 2, Week: 53, ProductionDate: "2019-12-31",
+			UniqueID: "000", ModelCode: "ABCD",
+		}},
+		// 无效的年份代码
+		{"F2LBK0ABCD12", SerialInfo{
+			Serial: "F2LBK0ABCD12", Format: SerialFormat12,
+			FactoryCode: "F2L", Factory: "富士康（中国郑州）",
+			UniqueID: "0AB", ModelCode: "CD12", Note: "年份或周代码无效",
+		}},
+		// 11 位: 年份 8 = 2008，第 15 周
+		{"W8815ABCXYZ", SerialInfo{
+			Serial: "W8815ABCXYZ", Format: SerialFormat11, Derivable: true,
+			FactoryCode: "W8", Factory: "中国上海",
+			Year: 2008, Week: 15, ProductionDate: "2008-04-08",
+			UniqueID: "ABC", ModelCode: "XYZ",
+		}},
+		// 11 位: 年份 0-2 视为 2010 年代
+		{"ym0234567ab", SerialInfo{
+			Serial: "YM0234567AB", Format: SerialFormat11, Derivable: true,
+			FactoryCode: "YM", Factory: "富士康（中国）",
+			Year: 2010, Week: 23, ProductionDate: "2010-06-04",
+			UniqueID: "456", ModelCode: "7AB",
+		}},
+		// 11 位: 无效的周
+		{"W8860ABCXYZ", SerialInfo{
+			Serial: "W8860ABCXYZ", Format: SerialFormat11,
+			FactoryCode: "W8", Factory: "中国上海",
+			UniqueID: "ABC", ModelCode: "XYZ", Note: "年份或周代码无效",
+		}},
+		// 10 位随机序列号
+		{" h6wq2xyz7k ", SerialInfo{
+			Serial: "H6WQ2XYZ7K", Format: SerialFormatRandomized, Note: "随机序列号，无法推算生产信息",
+		}},
+		{"ABC", SerialInfo{
+			Serial: "ABC", Format: SerialFormatUnknown, Note: "无法识别的序列号长度: 3",
+		}},
+	}
+	for _, tt := range tests {
+		if got := DecodeSerial(tt.serial); got != tt.want {
+			t.Errorf("DecodeSerial(%q)\n got %+v\nwant %+v", tt.serial, got, tt.want)
+		}
+	}
+}
+
+func TestSerialProductionDateDisplay(t *testing.T) {
+	tests := []struct {
+		serial string
+		want   string
+	}{
+		{"F2LXK0ABCD12", "2018年第42周(2018-10-15)"},
+		{"DNPCJ123ABCD", "2010年第15周(2010-04-09) 或 2020年"},
+		{"H6WQ2XYZ7K", "无法推算(随机序列号)"},
+		{"F2LBK0ABCD12", "未知"},
+	}
+	for _, tt := range tests {
+		if got := DecodeSerial(tt.serial).ProductionDateDisplay(); got != tt.want {
+			t.Errorf("DecodeSerial(%q).ProductionDateDisplay() = %q, want %q", tt.serial, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package device
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StorageInfo 设备存储空间使用情况，单位均为字节
+type StorageInfo struct {
+	TotalDisk       int64 `json:"total_disk"`        // 磁盘总容量
+	DataCapacity    int64 `json:"data_capacity"`     // 数据分区容量
+	DataAvailable   int64 `json:"data_available"`    // 数据分区可用空间
+	DataUsed        int64 `json:"data_used"`         // 数据分区已用空间
+	SystemCapacity  int64 `json:"system_capacity"`   // 系统分区容量（系统占用）
+	SystemAvailable int64 `json:"system_available"`  // 系统分区可用空间
+	PhotoUsage      int64 `json:"photo_usage"`       // 照片占用
+	CameraUsage     int64 `json:"camera_usage"`      // 相机占用
+	AppUsage        int64 `json:"app_usage"`         // 应用占用
+	CalendarUsage   int64 `json:"calendar_usage"`    // 日历占用
+	NotesUsage      int64 `json:"notes_usage"`       // 备忘录占用
+	VoicemailUsage  int64 `json:"voicemail_usage"`   // 语音留言占用
+	MediaCacheUsage int64 `json:"media_cache_usage"` // 媒体缓存占用
+
+	UsedPercent   float64 `json:"used_percent"`   // 已用空间占磁盘总容量的百分比
+	SystemPercent float64 `json:"system_percent"` // 系统占磁盘总容量的百分比
+	PhotoPercent  float64 `json:"photo_percent"`  // 照片占磁盘总容量的百分比
+	AppPercent    float64 `json:"app_percent"`    // 应用占磁盘总容量的百分比
+	FreePercent   float64 `json:"free_percent"`   // 可用空间占磁盘总容量的百分比
+
+	Source string `json:"source"` // 数据来源的 lockdown 域
+}
+
+// GetStorageInfo 从 com.apple.disk_usage 域读取存储空间使用情况
+// 该域不可用时（例如部分旧系统）退回到 com.apple.disk_usage.factory。
+func GetStorageInfo(udid string) (StorageInfo, error) {
+	var storage StorageInfo
+
+	if !IsDeviceConnected(udid) {
+		return storage, ErrDeviceNotConnected
+	}
+
+	source := "com.apple.disk_usage"
+	values, err := queryDomain(udid, source)
+	if err != nil || len(values) == 0 {
+		fmt.Printf("读取 %s 失败，尝试 factory 域: %v\n", source, err)
+		source = "com.apple.disk_usage.factory"
+		values, err = queryDomain(udid, source)
+		if err != nil {
+			return storage, err
+		}
+	}
+
+	storage = parseStorageInfo(values)
+	storage.Source = source
+	return storage, nil
+}
+
+// parseStorageInfo 将 disk_usage 域的键值转换为 StorageInfo
+func parseStorageInfo(values map[string]string) StorageInfo {
+	storage := StorageInfo{
+		TotalDisk:       parseBytes(values["TotalDiskCapacity"]),
+		DataCapacity:    parseBytes(values["TotalDataCapacity"]),
+		DataAvailable:   parseBytes(values["TotalDataAvailable"]),
+		SystemCapacity:  parseBytes(values["TotalSystemCapacity"]),
+		SystemAvailable: parseBytes(values["TotalSystemAvailable"]),
+		PhotoUsage:      parseBytes(values["PhotoUsage"]),
+		CameraUsage:     parseBytes(values["CameraUsage"]),
+		AppUsage:        parseBytes(values["MobileApplicationUsage"]),
+		CalendarUsage:   parseBytes(values["CalendarUsage"]),
+		NotesUsage:      parseBytes(values["NotesUsage"]),
+		VoicemailUsage:  parseBytes(values["VoicemailUsage"]),
+		MediaCacheUsage: parseBytes(values["MediaCacheUsage"]),
+	}
+
+	// 部分系统只提供 AmountDataAvailable
+	if storage.DataAvailable == 0 {
+		storage.DataAvailable = parseBytes(values["AmountDataAvailable"])
+	}
+	if storage.DataCapacity > storage.DataAvailable {
+		storage.DataUsed = storage.DataCapacity - storage.DataAvailable
+	}
+	// 没有磁盘总容量时用各分区容量之和代替
+	if storage.TotalDisk == 0 {
+		storage.TotalDisk = storage.DataCapacity + storage.SystemCapacity
+	}
+
+	storage.UsedPercent = percentOf(storage.TotalDisk-storage.DataAvailable, storage.TotalDisk)
+	storage.SystemPercent = percentOf(storage.SystemCapacity, storage.TotalDisk)
+	storage.PhotoPercent = percentOf(storage.PhotoUsage+storage.CameraUsage, storage.TotalDisk)
+	storage.AppPercent = percentOf(storage.AppUsage, storage.TotalDisk)
+	storage.FreePercent = percentOf(storage.DataAvailable, storage.TotalDisk)
+
+	return storage
+}
+
+// parseBytes 解析字节数，无法解析时返回0
+func parseBytes(value string) int64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// percentOf 计算百分比，保留一位小数
+func percentOf(part int64, total int64) float64 {
+	if total <= 0 || part <= 0 {
+		return 0
+	}
+	return float64(part*1000/total) / 10
+}
@@ -0,0 +1,412 @@
+package device
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 日志流参数默认值
+const (
+	DefaultSyslogCapacity = 20000                  // 缓冲区保留的最大行数
+	syslogBatchSize       = 200                    // 每批推送的最大行数
+	syslogBatchInterval   = 250 * time.Millisecond // 推送间隔
+	syslogMaxEntrySize    = 16 * 1024              // 单条日志（含续行）保留的最大字节数
+)
+
+// syslogTruncatedMark 超出 syslogMaxEntrySize 的日志末尾的标记
+const syslogTruncatedMark = "\n…(已截断)"
+
+// SyslogEntry 一条设备日志
+type SyslogEntry struct {
+	Time    string `json:"time"`    // 时间，保持设备输出的格式
+	Device  string `json:"device"`  // 设备名称
+	Process string `json:"process"` // 进程名
+	Library string `json:"library"` // 子系统或库，例如 SpringBoard(FrontBoard) 中的 FrontBoard
+	PID     int    `json:"pid"`     // 进程ID
+	Level   string `json:"level"`   // 日志级别: Notice, Error, Warning, Debug 等
+	Message string `json:"message"` // 日志内容
+	Raw     string `json:"raw"`     // 原始行
+}
+
+// SyslogFilter 日志过滤条件，空值表示不过滤
+type SyslogFilter struct {
+	Process string   `json:"process"` // 进程名，不区分大小写的子串匹配
+	Levels  []string `json:"levels"`  // 日志级别
+	Pattern string   `json:"pattern"` // 正则表达式，匹配原始行
+}
+
+// compiledSyslogFilter 预处理后的过滤条件
+type compiledSyslogFilter struct {
+	process string
+	levels  map[string]bool
+	pattern *regexp.Regexp
+}
+
+// compile 检查并预处理过滤条件
+func (f SyslogFilter) compile() (*compiledSyslogFilter, error) {
+	c := &compiledSyslogFilter{process: strings.ToLower(strings.TrimSpace(f.Process))}
+	if len(f.Levels) > 0 {
+		c.levels = make(map[string]bool, len(f.Levels))
+		for _, level := range f.Levels {
+			c.levels[strings.ToLower(level)] = true
+		}
+	}
+	if f.Pattern != "" {
+		pattern, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式: %v", err)
+		}
+		c.pattern = pattern
+	}
+	return c, nil
+}
+
+// match 判断日志是否满足过滤条件
+func (c *compiledSyslogFilter) match(entry SyslogEntry) bool {
+	if c == nil {
+		return true
+	}
+	if c.process != "" && !strings.Contains(strings.ToLower(entry.Process), c.process) {
+		return false
+	}
+	if c.levels != nil && !c.levels[strings.ToLower(entry.Level)] {
+		return false
+	}
+	if c.pattern != nil && !c.pattern.MatchString(entry.Raw) {
+		return false
+	}
+	return true
+}
+
+// syslogLinePattern 匹配 idevicesyslog 的输出，例如
+// Oct 19 09:12:55 iPhone SpringBoard(FrontBoard)[58] <Notice>: message
+var syslogLinePattern = regexp.MustCompile(`^(\w{3}\s+\d+\s+\d{2}:\d{2}:\d{2}(?:\.\d+)?)\s+(\S+)\s+([^\[\s]+(?:\([^)]*\))?)\[(\d+)\]\s+<(\w+)>:\s?(.*)$`)
+
+// ansiPattern 终端颜色控制符
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ParseSyslogLine 解析一行日志，不符合格式时返回 false
+func ParseSyslogLine(line string) (SyslogEntry, bool) {
+	line = ansiPattern.ReplaceAllString(strings.TrimRight(line, "\r\n"), "")
+	match := syslogLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return SyslogEntry{Raw: line, Message: line}, false
+	}
+
+	entry := SyslogEntry{
+		Time:    match[1],
+		Device:  match[2],
+		Process: match[3],
+		Level:   match[5],
+		Message: match[6],
+		Raw:     line,
+	}
+	if i := strings.Index(entry.Process, "("); i != -1 {
+		entry.Library = strings.TrimSuffix(entry.Process[i+1:], ")")
+		entry.Process = entry.Process[:i]
+	}
+	entry.PID, _ = strconv.Atoi(match[4])
+	return entry, true
+}
+
+// SyslogStream 通过 idevicesyslog 读取设备日志
+// 全部日志保存在有界缓冲区中，满足过滤条件的日志按批回调给 OnBatch。
+// 暂停时继续接收日志但不回调，恢复后可以通过 Entries 取回暂停期间的日志。
+type SyslogStream struct {
+	UDID string
+
+	// OnBatch 每批满足过滤条件的新日志
+	OnBatch func([]SyslogEntry)
+	// OnStop 日志进程退出时调用，主动停止时 err 为 nil
+	OnStop func(err error)
+
+	mu       sync.Mutex
+	buffer   []SyslogEntry
+	next     int
+	full     bool
+	filter   *compiledSyslogFilter
+	paused   bool
+	pending  []SyslogEntry
+	cmd      *exec.Cmd
+	stopping bool
+	done     chan struct{}
+}
+
+// NewSyslogStream 创建日志流
+func NewSyslogStream(udid string, capacity int) *SyslogStream {
+	if capacity <= 0 {
+		capacity = DefaultSyslogCapacity
+	}
+	return &SyslogStream{
+		UDID:   udid,
+		buffer: make([]SyslogEntry, capacity),
+	}
+}
+
+// Start 启动 idevicesyslog
+func (s *SyslogStream) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil {
+		return fmt.Errorf("日志已在读取")
+	}
+
+	cmd := exec.Command("idevicesyslog", "-u", s.UDID)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建日志管道失败: %v", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return commandError("idevicesyslog", "启动日志读取失败", err, stderr.String())
+	}
+
+	s.cmd = cmd
+	s.stopping = false
+	s.done = make(chan struct{})
+	go s.read(cmd, stdout, &stderr, s.done)
+
+	fmt.Printf("开始读取设备 %s 的日志\n", s.UDID)
+	return nil
+}
+
+// Stop 停止读取日志
+func (s *SyslogStream) Stop() {
+	s.mu.Lock()
+	cmd, done := s.cmd, s.done
+	s.stopping = true
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return
+	}
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+	<-done
+	fmt.Printf("停止读取设备 %s 的日志\n", s.UDID)
+}
+
+// Running 是否正在读取
+func (s *SyslogStream) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd != nil
+}
+
+// Pause 暂停推送
+func (s *SyslogStream) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.pending = nil
+	s.mu.Unlock()
+}
+
+// Resume 恢复推送
+func (s *SyslogStream) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+}
+
+// Paused 是否已暂停
+func (s *SyslogStream) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// SetFilter 设置过滤条件，对之后推送的日志以及 Entries、Save 生效
+func (s *SyslogStream) SetFilter(filter SyslogFilter) error {
+	compiled, err := filter.compile()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.filter = compiled
+	s.pending = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// Entries 按时间顺序返回缓冲区中满足过滤条件的日志
+func (s *SyslogStream) Entries() []SyslogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ordered []SyslogEntry
+	if s.full {
+		ordered = append(ordered, s.buffer[s.next:]...)
+	}
+	ordered = append(ordered, s.buffer[:s.next]...)
+
+	entries := make([]SyslogEntry, 0, len(ordered))
+	for _, entry := range ordered {
+		if s.filter.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Clear 清空缓冲区
+func (s *SyslogStream) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.buffer {
+		s.buffer[i] = SyslogEntry{}
+	}
+	s.next = 0
+	s.full = false
+	s.pending = nil
+}
+
+// Save 将满足过滤条件的日志按原始格式保存到文件
+func (s *SyslogStream) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建日志文件失败: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, entry := range s.Entries() {
+		w.WriteString(entry.Raw)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("写入日志文件失败: %v", err)
+	}
+	return nil
+}
+
+// read 读取日志进程的输出
+func (s *SyslogStream) read(cmd *exec.Cmd, stdout io.Reader, stderr *strings.Builder, done chan struct{}) {
+	defer close(done)
+
+	stopFlush := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		ticker := time.NewTicker(syslogBatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopFlush:
+				s.flush()
+				return
+			case <-ticker.C:
+				s.flush()
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, ok := ParseSyslogLine(scanner.Text())
+		if entry.Raw == "" {
+			continue
+		}
+		s.append(entry, ok)
+	}
+
+	err := cmd.Wait()
+	close(stopFlush)
+	<-flushed
+
+	s.mu.Lock()
+	stopping := s.stopping
+	s.cmd = nil
+	s.done = nil
+	onStop := s.OnStop
+	s.mu.Unlock()
+
+	if stopping {
+		err = nil
+	} else if err != nil {
+		err = commandError("idevicesyslog", "日志读取中断", err, stderr.String())
+	}
+	if onStop != nil {
+		onStop(err)
+	}
+}
+
+// append 将一行日志加入缓冲区，不符合格式的行视为上一条日志的续行
+func (s *SyslogStream) append(entry SyslogEntry, parsed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := (s.next - 1 + len(s.buffer)) % len(s.buffer)
+	if !parsed && (s.next > 0 || s.full) && s.buffer[last].Raw != "" {
+		prev := &s.buffer[last]
+		// 已截断的日志丢弃后续的续行，保证缓冲区占用的内存有上限
+		if strings.HasSuffix(prev.Raw, syslogTruncatedMark) {
+			return
+		}
+		oldRaw := prev.Raw
+		prev.Message = truncateSyslogText(prev.Message + "\n" + entry.Raw)
+		prev.Raw = truncateSyslogText(prev.Raw + "\n" + entry.Raw)
+		// 上一条还在待推送队列中时同步更新
+		if n := len(s.pending); n > 0 && s.pending[n-1].Raw == oldRaw {
+			s.pending[n-1] = *prev
+		}
+		return
+	}
+
+	entry.Raw = truncateSyslogText(entry.Raw)
+	entry.Message = truncateSyslogText(entry.Message)
+	s.buffer[s.next] = entry
+	s.next = (s.next + 1) % len(s.buffer)
+	if s.next == 0 {
+		s.full = true
+	}
+
+	if !s.paused && s.filter.match(entry) {
+		s.pending = append(s.pending, entry)
+	}
+}
+
+// truncateSyslogText 将超过 syslogMaxEntrySize 的文本截断并加上标记
+func truncateSyslogText(text string) string {
+	if len(text) <= syslogMaxEntrySize {
+		return text
+	}
+	cut := syslogMaxEntrySize
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + syslogTruncatedMark
+}
+
+// flush 推送待发送的日志
+func (s *SyslogStream) flush() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	onBatch := s.OnBatch
+	s.mu.Unlock()
+
+	if onBatch == nil {
+		return
+	}
+	for len(pending) > 0 {
+		n := len(pending)
+		if n > syslogBatchSize {
+			n = syslogBatchSize
+		}
+		onBatch(pending[:n])
+		pending = pending[n:]
+	}
+}
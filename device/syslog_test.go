@@ -0,0 +1,168 @@
+package device
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSyslogContinuationLinesAreBounded(t *testing.T) {
+	s := NewSyslogStream("udid", 4)
+	entry, ok := ParseSyslogLine("Oct 19 09:12:55 iPhone SpringBoard(FrontBoard)[58] <Notice>: 开始")
+	if !ok {
+		t.Fatal("日志行解析失败")
+	}
+	s.append(entry, true)
+
+	line := strings.Repeat("续行内容", 100)
+	for i := 0; i < 1000; i++ {
+		continuation, _ := ParseSyslogLine(line)
+		s.append(continuation, false)
+	}
+
+	last := s.buffer[0]
+	for _, text := range []string{last.Raw, last.Message} {
+		if len(text) > syslogMaxEntrySize+len(syslogTruncatedMark) {
+			t.Errorf("日志长度 %d 超过上限", len(text))
+		}
+		if !strings.HasSuffix(text, syslogTruncatedMark) {
+			t.Error("截断的日志缺少标记")
+		}
+		if !utf8.ValidString(text) {
+			t.Error("截断后不是有效的 UTF-8")
+		}
+	}
+	if len(s.pending) != 1 || s.pending[0].Raw != last.Raw {
+		t.Error("待推送队列中的日志没有同步更新")
+	}
+}
+
+func TestParseSyslogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want SyslogEntry
+		ok   bool
+	}{
+		{
+			name: "带子系统",
+			line: "Oct 19 09:12:55 iPhone SpringBoard(FrontBoard)[58] <Notice>: [app<com.example.app>] 启动",
+			want: SyslogEntry{Time: "Oct 19 09:12:55", Device: "iPhone", Process: "SpringBoard", Library: "FrontBoard", PID: 58, Level: "Notice", Message: "[app<com.example.app>] 启动"},
+			ok:   true,
+		},
+		{
+			name: "没有子系统，日期补空格",
+			line: "Oct  9 09:12:55 iPhone kernel[0] <Error>: AMFI: denied",
+			want: SyslogEntry{Time: "Oct  9 09:12:55", Device: "iPhone", Process: "kernel", PID: 0, Level: "Error", Message: "AMFI: denied"},
+			ok:   true,
+		},
+		{
+			name: "子系统中有空格，时间带小数",
+			line: "Oct 19 09:12:55.123456 Zhangs-iPhone locationd(CoreLocation Protobuf)[77] <Debug>: fix",
+			want: SyslogEntry{Time: "Oct 19 09:12:55.123456", Device: "Zhangs-iPhone", Process: "locationd", Library: "CoreLocation Protobuf", PID: 77, Level: "Debug", Message: "fix"},
+			ok:   true,
+		},
+		{
+			name: "进程名带点号，消息为空",
+			line: "Oct 19 09:12:55 iPhone com.apple.WebKit.WebContent(WebKit)[1234] <Fault>:",
+			want: SyslogEntry{Time: "Oct 19 09:12:55", Device: "iPhone", Process: "com.apple.WebKit.WebContent", Library: "WebKit", PID: 1234, Level: "Fault"},
+			ok:   true,
+		},
+		{
+			name: "颜色控制符和换行",
+			line: "\x1b[0;32mOct 19 09:12:55\x1b[0m iPhone \x1b[1;36mbackboardd\x1b[0m[66] <\x1b[0;33mWarning\x1b[0m>: low\r\n",
+			want: SyslogEntry{Time: "Oct 19 09:12:55", Device: "iPhone", Process: "backboardd", PID: 66, Level: "Warning", Message: "low"},
+			ok:   true,
+		},
+		{
+			name: "续行",
+			line: "    0   CoreFoundation  0x1a2b3c __exceptionPreprocess + 164",
+			want: SyslogEntry{Message: "    0   CoreFoundation  0x1a2b3c __exceptionPreprocess + 164"},
+		},
+		{
+			name: "连接提示",
+			line: "[connected:00008101-000A1B2C3D4E001E]",
+			want: SyslogEntry{Message: "[connected:00008101-000A1B2C3D4E001E]"},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSyslogLine(tt.line)
+		tt.want.Raw = ansiPattern.ReplaceAllString(strings.TrimRight(tt.line, "\r\n"), "")
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s:\n got %+v, %v\nwant %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSyslogFilter(t *testing.T) {
+	lines := []string{
+		"Oct 19 09:12:55 iPhone SpringBoard(FrontBoard)[58] <Notice>: scene created",
+		"Oct 19 09:12:56 iPhone MyApp(CFNetwork)[901] <Error>: Task <1A2B> finished with error -1001",
+		"Oct 19 09:12:57 iPhone myappExtension[902] <Debug>: widget refresh",
+		"Oct 19 09:12:58 iPhone kernel[0] <Notice>: Sandbox: MyApp(901) deny file-read-data",
+	}
+	var entries []SyslogEntry
+	for _, line := range lines {
+		entry, ok := ParseSyslogLine(line)
+		if !ok {
+			t.Fatalf("解析失败: %q", line)
+		}
+		entries = append(entries, entry)
+	}
+
+	tests := []struct {
+		name   string
+		filter SyslogFilter
+		want   []int // 满足条件的 entries 序号
+	}{
+		{"不过滤", SyslogFilter{}, []int{0, 1, 2, 3}},
+		{"进程名不区分大小写的子串", SyslogFilter{Process: " MYAPP "}, []int{1, 2}},
+		{"进程名不匹配子系统", SyslogFilter{Process: "FrontBoard"}, nil},
+		{"级别不区分大小写", SyslogFilter{Levels: []string{"notice", "ERROR"}}, []int{0, 1, 3}},
+		{"正则匹配原始行", SyslogFilter{Pattern: `MyApp\(901\)|error -\d+`}, []int{1, 3}},
+		{"条件同时满足", SyslogFilter{Process: "myapp", Levels: []string{"Error"}, Pattern: "finished"}, []int{1}},
+		{"空级别列表不过滤", SyslogFilter{Levels: []string{}}, []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		compiled, err := tt.filter.compile()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []int
+		for i, entry := range entries {
+			if compiled.match(entry) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	var none *compiledSyslogFilter
+	if !none.match(entries[0]) {
+		t.Error("没有过滤条件时应匹配全部日志")
+	}
+	if _, err := (SyslogFilter{Pattern: "("}).compile(); err == nil {
+		t.Error("无效的正则表达式应返回错误")
+	}
+
+	// 过滤条件同样作用于缓冲区中已有的日志
+	s := NewSyslogStream("udid", 10)
+	for _, entry := range entries {
+		s.append(entry, true)
+	}
+	if err := s.SetFilter(SyslogFilter{Process: "myapp"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Entries(); len(got) != 2 || got[0].PID != 901 || got[1].PID != 902 {
+		t.Errorf("Entries = %+v", got)
+	}
+	if err := s.SetFilter(SyslogFilter{Pattern: "["}); err == nil {
+		t.Error("SetFilter 应拒绝无效的正则表达式")
+	}
+	if got := s.Entries(); len(got) != 2 {
+		t.Errorf("设置失败后应保留原过滤条件，Entries = %d 条", len(got))
+	}
+}
@@ -0,0 +1,239 @@
+package device
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 采样参数默认值
+const (
+	DefaultTelemetryInterval = 5 * time.Second
+	MinTelemetryInterval     = 1 * time.Second
+	DefaultTelemetryCapacity = 720 // 5秒间隔时约一小时
+)
+
+// TelemetrySample 一次电池遥测采样
+type TelemetrySample struct {
+	Time              time.Time `json:"time"`               // 采样时间
+	UDID              string    `json:"udid"`               // 设备UDID
+	Level             int       `json:"level"`              // 电量百分比
+	CurrentCapacity   int64     `json:"current_capacity"`   // 当前容量(mAh)
+	Temperature       float64   `json:"temperature"`        // 温度(摄氏度)
+	Voltage           int64     `json:"voltage"`            // 电压(mV)
+	Amperage          int64     `json:"amperage"`           // 电流(mA)，负数表示放电
+	Power             float64   `json:"power"`              // 功率(W)，负数表示放电
+	IsCharging        bool      `json:"is_charging"`        // 是否正在充电
+	ExternalConnected bool      `json:"external_connected"` // 是否连接外部电源
+	Error             string    `json:"error,omitempty"`    // 采样失败时的错误信息
+}
+
+// TelemetrySampler 定时采集设备电池遥测数据
+// 最近的采样保存在环形缓冲区中，可选地追加写入磁盘（每行一条 JSON）。
+type TelemetrySampler struct {
+	UDID     string
+	Interval time.Duration
+	LogPath  string // 为空时不写入磁盘
+
+	// OnSample 每次采样完成后调用，用于推送给前端
+	OnSample func(TelemetrySample)
+
+	mu      sync.Mutex
+	buffer  []TelemetrySample
+	next    int
+	full    bool
+	stop    chan struct{}
+	done    chan struct{}
+	logFile *os.File
+}
+
+// NewTelemetrySampler 创建遥测采样器
+func NewTelemetrySampler(udid string, interval time.Duration, capacity int, logPath string) *TelemetrySampler {
+	if interval < MinTelemetryInterval {
+		interval = MinTelemetryInterval
+	}
+	if capacity <= 0 {
+		capacity = DefaultTelemetryCapacity
+	}
+	return &TelemetrySampler{
+		UDID:     udid,
+		Interval: interval,
+		LogPath:  logPath,
+		buffer:   make([]TelemetrySample, capacity),
+	}
+}
+
+// Start 开始采样
+func (s *TelemetrySampler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return fmt.Errorf("采样已在运行")
+	}
+
+	if s.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(s.LogPath), 0755); err != nil {
+			return fmt.Errorf("创建遥测目录失败: %v", err)
+		}
+		f, err := os.OpenFile(s.LogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("打开遥测文件失败: %v", err)
+		}
+		s.logFile = f
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+
+	fmt.Printf("开始采集设备 %s 的遥测数据，间隔 %v\n", s.UDID, s.Interval)
+	return nil
+}
+
+// Stop 停止采样并等待正在进行的采样结束
+func (s *TelemetrySampler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+
+	s.mu.Lock()
+	if s.logFile != nil {
+		s.logFile.Close()
+		s.logFile = nil
+	}
+	s.mu.Unlock()
+
+	fmt.Printf("停止采集设备 %s 的遥测数据\n", s.UDID)
+}
+
+// Running 是否正在采样
+func (s *TelemetrySampler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop != nil
+}
+
+// Samples 按时间顺序返回缓冲区中的全部采样
+func (s *TelemetrySampler) Samples() []TelemetrySample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.full {
+		samples := make([]TelemetrySample, s.next)
+		copy(samples, s.buffer[:s.next])
+		return samples
+	}
+	samples := make([]TelemetrySample, 0, len(s.buffer))
+	samples = append(samples, s.buffer[s.next:]...)
+	samples = append(samples, s.buffer[:s.next]...)
+	return samples
+}
+
+// ExportCSV 将缓冲区中的采样导出为 CSV 文件
+func (s *TelemetrySampler) ExportCSV(path string) error {
+	return WriteTelemetryCSV(path, s.Samples())
+}
+
+// run 采样循环
+func (s *TelemetrySampler) run(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	s.sampleOnce()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.sampleOnce()
+		}
+	}
+}
+
+// sampleOnce 采集一次数据
+func (s *TelemetrySampler) sampleOnce() {
+	sample := TelemetrySample{
+		Time: time.Now(),
+		UDID: s.UDID,
+	}
+
+	registry, err := queryBatteryRegistry(s.UDID)
+	if err != nil {
+		sample.Error = err.Error()
+	} else {
+		battery := parseBatteryRegistry(registry)
+		sample.Level = battery.Level
+		sample.CurrentCapacity = battery.CurrentCapacity
+		sample.Temperature = battery.Temperature
+		sample.Voltage = battery.Voltage
+		sample.Amperage = battery.Amperage
+		sample.Power = float64(battery.Voltage*battery.Amperage) / 1e6
+		sample.IsCharging = battery.IsCharging
+		sample.ExternalConnected = battery.ExternalConnected
+	}
+
+	s.mu.Lock()
+	s.buffer[s.next] = sample
+	s.next = (s.next + 1) % len(s.buffer)
+	if s.next == 0 {
+		s.full = true
+	}
+	if s.logFile != nil {
+		if line, err := json.Marshal(sample); err == nil {
+			s.logFile.Write(append(line, '\n'))
+		}
+	}
+	onSample := s.OnSample
+	s.mu.Unlock()
+
+	if onSample != nil {
+		onSample(sample)
+	}
+}
+
+// WriteTelemetryCSV 将采样写入 CSV 文件
+func WriteTelemetryCSV(path string, samples []TelemetrySample) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建CSV文件失败: %v", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"time", "udid", "level", "current_capacity_mah", "temperature_c", "voltage_mv", "amperage_ma", "power_w", "is_charging", "external_connected", "error"})
+	for _, sample := range samples {
+		w.Write([]string{
+			sample.Time.Format(time.RFC3339),
+			sample.UDID,
+			strconv.Itoa(sample.Level),
+			strconv.FormatInt(sample.CurrentCapacity, 10),
+			strconv.FormatFloat(sample.Temperature, 'f', 2, 64),
+			strconv.FormatInt(sample.Voltage, 10),
+			strconv.FormatInt(sample.Amperage, 10),
+			strconv.FormatFloat(sample.Power, 'f', 3, 64),
+			strconv.FormatBool(sample.IsCharging),
+			strconv.FormatBool(sample.ExternalConnected),
+			sample.Error,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("写入CSV文件失败: %v", err)
+	}
+	return nil
+}
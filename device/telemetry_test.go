@@ -0,0 +1,184 @@
+package device
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"myitools/internal/testutil"
+)
+
+// fakeBatteryDiagnostics 假的 idevicediagnostics，每次调用返回的 AppleRawCurrentCapacity 依次为 1、2、3……
+func fakeBatteryDiagnostics(t *testing.T) {
+	t.Helper()
+	fixture, err := filepath.Abs(filepath.Join("testdata", "battery_ioregentry.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := filepath.Join(t.TempDir(), "count")
+	testutil.NewFakeTools(t, map[string]string{
+		"idevicediagnostics": "n=$(cat '" + counter + "' 2>/dev/null || echo 0)\nn=$((n+1))\necho $n > '" + counter + "'\n" +
+			"sed \"s|<integer>2456</integer>|<integer>$n</integer>|\" '" + fixture + "'\n",
+	})
+}
+
+func sampleCapacities(samples []TelemetrySample) []int64 {
+	capacities := make([]int64, len(samples))
+	for i, sample := range samples {
+		capacities[i] = sample.CurrentCapacity
+	}
+	return capacities
+}
+
+func TestNewTelemetrySamplerDefaults(t *testing.T) {
+	s := NewTelemetrySampler("udid", 10*time.Millisecond, 0, "")
+	if s.Interval != MinTelemetryInterval {
+		t.Errorf("Interval = %v, want %v", s.Interval, MinTelemetryInterval)
+	}
+	if len(s.buffer) != DefaultTelemetryCapacity {
+		t.Errorf("容量 = %d, want %d", len(s.buffer), DefaultTelemetryCapacity)
+	}
+	if got := s.Samples(); len(got) != 0 {
+		t.Errorf("新采样器的采样 = %v", got)
+	}
+}
+
+func TestTelemetrySamplerRingBuffer(t *testing.T) {
+	fakeBatteryDiagnostics(t)
+	s := NewTelemetrySampler("test-udid", time.Second, 3, "")
+
+	want := [][]int64{
+		{1},
+		{1, 2},
+		{1, 2, 3}, // 刚好写满
+		{2, 3, 4}, // 覆盖最早的采样
+		{3, 4, 5},
+		{4, 5, 6},
+		{5, 6, 7}, // 第二次回绕
+	}
+	for i, w := range want {
+		s.sampleOnce()
+		samples := s.Samples()
+		if got := sampleCapacities(samples); !reflect.DeepEqual(got, w) {
+			t.Fatalf("第 %d 次采样后 = %v, want %v", i+1, got, w)
+		}
+		for j := 1; j < len(samples); j++ {
+			if samples[j].Time.Before(samples[j-1].Time) {
+				t.Errorf("第 %d 次采样后顺序错误: %v", i+1, samples)
+			}
+		}
+	}
+
+	last := s.Samples()[2]
+	if last.UDID != "test-udid" || last.Level != 79 || last.Voltage != 4120 || last.Amperage != -523 || !last.IsCharging || last.Error != "" {
+		t.Errorf("采样内容 = %+v", last)
+	}
+	if want := float64(4120*-523) / 1e6; last.Power != want {
+		t.Errorf("Power = %v, want %v", last.Power, want)
+	}
+}
+
+func TestTelemetrySamplerRecordsErrors(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{
+		"idevicediagnostics": "echo 'ERROR: Could not connect to lockdownd' >&2\nexit 1\n",
+	})
+	s := NewTelemetrySampler("test-udid", time.Second, 2, "")
+	s.sampleOnce()
+	if samples := s.Samples(); len(samples) != 1 || samples[0].Error == "" {
+		t.Errorf("采样失败时应记录错误: %+v", samples)
+	}
+}
+
+func TestTelemetrySamplerWritesJSONL(t *testing.T) {
+	fakeBatteryDiagnostics(t)
+	logPath := filepath.Join(t.TempDir(), "telemetry", "test-udid.jsonl")
+	s := NewTelemetrySampler("test-udid", time.Minute, 10, logPath)
+	sampled := make(chan TelemetrySample, 10)
+	s.OnSample = func(sample TelemetrySample) { sampled <- sample }
+
+	// 每次启动立即采样一次，重新启动时追加写入
+	for run := 0; run < 2; run++ {
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(); err == nil {
+			t.Error("重复启动应返回错误")
+		}
+		select {
+		case <-sampled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("启动后没有采样")
+		}
+		s.Stop()
+		if s.Running() {
+			t.Error("停止后仍在运行")
+		}
+	}
+	s.Stop()
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var logged []TelemetrySample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample TelemetrySample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			t.Fatalf("日志行 %q: %v", scanner.Text(), err)
+		}
+		logged = append(logged, sample)
+	}
+	if got := sampleCapacities(logged); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("日志中的采样 = %v", got)
+	}
+	if !reflect.DeepEqual(sampleCapacities(s.Samples()), []int64{1, 2}) {
+		t.Errorf("缓冲区中的采样 = %v", sampleCapacities(s.Samples()))
+	}
+	if logged[0].UDID != "test-udid" || logged[0].Time.IsZero() {
+		t.Errorf("日志内容 = %+v", logged[0])
+	}
+}
+
+func TestWriteTelemetryCSV(t *testing.T) {
+	at := time.Date(2024, 6, 10, 8, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	samples := []TelemetrySample{
+		{Time: at, UDID: "udid-1", Level: 79, CurrentCapacity: 2456, Temperature: 30.456, Voltage: 4120, Amperage: -523, Power: -2.15476, IsCharging: false, ExternalConnected: true},
+		{Time: at.Add(5 * time.Second), UDID: "udid-1", Error: "读取失败: \"lockdownd\", code -3\n重试"},
+	}
+	path := filepath.Join(t.TempDir(), "telemetry.csv")
+	if err := WriteTelemetryCSV(path, samples); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"time", "udid", "level", "current_capacity_mah", "temperature_c", "voltage_mv", "amperage_ma", "power_w", "is_charging", "external_connected", "error"},
+		{"2024-06-10T08:30:00+08:00", "udid-1", "79", "2456", "30.46", "4120", "-523", "-2.155", "false", "true", ""},
+		{"2024-06-10T08:30:05+08:00", "udid-1", "0", "0", "0.00", "0", "0", "0.000", "false", "false", "读取失败: \"lockdownd\", code -3\n重试"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV 内容:\n got %q\nwant %q", records, want)
+	}
+	if !strings.Contains(string(data), `"读取失败: ""lockdownd"", code -3`) {
+		t.Errorf("含逗号和引号的字段应加引号:\n%s", data)
+	}
+
+	if err := WriteTelemetryCSV(filepath.Join(t.TempDir(), "missing", "telemetry.csv"), samples); err == nil {
+		t.Error("目录不存在时应返回错误")
+	}
+}
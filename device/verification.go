@@ -0,0 +1,509 @@
+package device
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 校验项结果
+const (
+	CheckPass        = "pass"        // 一致
+	CheckWarning     = "warning"     // 可能存在问题，需要人工确认
+	CheckMismatch    = "mismatch"    // 不一致
+	CheckUnavailable = "unavailable" // 无法读取，不能判断
+)
+
+// Identifier 设备上读取到的一个标识
+type Identifier struct {
+	Key    string `json:"key"`    // 标识名，例如 SerialNumber
+	Label  string `json:"label"`  // 显示名称
+	Value  string `json:"value"`  // 值，读取不到时为空
+	Source string `json:"source"` // 来源: lockdown, ioreg, battery
+}
+
+// VerificationCheck 一项一致性校验
+type VerificationCheck struct {
+	Name     string `json:"name"`     // 校验项名称
+	Status   string `json:"status"`   // 校验结果
+	Expected string `json:"expected"` // 期望值
+	Actual   string `json:"actual"`   // 实际值
+	Detail   string `json:"detail"`   // 说明
+	Critical bool   `json:"critical"` // 是否计入五码匹配
+}
+
+// VerificationReport 设备验机报告
+type VerificationReport struct {
+	UDID        string              `json:"udid"`
+	DeviceName  string              `json:"device_name"`
+	Model       string              `json:"model"`
+	ProductType string              `json:"product_type"`
+	IOSVersion  string              `json:"ios_version"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Identifiers []Identifier        `json:"identifiers"`
+	Checks      []VerificationCheck `json:"checks"`
+	Matched     bool                `json:"matched"`  // 五码匹配：全部关键校验通过
+	Warnings    int                 `json:"warnings"` // 需要人工确认的项数
+	Summary     string              `json:"summary"`
+}
+
+// verificationInput 生成报告所需的原始数据
+type verificationInput struct {
+	udid     string
+	info     map[string]string // GetDeviceInfo 返回的信息，包含 lockdown 原始键
+	platform map[string]any    // IOPlatformExpertDevice
+	battery  *BatteryInfo      // 电池信息，读取失败时为 nil
+	sensors  []Identifier      // ioreg 中的摄像头、传感器序列号
+}
+
+// sensorNodePatterns 需要读取序列号的 ioreg 节点名称关键字及显示名称
+// 关键字只在名称边界上匹配，例如 als 匹配 als、als@0，不匹配 ballast。
+var sensorNodePatterns = []struct {
+	pattern string
+	label   string
+}{
+	{"rear-camera", "后置摄像头"},
+	{"back-camera", "后置摄像头"},
+	{"front-camera", "前置摄像头"},
+	{"pearl", "红外摄像头"},
+	{"jasper", "红外摄像头"},
+	{"prox", "距离传感器"},
+	{"als", "环境光传感器"},
+	{"vibrator", "振动器"},
+	{"haptic", "振动器"},
+}
+
+// sensorSerialKeys ioreg 节点中可能保存序列号的属性
+var sensorSerialKeys = []string{"SerialNumber", "serial-number", "ModuleSerialNumber", "ModuleSN", "SensorSerialNumber"}
+
+// VerifyDevice 生成设备验机报告
+// 收集 lockdown 和 ioreg 中的各类标识并交叉校验，读取不到的项标记为无法判断而不是报错。
+func VerifyDevice(udid string) (VerificationReport, error) {
+	// GetDeviceInfo 已经读取过电池信息，直接复用
+	info, battery, err := getDeviceInfo(udid)
+	if err != nil {
+		return VerificationReport{}, err
+	}
+
+	input := verificationInput{udid: udid, info: info, battery: battery}
+
+	if platform, err := queryIoregEntry(udid, "IOPlatformExpertDevice"); err == nil {
+		input.platform = platform
+	} else {
+		fmt.Printf("读取 IOPlatformExpertDevice 失败: %v\n", err)
+	}
+
+	if sensors, err := querySensorSerials(udid); err == nil {
+		input.sensors = sensors
+	} else {
+		fmt.Printf("读取传感器序列号失败: %v\n", err)
+	}
+
+	return buildVerificationReport(input), nil
+}
+
+// buildVerificationReport 根据原始数据生成报告
+func buildVerificationReport(in verificationInput) VerificationReport {
+	info := in.info
+	report := VerificationReport{
+		UDID:        in.udid,
+		DeviceName:  info["DeviceName"],
+		Model:       marketingName(info["ProductType"]),
+		ProductType: info["ProductType"],
+		IOSVersion:  info["ProductVersion"],
+		GeneratedAt: time.Now(),
+	}
+
+	lockdown := func(key, label string) {
+		report.Identifiers = append(report.Identifiers, Identifier{Key: key, Label: label, Value: info[key], Source: "lockdown"})
+	}
+	lockdown("SerialNumber", "序列号")
+	lockdown("InternationalMobileEquipmentIdentity", "IMEI")
+	lockdown("InternationalMobileEquipmentIdentity2", "IMEI2")
+	lockdown("MobileEquipmentIdentifier", "MEID")
+	lockdown("UniqueChipID", "ECID")
+	lockdown("WiFiAddress", "WiFi地址")
+	lockdown("BluetoothAddress", "蓝牙地址")
+	lockdown("MLBSerialNumber", "主板序列号")
+	lockdown("ModelNumber", "销售型号")
+	lockdown("RegionInfo", "销售地区")
+
+	platformSerial := plistDataString(in.platform, "IOPlatformSerialNumber", "serial-number")
+	platformMLB := plistDataString(in.platform, "mlb-serial-number")
+	batterySerial := ""
+	if in.battery != nil {
+		batterySerial = in.battery.Serial
+	}
+	report.Identifiers = append(report.Identifiers,
+		Identifier{Key: "IOPlatformSerialNumber", Label: "主板记录的序列号", Value: platformSerial, Source: "ioreg"},
+		Identifier{Key: "mlb-serial-number", Label: "主板记录的主板序列号", Value: platformMLB, Source: "ioreg"},
+		Identifier{Key: "BatterySerial", Label: "电池序列号", Value: batterySerial, Source: "battery"},
+	)
+	report.Identifiers = append(report.Identifiers, in.sensors...)
+
+	report.Checks = []VerificationCheck{
+		compareCheck("序列号与主板记录一致", info["SerialNumber"], platformSerial, true),
+		compareCheck("主板序列号与主板记录一致", info["MLBSerialNumber"], platformMLB, true),
+		checkECID(in.udid, info["UniqueChipID"]),
+		checkIMEI("IMEI 校验位", info["InternationalMobileEquipmentIdentity"]),
+		checkIMEI("IMEI2 校验位", info["InternationalMobileEquipmentIdentity2"]),
+		checkMEID(info["MobileEquipmentIdentifier"], info["InternationalMobileEquipmentIdentity"]),
+		checkModel(info["ProductType"], info["HardwareModel"]),
+		checkAddresses(info["WiFiAddress"], info["BluetoothAddress"]),
+		checkBatterySerial(in.battery),
+	}
+
+	report.Matched = true
+	for _, check := range report.Checks {
+		switch {
+		case check.Critical && check.Status == CheckMismatch:
+			report.Matched = false
+		case check.Status == CheckWarning:
+			report.Warnings++
+		}
+	}
+
+	switch {
+	case !report.Matched:
+		report.Summary = "五码不匹配，设备可能更换过主板或部件"
+	case report.Warnings > 0:
+		report.Summary = fmt.Sprintf("五码匹配，另有 %d 项需要人工确认", report.Warnings)
+	default:
+		report.Summary = "五码匹配"
+	}
+	return report
+}
+
+// compareCheck 比较两个来源的同一标识
+func compareCheck(name string, expected string, actual string, critical bool) VerificationCheck {
+	check := VerificationCheck{Name: name, Expected: expected, Actual: actual, Critical: critical}
+	switch {
+	case expected == "" || actual == "":
+		check.Status = CheckUnavailable
+		check.Detail = "无法读取其中一项"
+	case strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(actual)):
+		check.Status = CheckPass
+	default:
+		check.Status = CheckMismatch
+		check.Detail = "两处记录的值不同"
+	}
+	return check
+}
+
+// checkECID 校验 ECID 与 UDID
+// 2018 年以后的设备 UDID 格式为 芯片ID-ECID，ECID 部分应与 UniqueChipID 一致。
+func checkECID(udid string, uniqueChipID string) VerificationCheck {
+	check := VerificationCheck{Name: "ECID 与 UDID 一致", Critical: true}
+	ecid, err := strconv.ParseUint(uniqueChipID, 10, 64)
+	if err != nil {
+		check.Status = CheckUnavailable
+		check.Detail = "无法读取 ECID"
+		return check
+	}
+	check.Expected = fmt.Sprintf("%X", ecid)
+
+	parts := strings.Split(udid, "-")
+	if len(parts) != 2 || len(udid) != 25 {
+		check.Status = CheckUnavailable
+		check.Detail = "旧格式 UDID 不包含 ECID"
+		return check
+	}
+	udidECID, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		check.Status = CheckUnavailable
+		check.Detail = "无法解析 UDID"
+		return check
+	}
+	check.Actual = fmt.Sprintf("%X", udidECID)
+	if udidECID == ecid {
+		check.Status = CheckPass
+	} else {
+		check.Status = CheckMismatch
+		check.Detail = "UDID 中的 ECID 与芯片记录不同"
+	}
+	return check
+}
+
+// checkIMEI 使用 Luhn 算法校验 IMEI
+func checkIMEI(name string, imei string) VerificationCheck {
+	check := VerificationCheck{Name: name, Actual: imei, Critical: true}
+	imei = strings.ReplaceAll(imei, " ", "")
+	switch {
+	case imei == "":
+		check.Status = CheckUnavailable
+		check.Detail = "设备没有该 IMEI"
+	case len(imei) != 15 || !luhnValid(imei):
+		check.Status = CheckMismatch
+		check.Detail = "IMEI 校验位错误"
+	default:
+		check.Status = CheckPass
+	}
+	return check
+}
+
+// checkMEID 校验 MEID，iPhone 的 MEID 为 IMEI 的前 14 位
+func checkMEID(meid string, imei string) VerificationCheck {
+	check := VerificationCheck{Name: "MEID 与 IMEI 一致", Actual: meid, Critical: true}
+	if meid == "" || len(imei) < 14 {
+		check.Status = CheckUnavailable
+		check.Detail = "设备没有 MEID"
+		return check
+	}
+	check.Expected = imei[:14]
+	if strings.EqualFold(meid, imei[:14]) {
+		check.Status = CheckPass
+	} else {
+		check.Status = CheckMismatch
+		check.Detail = "MEID 与 IMEI 不对应"
+	}
+	return check
+}
+
+// checkModel 校验 ProductType 与 HardwareModel 是否属于同一机型
+func checkModel(productType string, hardwareModel string) VerificationCheck {
+	check := VerificationCheck{Name: "产品类型与主板型号一致", Expected: productType, Actual: hardwareModel, Critical: true}
+	byType, okType := LookupModel(productType)
+	byHardware, okHardware := LookupModelByHardware(hardwareModel)
+	switch {
+	case !okType || !okHardware:
+		check.Status = CheckUnavailable
+		check.Detail = "机型数据库中没有该机型"
+	case byType.Name == byHardware.Name:
+		check.Status = CheckPass
+	default:
+		check.Status = CheckMismatch
+		check.Detail = fmt.Sprintf("产品类型为 %s，主板型号属于 %s", byType.Name, byHardware.Name)
+	}
+	return check
+}
+
+// checkAddresses 校验 WiFi 与蓝牙地址
+// 原装设备的蓝牙地址通常为 WiFi 地址加一，不符合时只提示人工确认。
+func checkAddresses(wifi string, bluetooth string) VerificationCheck {
+	check := VerificationCheck{Name: "WiFi 与蓝牙地址相邻", Expected: wifi, Actual: bluetooth}
+	w, errW := parseMAC(wifi)
+	b, errB := parseMAC(bluetooth)
+	switch {
+	case errW != nil || errB != nil:
+		check.Status = CheckUnavailable
+		check.Detail = "无法读取地址"
+	case b == w+1:
+		check.Status = CheckPass
+	default:
+		check.Status = CheckWarning
+		check.Detail = "地址不相邻，可能更换过无线模块，也可能是该机型的正常情况"
+	}
+	return check
+}
+
+// checkBatterySerial 检查电池序列号，ioreg 中读取不到时可能是非原装电池
+// lockdown 电池域本身不提供序列号，此时无法判断。
+func checkBatterySerial(battery *BatteryInfo) VerificationCheck {
+	check := VerificationCheck{Name: "电池序列号校验"}
+	switch {
+	case battery == nil:
+		check.Status = CheckUnavailable
+		check.Detail = "无法读取电池信息"
+	case battery.Source == "lockdown":
+		check.Status = CheckUnavailable
+		check.Detail = "无法读取电池的 ioreg 信息，lockdown 不提供电池序列号"
+	case battery.Serial == "":
+		check.Status = CheckWarning
+		check.Detail = "读取不到电池序列号，电池可能不是原装"
+	default:
+		check.Actual = battery.Serial
+		check.Status = CheckPass
+	}
+	return check
+}
+
+// containsWord 判断 name 中是否有前后都不是字母的 word
+func containsWord(name string, word string) bool {
+	for start := 0; ; {
+		i := strings.Index(name[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		if (i == 0 || !isASCIILetter(name[i-1])) && (end == len(name) || !isASCIILetter(name[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// isASCIILetter 判断是否为英文字母
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// luhnValid Luhn 校验
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// parseMAC 将 aa:bb:cc:dd:ee:ff 格式的地址转换为整数
+func parseMAC(addr string) (uint64, error) {
+	hex := strings.ReplaceAll(strings.TrimSpace(addr), ":", "")
+	if len(hex) != 12 {
+		return 0, fmt.Errorf("无效的地址: %s", addr)
+	}
+	return strconv.ParseUint(hex, 16, 64)
+}
+
+// plistDataString 读取 ioreg 中的字符串属性，data 类型会去掉结尾的 0 字节
+func plistDataString(dict map[string]any, keys ...string) string {
+	for _, key := range keys {
+		switch v := dict[key].(type) {
+		case string:
+			if v != "" {
+				return strings.TrimSpace(v)
+			}
+		case []byte:
+			if s := strings.TrimSpace(string(bytes.TrimRight(v, "\x00"))); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// querySensorSerials 在 IODeviceTree 中查找摄像头、传感器的序列号
+func querySensorSerials(udid string) ([]Identifier, error) {
+	cmd := exec.Command("idevicediagnostics", "-u", udid, "ioreg", "IODeviceTree")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("idevicediagnostics", "读取 IODeviceTree 失败", err, stderr.String())
+	}
+	root, err := parseXMLPlist(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []Identifier
+	var walk func(node map[string]any)
+	walk = func(node map[string]any) {
+		name := strings.ToLower(plistDataString(node, "IORegistryEntryName", "name"))
+		for _, p := range sensorNodePatterns {
+			if !containsWord(name, p.pattern) {
+				continue
+			}
+			if serial := plistDataString(node, sensorSerialKeys...); serial != "" {
+				sensors = append(sensors, Identifier{Key: name, Label: p.label, Value: serial, Source: "ioreg"})
+			}
+			break
+		}
+		children, _ := node["IORegistryEntryChildren"].([]any)
+		for _, child := range children {
+			if dict, ok := child.(map[string]any); ok {
+				walk(dict)
+			}
+		}
+	}
+	if dict := plistDict(root, "IORegistry"); dict != nil {
+		walk(dict)
+	} else if dict := plistDict(root); dict != nil {
+		walk(dict)
+	}
+	return sensors, nil
+}
+
+// WriteJSON 将报告写为 JSON
+func (r VerificationReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteHTML 将报告写为可直接打印的 HTML
+func (r VerificationReport) WriteHTML(w io.Writer) error {
+	return verificationTemplate.Execute(w, r)
+}
+
+var checkStatusNames = map[string]string{
+	CheckPass:        "一致",
+	CheckWarning:     "需确认",
+	CheckMismatch:    "不一致",
+	CheckUnavailable: "无法判断",
+}
+
+var verificationTemplate = template.Must(template.New("verification").Funcs(template.FuncMap{
+	"statusName": func(status string) string { return checkStatusNames[status] },
+	"orUnknown": func(value string) string {
+		if value == "" {
+			return "未知"
+		}
+		return value
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>验机报告 - {{.DeviceName}}</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; margin: 32px; color: #222; }
+header { display: flex; gap: 24px; align-items: center; margin-bottom: 24px; }
+.photo { width: 120px; height: 160px; border: 1px dashed #aaa; border-radius: 12px; display: flex; align-items: center; justify-content: center; color: #999; font-size: 12px; }
+.summary { font-size: 18px; font-weight: bold; }
+.matched { color: #1a7f37; }
+.unmatched { color: #cf222e; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border: 1px solid #ddd; padding: 6px 10px; text-align: left; font-size: 13px; }
+th { background: #f6f8fa; }
+.pass { color: #1a7f37; }
+.warning { color: #9a6700; }
+.mismatch { color: #cf222e; font-weight: bold; }
+.unavailable { color: #888; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<header>
+<div class="photo">设备照片</div>
+<div>
+<h1>{{.DeviceName}}</h1>
+<div>{{.Model}} ({{.ProductType}}) · iOS {{.IOSVersion}}</div>
+<div>UDID: {{.UDID}}</div>
+<div>生成时间: {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</div>
+<p class="summary {{if .Matched}}matched{{else}}unmatched{{end}}">{{.Summary}}</p>
+</div>
+</header>
+<h2>校验结果</h2>
+<table>
+<tr><th>校验项</th><th>结果</th><th>期望值</th><th>实际值</th><th>说明</th></tr>
+{{range .Checks}}<tr><td>{{.Name}}</td><td class="{{.Status}}">{{statusName .Status}}</td><td>{{.Expected}}</td><td>{{.Actual}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+<h2>设备标识</h2>
+<table>
+<tr><th>名称</th><th>值</th><th>来源</th></tr>
+{{range .Identifiers}}<tr><td>{{.Label}}</td><td>{{orUnknown .Value}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
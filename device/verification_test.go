@@ -0,0 +1,197 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+
+	"myitools/internal/testutil"
+)
+
+func TestCheckBatterySerial(t *testing.T) {
+	tests := []struct {
+		name    string
+		battery *BatteryInfo
+		want    string
+	}{
+		{"读取失败", nil, CheckUnavailable},
+		{"lockdown 电池域", &BatteryInfo{Source: "lockdown"}, CheckUnavailable},
+		{"ioreg 无序列号", &BatteryInfo{Source: "ioreg"}, CheckWarning},
+		{"ioreg 有序列号", &BatteryInfo{Source: "ioreg", Serial: "F5D2245A1Q2PQ6WAK"}, CheckPass},
+	}
+	for _, tt := range tests {
+		if got := checkBatterySerial(tt.battery).Status; got != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 导出报告时校验项和标识放在同一节中，名称相同会互相覆盖
+func TestVerificationNamesDoNotCollide(t *testing.T) {
+	report := GetMockVerificationReport("")
+	labels := map[string]bool{}
+	for _, id := range report.Identifiers {
+		labels[id.Label] = true
+	}
+	for _, check := range report.Checks {
+		if labels[check.Name] {
+			t.Errorf("校验项 %q 与标识名称相同", check.Name)
+		}
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := map[string]bool{
+		"490154203237518": true,
+		"356938035643809": true,
+		"79927398713":     true,
+		"0":               true,
+		"490154203237519": false,
+		"356938035643808": false,
+		"79927398710":     false,
+		"49015420323751A": false,
+		"4901542032 3751": false,
+	}
+	for number, want := range tests {
+		if got := luhnValid(number); got != want {
+			t.Errorf("luhnValid(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestCheckIMEI(t *testing.T) {
+	tests := []struct {
+		imei string
+		want string
+	}{
+		{"490154203237518", CheckPass},
+		{"49 015420 323751 8", CheckPass},
+		{"490154203237519", CheckMismatch},
+		{"49015420323751", CheckMismatch},
+		{"", CheckUnavailable},
+	}
+	for _, tt := range tests {
+		if got := checkIMEI("IMEI", tt.imei).Status; got != tt.want {
+			t.Errorf("checkIMEI(%q) = %s, want %s", tt.imei, got, tt.want)
+		}
+	}
+}
+
+func TestCheckECID(t *testing.T) {
+	// 0x000A1B2C3D4E001E
+	const ecid = "2844626588139550"
+	tests := []struct {
+		name         string
+		udid         string
+		uniqueChipID string
+		want         string
+	}{
+		{"一致", "00008101-000A1B2C3D4E001E", ecid, CheckPass},
+		{"UDID 小写", "00008101-000a1b2c3d4e001e", ecid, CheckPass},
+		{"不一致", "00008101-000A1B2C3D4E001F", ecid, CheckMismatch},
+		{"旧格式 UDID", "2b6f0cc904d137be2e1730235f5664094b831186", ecid, CheckUnavailable},
+		{"UDID 无法解析", "00008101-000A1B2C3D4EXYZW", ecid, CheckUnavailable},
+		{"没有 ECID", "00008101-000A1B2C3D4E001E", "", CheckUnavailable},
+	}
+	for _, tt := range tests {
+		check := checkECID(tt.udid, tt.uniqueChipID)
+		if check.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s (%+v)", tt.name, check.Status, tt.want, check)
+		}
+	}
+	if check := checkECID("00008101-000A1B2C3D4E001E", ecid); check.Expected != "A1B2C3D4E001E" || check.Actual != "A1B2C3D4E001E" {
+		t.Errorf("Expected = %q, Actual = %q", check.Expected, check.Actual)
+	}
+}
+
+func TestCheckMEID(t *testing.T) {
+	tests := []struct {
+		name string
+		meid string
+		imei string
+		want string
+	}{
+		{"IMEI 前 14 位", "35693803564380", "356938035643809", CheckPass},
+		{"不对应", "35693803564381", "356938035643809", CheckMismatch},
+		{"没有 MEID", "", "356938035643809", CheckUnavailable},
+		{"没有 IMEI", "35693803564380", "", CheckUnavailable},
+	}
+	for _, tt := range tests {
+		if got := checkMEID(tt.meid, tt.imei).Status; got != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		name string
+		word string
+		want bool
+	}{
+		{"als", "als", true},
+		{"als@0", "als", true},
+		{"dock-als", "als", true},
+		{"als2", "als", true},
+		{"ballast", "als", false},
+		{"calsense", "als", false},
+		{"dials,als", "als", true},
+		{"rear-camera", "rear-camera", true},
+		{"rear-camera-module", "rear-camera", true},
+		{"prox", "prox", true},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.name, tt.word); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.name, tt.word, got, tt.want)
+		}
+	}
+}
+
+// ioregDeviceTree 假的 IODeviceTree，ballast 含有 als 但不是传感器
+const ioregDeviceTree = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>IORegistry</key>
+	<dict>
+		<key>IORegistryEntryName</key><string>device-tree</string>
+		<key>IORegistryEntryChildren</key>
+		<array>
+			<dict>
+				<key>IORegistryEntryName</key><string>ballast</string>
+				<key>SerialNumber</key><string>NOT-A-SENSOR</string>
+			</dict>
+			<dict>
+				<key>IORegistryEntryName</key><string>als</string>
+				<key>SerialNumber</key><data>QUxTMTIzNAA=</data>
+				<key>IORegistryEntryChildren</key>
+				<array>
+					<dict>
+						<key>name</key><string>rear-camera</string>
+						<key>ModuleSerialNumber</key><string>DN8123</string>
+					</dict>
+				</array>
+			</dict>
+			<dict>
+				<key>IORegistryEntryName</key><string>prox</string>
+			</dict>
+		</array>
+	</dict>
+</dict>
+</plist>
+`
+
+func TestQuerySensorSerials(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{
+		"idevicediagnostics": "cat <<'PLIST'\n" + ioregDeviceTree + "PLIST\n",
+	})
+	sensors, err := querySensorSerials("test-udid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Identifier{
+		{Key: "als", Label: "环境光传感器", Value: "ALS1234", Source: "ioreg"},
+		{Key: "rear-camera", Label: "后置摄像头", Value: "DN8123", Source: "ioreg"},
+	}
+	if !reflect.DeepEqual(sensors, want) {
+		t.Errorf("传感器:\n got %+v\nwant %+v", sensors, want)
+	}
+}
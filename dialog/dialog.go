@@ -59,6 +59,21 @@ func (d *DialogManager) OpenFileDialog(title string, defaultPath string, filters
 	return runtime.OpenFileDialog(d.ctx, options)
 }
 
+// OpenMultipleFilesDialog 打开多文件选择对话框
+func (d *DialogManager) OpenMultipleFilesDialog(title string, defaultPath string, filters []runtime.FileFilter) ([]string, error) {
+	if d.ctx == nil {
+		return nil, fmt.Errorf("context not set")
+	}
+	
+	options := runtime.OpenDialogOptions{
+		Title:            title,
+		DefaultDirectory: defaultPath,
+		Filters:          filters,
+	}
+	
+	return runtime.OpenMultipleFilesDialog(d.ctx, options)
+}
+
 // SaveFileDialog 打开文件保存对话框
 func (d *DialogManager) SaveFileDialog(title string, defaultPath string, filters []runtime.FileFilter) (string, error) {
 	if d.ctx == nil {
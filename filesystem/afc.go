@@ -0,0 +1,338 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"myitools/device"
+)
+
+// 文件类型
+const (
+	TypeFile   = "file"
+	TypeFolder = "folder"
+	TypeLink   = "link"
+)
+
+// FileInfo 设备上的文件信息
+type FileInfo struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	Type         string    `json:"type"` // file、folder 或 link
+	Size         int64     `json:"size"`
+	Blocks       int64     `json:"blocks"`
+	Links        int       `json:"links"`
+	ModifiedTime time.Time `json:"modified_time"`
+	CreatedTime  time.Time `json:"created_time"`
+	LinkTarget   string    `json:"link_target,omitempty"`
+}
+
+// FSInfo 设备文件系统信息
+type FSInfo struct {
+	Model      string `json:"model"`
+	TotalBytes int64  `json:"total_bytes"`
+	FreeBytes  int64  `json:"free_bytes"`
+	BlockSize  int64  `json:"block_size"`
+}
+
+// Client 通过 afcclient 访问设备的媒体分区(com.apple.afc)或应用的数据容器
+type Client struct {
+	udid     string
+	vendArgs []string // 访问应用数据时为 --documents 或 --container 参数
+}
+
+// NewClient 创建访问媒体分区(/private/var/mobile/Media)的客户端
+func NewClient(udid string) *Client {
+	return &Client{udid: udid}
+}
+
+// NewAppClient 创建通过 house_arrest 访问应用数据的客户端
+// container 为 false 时只能访问 Documents 目录，需要应用开启文件共享。
+func NewAppClient(udid string, bundleID string, container bool) *Client {
+	vend := "--documents"
+	if container {
+		vend = "--container"
+	}
+	return &Client{udid: udid, vendArgs: []string{vend, bundleID}}
+}
+
+// OpenAppData 创建访问应用数据的客户端，供 device 包备份和恢复应用数据
+func OpenAppData(udid string, bundleID string, container bool) device.AppDataClient {
+	return NewAppClient(udid, bundleID, container)
+}
+
+// UDID 返回设备 UDID
+func (c *Client) UDID() string {
+	return c.udid
+}
+
+// ReadDir 列出目录中的文件
+// 使用 ls -l 在一次连接中取得全部文件的属性，无法解析的行再单独查询。
+func (c *Client) ReadDir(ctx context.Context, dir string) ([]FileInfo, error) {
+	dir = cleanPath(dir)
+	output, err := c.run(ctx, "读取目录 "+dir+" 失败", "ls", "-l", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, unparsed := parseLongListing(dir, output, time.Now())
+	for _, name := range unparsed {
+		p := path.Join(dir, name)
+		info, err := c.Stat(ctx, p)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// 没有权限读取属性的文件仍然显示
+			info = FileInfo{Name: name, Path: p, Type: TypeFile}
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// longListingPattern afcclient ls -l 输出的一行：类型 链接数 大小 修改时间 名称
+// 修改时间与 ls 相同，半年内为 "Jan  2 15:04"，否则为 "Jan  2  2006"。
+var longListingPattern = regexp.MustCompile(`^([-dlbcps])\S*\s+(\d+)\s+(\d+)\s+([A-Z][a-z]{2})\s+(\d{1,2})\s+(\d{1,2}:\d{2}|\d{4}) (.+)$`)
+
+// parseLongListing 解析 afcclient ls -l 的输出，返回解析出的文件和无法解析的文件名
+// ls -l 不包含创建时间和块数，这两项为零值；修改时间精确到分钟或天。
+func parseLongListing(dir string, output string, now time.Time) ([]FileInfo, []string) {
+	var files []FileInfo
+	var unparsed []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "total ") {
+			continue
+		}
+		m := longListingPattern.FindStringSubmatch(line)
+		if m == nil {
+			// 不是 ls -l 格式时按只有文件名处理
+			if name := strings.TrimSpace(line); name != "." && name != ".." {
+				unparsed = append(unparsed, name)
+			}
+			continue
+		}
+
+		name := m[7]
+		info := FileInfo{Type: TypeFile}
+		switch m[1] {
+		case "d":
+			info.Type = TypeFolder
+		case "l":
+			info.Type = TypeLink
+			if i := strings.LastIndex(name, " -> "); i != -1 {
+				name, info.LinkTarget = name[:i], name[i+len(" -> "):]
+			}
+		}
+		if name == "." || name == ".." {
+			continue
+		}
+		info.Name = name
+		info.Path = path.Join(dir, name)
+		info.Links, _ = strconv.Atoi(m[2])
+		info.Size, _ = strconv.ParseInt(m[3], 10, 64)
+		info.ModifiedTime = parseListingTime(m[4], m[5], m[6], now)
+		files = append(files, info)
+	}
+	return files, unparsed
+}
+
+// parseListingTime 解析 ls -l 格式的修改时间，只有时分时取最近一年内的日期
+func parseListingTime(month string, day string, clock string, now time.Time) time.Time {
+	if !strings.Contains(clock, ":") {
+		t, err := time.ParseInLocation("Jan 2 2006", month+" "+day+" "+clock, time.Local)
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	t, err := time.ParseInLocation("2006 Jan 2 15:04", fmt.Sprintf("%d %s %s %s", now.Year(), month, day, clock), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// Stat 获取文件属性
+func (c *Client) Stat(ctx context.Context, p string) (FileInfo, error) {
+	p = cleanPath(p)
+	output, err := c.run(ctx, "获取 "+p+" 的属性失败", "info", p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	values := parseKeyValues(output)
+	if len(values) == 0 {
+		return FileInfo{}, fmt.Errorf("获取 %s 的属性失败: %s", p, strings.TrimSpace(output))
+	}
+
+	info := FileInfo{Name: path.Base(p), Path: p, Type: TypeFile, LinkTarget: values["LinkTarget"]}
+	switch values["st_ifmt"] {
+	case "S_IFDIR":
+		info.Type = TypeFolder
+	case "S_IFLNK":
+		info.Type = TypeLink
+	}
+	info.Size, _ = strconv.ParseInt(values["st_size"], 10, 64)
+	info.Blocks, _ = strconv.ParseInt(values["st_blocks"], 10, 64)
+	info.Links, _ = strconv.Atoi(values["st_nlink"])
+	info.ModifiedTime = parseAFCTime(values["st_mtime"])
+	info.CreatedTime = parseAFCTime(values["st_birthtime"])
+	return info, nil
+}
+
+// Mkdir 创建目录，父目录不存在时一并创建
+func (c *Client) Mkdir(ctx context.Context, dir string) error {
+	dir = cleanPath(dir)
+	_, err := c.run(ctx, "创建目录 "+dir+" 失败", "mkdir", dir)
+	return err
+}
+
+// Remove 删除文件或目录，recursive 为 true 时删除目录中的全部内容
+func (c *Client) Remove(ctx context.Context, p string, recursive bool) error {
+	p = cleanPath(p)
+	if p == "/" {
+		return fmt.Errorf("不能删除根目录")
+	}
+	args := []string{"rm"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	_, err := c.run(ctx, "删除 "+p+" 失败", append(args, p)...)
+	return err
+}
+
+// Rename 重命名或移动文件
+func (c *Client) Rename(ctx context.Context, from string, to string) error {
+	from, to = cleanPath(from), cleanPath(to)
+	_, err := c.run(ctx, "重命名 "+from+" 失败", "mv", from, to)
+	return err
+}
+
+// Symlink 创建指向 target 的符号链接
+func (c *Client) Symlink(ctx context.Context, target string, link string) error {
+	link = cleanPath(link)
+	_, err := c.run(ctx, "创建链接 "+link+" 失败", "ln", "-s", target, link)
+	return err
+}
+
+// Truncate 将文件截断或扩展到指定大小
+// afcclient 不提供截断命令，这里下载到本地修改后再上传。
+func (c *Client) Truncate(ctx context.Context, p string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("文件大小不能为负数")
+	}
+	p = cleanPath(p)
+	info, err := c.Stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if info.Type != TypeFile {
+		return fmt.Errorf("%s 不是文件", p)
+	}
+
+	tmp, err := os.CreateTemp("", "myitools-afc-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	// 截断为 0 时不需要下载原文件
+	if size > 0 {
+		if _, err := c.run(ctx, "下载 "+p+" 失败", "get", "-f", p, tmpPath); err != nil {
+			return err
+		}
+	}
+	if err := os.Truncate(tmpPath, size); err != nil {
+		return fmt.Errorf("截断文件失败: %v", err)
+	}
+	_, err = c.run(ctx, "上传 "+p+" 失败", "put", "-f", tmpPath, p)
+	return err
+}
+
+// GetTree 将设备上的文件或目录递归下载到本地路径
+func (c *Client) GetTree(ctx context.Context, remote string, local string) error {
+	remote = cleanPath(remote)
+	_, err := c.run(ctx, "下载 "+remote+" 失败", "get", "-r", "-f", remote, local)
+	return err
+}
+
+// PutFile 将本地文件上传到设备，已存在的文件会被覆盖
+func (c *Client) PutFile(ctx context.Context, local string, remote string) error {
+	remote = cleanPath(remote)
+	_, err := c.run(ctx, "上传 "+remote+" 失败", "put", "-f", local, remote)
+	return err
+}
+
+// DeviceInfo 获取设备文件系统容量信息
+func (c *Client) DeviceInfo(ctx context.Context) (FSInfo, error) {
+	output, err := c.run(ctx, "获取文件系统信息失败", "devinfo")
+	if err != nil {
+		return FSInfo{}, err
+	}
+	values := parseKeyValues(output)
+	info := FSInfo{Model: values["Model"]}
+	info.TotalBytes, _ = strconv.ParseInt(values["FSTotalBytes"], 10, 64)
+	info.FreeBytes, _ = strconv.ParseInt(values["FSFreeBytes"], 10, 64)
+	info.BlockSize, _ = strconv.ParseInt(values["FSBlockSize"], 10, 64)
+	return info, nil
+}
+
+// command 构造 afcclient 命令
+func (c *Client) command(ctx context.Context, args ...string) *exec.Cmd {
+	full := append([]string{"-u", c.udid}, c.vendArgs...)
+	return exec.CommandContext(ctx, "afcclient", append(full, args...)...)
+}
+
+// run 运行 afcclient 并返回输出
+func (c *Client) run(ctx context.Context, message string, args ...string) (string, error) {
+	output, err := c.command(ctx, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", device.CommandError("afcclient", message, err, string(output))
+	}
+	return string(output), nil
+}
+
+// parseKeyValues 解析 afcclient 输出的 "键: 值" 行
+func parseKeyValues(output string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// parseAFCTime 解析 AFC 返回的纳秒时间戳
+func parseAFCTime(value string) time.Time {
+	ns, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ns <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// cleanPath 规范化设备上的路径，始终以 / 开头
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
@@ -0,0 +1,37 @@
+package filesystem
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLongListing(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.Local)
+	output := "total 3\n" +
+		"drwxr-xr-x    2        128 Mar  9 08:15 .\n" +
+		"drwxr-xr-x    5        320 Mar  9 08:15 ..\n" +
+		"drwxr-xr-x   12        384 Feb 28 19:02 100APPLE\n" +
+		"-rw-r--r--    1    2345678 Dec 31 23:59 IMG 0001.HEIC\n" +
+		"-rw-r--r--    1        512 Jun  5  2021 old.txt\n" +
+		"lrwxr-xr-x    1         11 Mar  1 10:00 link -> /var/target\n" +
+		"plain-name\n"
+
+	files, unparsed := parseLongListing("/DCIM", output, now)
+	want := []FileInfo{
+		{Name: "100APPLE", Path: "/DCIM/100APPLE", Type: TypeFolder, Size: 384, Links: 12,
+			ModifiedTime: time.Date(2024, time.February, 28, 19, 2, 0, 0, time.Local)},
+		{Name: "IMG 0001.HEIC", Path: "/DCIM/IMG 0001.HEIC", Type: TypeFile, Size: 2345678, Links: 1,
+			ModifiedTime: time.Date(2023, time.December, 31, 23, 59, 0, 0, time.Local)},
+		{Name: "old.txt", Path: "/DCIM/old.txt", Type: TypeFile, Size: 512, Links: 1,
+			ModifiedTime: time.Date(2021, time.June, 5, 0, 0, 0, 0, time.Local)},
+		{Name: "link", Path: "/DCIM/link", Type: TypeLink, Size: 11, Links: 1, LinkTarget: "/var/target",
+			ModifiedTime: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.Local)},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("parseLongListing =\n%+v\nwant\n%+v", files, want)
+	}
+	if !reflect.DeepEqual(unparsed, []string{"plain-name"}) {
+		t.Errorf("unparsed = %q", unparsed)
+	}
+}
@@ -0,0 +1,37 @@
+package filesystem
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ListFiles 列出目录内容供文件浏览器显示，目录在前，按名称排序
+// showHidden 为 false 时不显示以 . 开头的文件。
+func ListFiles(ctx context.Context, c *Client, dir string, showHidden bool) ([]FileInfo, error) {
+	files, err := c.ReadDir(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]FileInfo, 0, len(files))
+	for _, file := range files {
+		if !showHidden && strings.HasPrefix(file.Name, ".") {
+			continue
+		}
+		result = append(result, file)
+	}
+	SortFiles(result)
+	return result, nil
+}
+
+// SortFiles 目录在前，同类按名称排序，不区分大小写
+func SortFiles(files []FileInfo) {
+	sort.SliceStable(files, func(i, j int) bool {
+		iDir, jDir := files[i].Type == TypeFolder, files[j].Type == TypeFolder
+		if iDir != jDir {
+			return iDir
+		}
+		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
+	})
+}
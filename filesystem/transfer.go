@@ -0,0 +1,328 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"myitools/device"
+)
+
+// 传输方向
+const (
+	DirectionDownload = "download"
+	DirectionUpload   = "upload"
+)
+
+// downloadPollInterval 下载时查询本地文件大小的间隔
+const downloadPollInterval = 200 * time.Millisecond
+
+// cleanupTimeout 取消上传后删除设备上未完成文件的超时时间
+const cleanupTimeout = 10 * time.Second
+
+// partialSuffix 传输中的文件名后缀，完成后再重命名为目标文件，失败时只删除临时文件
+const partialSuffix = ".part"
+
+// percentPattern afcclient 传输较大文件时输出的百分比进度
+var percentPattern = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)%`)
+
+// TransferProgress 上传或下载进度
+type TransferProgress struct {
+	ID          string `json:"id"`
+	UDID        string `json:"udid"`
+	Direction   string `json:"direction"` // download 或 upload
+	CurrentFile string `json:"current_file"`
+	DoneFiles   int    `json:"done_files"`
+	TotalFiles  int    `json:"total_files"`
+	DoneBytes   int64  `json:"done_bytes"`
+	TotalBytes  int64  `json:"total_bytes"`
+	Percent     int    `json:"percent"`
+}
+
+// transferItem 一个需要传输的文件或目录
+type transferItem struct {
+	src   string
+	dst   string
+	size  int64
+	isDir bool
+}
+
+// transfer 一次传输任务的进度统计
+type transfer struct {
+	progress   TransferProgress
+	onProgress func(TransferProgress)
+}
+
+// report 报告进度，current 为当前文件已传输的字节数
+func (t *transfer) report(current int64) {
+	if t.onProgress == nil {
+		return
+	}
+	p := t.progress
+	p.DoneBytes += current
+	if p.TotalBytes > 0 {
+		p.Percent = int(p.DoneBytes * 100 / p.TotalBytes)
+	} else if p.TotalFiles > 0 {
+		p.Percent = p.DoneFiles * 100 / p.TotalFiles
+	}
+	t.onProgress(p)
+}
+
+// Download 将设备上的文件或目录下载到本地目录
+// 文件先下载为 .part，完成后再替换目标文件；ctx 取消时停止传输并删除未完成的 .part。
+func (c *Client) Download(ctx context.Context, remotePaths []string, localDir string, onProgress func(TransferProgress)) error {
+	var items []transferItem
+	for _, remote := range remotePaths {
+		found, err := c.collectRemote(ctx, cleanPath(remote), filepath.Join(localDir, path.Base(cleanPath(remote))))
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+	}
+
+	t := newTransfer(c.udid, DirectionDownload, items, onProgress)
+	for _, item := range items {
+		if item.isDir {
+			if err := os.MkdirAll(item.dst, 0755); err != nil {
+				return fmt.Errorf("创建目录失败: %v", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(item.dst), 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %v", err)
+		}
+
+		t.progress.CurrentFile = item.src
+		partial := item.dst + partialSuffix
+		err := c.copyWithProgress(ctx, t, item, func() int64 {
+			if stat, err := os.Stat(partial); err == nil {
+				return stat.Size()
+			}
+			return 0
+		}, "下载 "+item.src+" 失败", "get", "-f", item.src, partial)
+		if err == nil {
+			err = os.Rename(partial, item.dst)
+		}
+		if err != nil {
+			os.Remove(partial)
+			return err
+		}
+		t.finishFile(item.size)
+	}
+	t.progress.CurrentFile = ""
+	t.report(0)
+	return nil
+}
+
+// Upload 将本地文件或目录上传到设备上的目录
+// 文件先上传为 .part，完成后再移动到目标位置；ctx 取消时停止传输并删除设备上未完成的 .part。
+func (c *Client) Upload(ctx context.Context, localPaths []string, remoteDir string, onProgress func(TransferProgress)) error {
+	remoteDir = cleanPath(remoteDir)
+	var items []transferItem
+	for _, local := range localPaths {
+		found, err := collectLocal(local, path.Join(remoteDir, filepath.Base(local)))
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+	}
+
+	t := newTransfer(c.udid, DirectionUpload, items, onProgress)
+	for _, item := range items {
+		if item.isDir {
+			if err := c.Mkdir(ctx, item.dst); err != nil {
+				return err
+			}
+			continue
+		}
+
+		t.progress.CurrentFile = item.src
+		partial := item.dst + partialSuffix
+		err := c.copyWithProgress(ctx, t, item, nil, "上传 "+item.src+" 失败", "put", "-f", item.src, partial)
+		if err == nil {
+			err = c.Rename(ctx, partial, item.dst)
+		}
+		if err != nil {
+			c.removePartial(partial)
+			return err
+		}
+		t.finishFile(item.size)
+	}
+	t.progress.CurrentFile = ""
+	t.report(0)
+	return nil
+}
+
+// copyWithProgress 运行 afcclient 传输一个文件，边传输边报告进度
+// afcclient 传输较大的文件时会在输出中打印百分比进度，读取输出即可得到已传输的字节数；
+// localSize 不为 nil 时（下载）同时按本地文件的大小报告进度。
+func (c *Client) copyWithProgress(ctx context.Context, t *transfer, item transferItem, localSize func() int64, message string, args ...string) error {
+	t.report(0)
+	cmd := c.command(ctx, args...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	// 取消后不再等待仍占用输出的子进程
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		return device.CommandError("afcclient", message, err, "")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+
+	// 进度以 \r 刷新，按 \r 和 \n 分行读取
+	progress := make(chan int64)
+	var output strings.Builder
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(pr)
+		scanner.Split(device.ScanProgressLines)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if m := percentPattern.FindStringSubmatch(line); m != nil {
+				if percent, err := strconv.ParseFloat(m[1], 64); err == nil && percent <= 100 {
+					select {
+					case progress <- int64(float64(item.size) * percent / 100):
+					case <-ctx.Done():
+					}
+				}
+				continue
+			}
+			output.WriteString(line + "\n")
+		}
+	}()
+
+	var tick <-chan time.Time
+	if localSize != nil {
+		ticker := time.NewTicker(downloadPollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case err := <-done:
+			// 读完剩余的输出，期间的进度仍需接收，否则读取输出的协程会阻塞
+			for finished := false; !finished; {
+				select {
+				case <-outputDone:
+					finished = true
+				case n := <-progress:
+					t.report(n)
+				}
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("传输已取消")
+			}
+			if err != nil {
+				return device.CommandError("afcclient", message, err, output.String())
+			}
+			return nil
+		case n := <-progress:
+			t.report(n)
+		case <-tick:
+			t.report(localSize())
+		}
+	}
+}
+
+// removePartial 删除设备上未完成的上传文件
+// 传输的 ctx 可能已被取消，使用新的 ctx。
+func (c *Client) removePartial(remote string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := c.Remove(ctx, remote, false); err != nil {
+		fmt.Printf("删除未完成的文件 %s 失败: %v\n", remote, err)
+	}
+}
+
+// finishFile 记录一个文件传输完成
+func (t *transfer) finishFile(size int64) {
+	t.progress.DoneFiles++
+	t.progress.DoneBytes += size
+}
+
+// newTransfer 根据待传输的文件创建进度统计
+func newTransfer(udid string, direction string, items []transferItem, onProgress func(TransferProgress)) *transfer {
+	t := &transfer{
+		progress:   TransferProgress{UDID: udid, Direction: direction},
+		onProgress: onProgress,
+	}
+	for _, item := range items {
+		if !item.isDir {
+			t.progress.TotalFiles++
+			t.progress.TotalBytes += item.size
+		}
+	}
+	return t
+}
+
+// collectRemote 列出设备上需要下载的文件，目录会递归展开
+func (c *Client) collectRemote(ctx context.Context, remote string, local string) ([]transferItem, error) {
+	info, err := c.Stat(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != TypeFolder {
+		return []transferItem{{src: remote, dst: local, size: info.Size}}, nil
+	}
+
+	items := []transferItem{{src: remote, dst: local, isDir: true}}
+	entries, err := c.ReadDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		dst := filepath.Join(local, entry.Name)
+		if entry.Type == TypeFolder {
+			children, err := c.collectRemote(ctx, entry.Path, dst)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, children...)
+			continue
+		}
+		items = append(items, transferItem{src: entry.Path, dst: dst, size: entry.Size})
+	}
+	return items, nil
+}
+
+// collectLocal 列出本地需要上传的文件，目录会递归展开
+func collectLocal(local string, remote string) ([]transferItem, error) {
+	var items []transferItem
+	err := filepath.Walk(local, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(local, p)
+		dst := remote
+		if rel != "." {
+			dst = path.Join(remote, filepath.ToSlash(rel))
+		}
+		switch {
+		case info.IsDir():
+			items = append(items, transferItem{src: p, dst: dst, isDir: true})
+		case info.Mode().IsRegular():
+			items = append(items, transferItem{src: p, dst: dst, size: info.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", local, err)
+	}
+	return items, nil
+}
@@ -0,0 +1,127 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"myitools/internal/testutil"
+)
+
+func writeLocalFile(t *testing.T, size int) string {
+	t.Helper()
+	local := filepath.Join(t.TempDir(), "video.mov")
+	if err := os.WriteFile(local, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return local
+}
+
+func TestUploadReportsStreamedProgress(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in put) printf '25.0%%\r50.0%%\r100.0%%\n' ;; esac` + "\n"})
+	local := writeLocalFile(t, 1000)
+
+	var reported []int64
+	err := NewClient("udid").Upload(context.Background(), []string{local}, "/Downloads", func(p TransferProgress) {
+		reported = append(reported, p.DoneBytes)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{0, 250, 500, 1000, 1000}
+	if len(reported) != len(want) {
+		t.Fatalf("进度 = %v, want %v", reported, want)
+	}
+	for i := range want {
+		if reported[i] != want[i] {
+			t.Fatalf("进度 = %v, want %v", reported, want)
+		}
+	}
+}
+
+func TestCancelledUploadRemovesPartialFile(t *testing.T) {
+	tools := testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in put) sleep 5 ;; esac` + "\n"})
+	local := writeLocalFile(t, 1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	if err := NewClient("udid").Upload(ctx, []string{local}, "/Downloads", nil); err == nil {
+		t.Fatal("取消的上传应返回错误")
+	}
+
+	calls := tools.Calls(t, "afcclient")
+	if last := calls[len(calls)-1]; last != "-u udid rm /Downloads/video.mov.part" {
+		t.Errorf("取消后应只删除设备上未完成的临时文件，最后一次调用为 %q", last)
+	}
+	for _, call := range calls {
+		if call == "-u udid rm /Downloads/video.mov" || strings.HasPrefix(call, "-u udid mv ") {
+			t.Errorf("取消后不应改动目标文件: %q", call)
+		}
+	}
+}
+
+func TestUploadMovesTemporaryFileIntoPlace(t *testing.T) {
+	tools := testutil.NewFakeTools(t, map[string]string{"afcclient": "true\n"})
+	local := writeLocalFile(t, 1000)
+
+	if err := NewClient("udid").Upload(context.Background(), []string{local}, "/Downloads", nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"-u udid put -f " + local + " /Downloads/video.mov.part",
+		"-u udid mv /Downloads/video.mov.part /Downloads/video.mov",
+	}
+	if calls := tools.Calls(t, "afcclient"); !reflect.DeepEqual(calls, want) {
+		t.Errorf("afcclient 调用:\n got %q\nwant %q", calls, want)
+	}
+}
+
+// fakeDownload 假的 afcclient，info 返回 1000 字节的文件，get 时执行 get
+func fakeDownload(t *testing.T, get string) {
+	t.Helper()
+	testutil.NewFakeTools(t, map[string]string{
+		"afcclient": "case \"$3\" in\n" +
+			"info) printf 'st_size: 1000\\nst_ifmt: S_IFREG\\n' ;;\n" +
+			"get) " + get + " ;;\n" +
+			"esac\n",
+	})
+}
+
+func TestDownloadRenamesPartialFile(t *testing.T) {
+	fakeDownload(t, `printf data > "$6"`)
+	localDir := t.TempDir()
+	if err := NewClient("udid").Download(context.Background(), []string{"/DCIM/video.mov"}, localDir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(localDir, "video.mov")); err != nil || string(data) != "data" {
+		t.Errorf("下载的文件 = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(localDir, "video.mov.part")); !os.IsNotExist(err) {
+		t.Errorf("临时文件未重命名: %v", err)
+	}
+}
+
+func TestCancelledDownloadKeepsExistingFile(t *testing.T) {
+	fakeDownload(t, `printf partial > "$6"; sleep 5`)
+	localDir := t.TempDir()
+	existing := filepath.Join(localDir, "video.mov")
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	if err := NewClient("udid").Download(ctx, []string{"/DCIM/video.mov"}, localDir, nil); err == nil {
+		t.Fatal("取消的下载应返回错误")
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "existing" {
+		t.Errorf("已有的文件被改动: %q, %v", data, err)
+	}
+	if _, err := os.Stat(existing + ".part"); !os.IsNotExist(err) {
+		t.Errorf("未删除临时文件: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Package testutil 测试中共用的辅助函数
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// FakeTools 放在 PATH 最前面的假命令行工具，用于在没有设备的环境下测试调用外部工具的代码
+type FakeTools struct {
+	dir string
+}
+
+// NewFakeTools 创建假工具，scripts 为工具名到 shell 脚本的映射
+// 每个工具被调用时先把参数追加到调用记录中，再执行脚本。Windows 下跳过测试。
+func NewFakeTools(t testing.TB, scripts map[string]string) *FakeTools {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("假工具使用 shell 脚本")
+	}
+	dir := t.TempDir()
+	for name, script := range scripts {
+		content := "#!/bin/sh\necho \"$@\" >> '" + filepath.Join(dir, name+".calls") + "'\n" + script
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return &FakeTools{dir: dir}
+}
+
+// Calls 返回工具每次被调用时的参数，每次调用一行
+func (f *FakeTools) Calls(t testing.TB, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(f.dir, name+".calls"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"log"
+	"myitools/device"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -36,6 +37,7 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 248, G: 250, B: 252, A: 1},
 		OnStartup:        app.startup,
 		OnShutdown:       app.shutdown,
+		ErrorFormatter:   device.FormatError,
 		Bind: []interface{}{
 			app,
 		},
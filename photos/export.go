@@ -0,0 +1,401 @@
+package photos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"myitools/device"
+	"myitools/filesystem"
+)
+
+// 导出目录结构
+const (
+	LayoutYearMonth = "year_month" // 2024/2024-03
+	LayoutDay       = "day"        // 2024/2024-03-15
+	LayoutFlat      = "flat"       // 全部放在导出目录中
+)
+
+// stateDir 导出目录中保存导入记录的目录
+const stateDir = ".myitools"
+
+// ExportOptions 照片导出选项
+type ExportOptions struct {
+	Layout           string `json:"layout"`             // year_month、day 或 flat，为空时为 year_month
+	ConvertHEIC      bool   `json:"convert_heic"`       // 将 HEIC 转换为 JPEG
+	ExcludeLiveVideo bool   `json:"exclude_live_video"` // 不导出实况照片的视频部分
+	ExcludeSidecars  bool   `json:"exclude_sidecars"`   // 不导出编辑后的文件和编辑记录
+	Incremental      bool   `json:"incremental"`        // 跳过此设备上已导入过的资源
+}
+
+// ExportProgress 导出进度
+type ExportProgress struct {
+	ID          string `json:"id"`
+	UDID        string `json:"udid"`
+	CurrentFile string `json:"current_file"`
+	Done        int    `json:"done"`
+	Total       int    `json:"total"`
+	Percent     int    `json:"percent"`
+}
+
+// ExportResult 导出结果
+type ExportResult struct {
+	Dest       string   `json:"dest"`
+	Exported   int      `json:"exported"`   // 新导出的资源数
+	Skipped    int      `json:"skipped"`    // 增量导入时跳过的资源数
+	Duplicates int      `json:"duplicates"` // 内容与已导出文件相同而跳过的资源数
+	Failed     int      `json:"failed"`
+	Errors     []string `json:"errors"`
+}
+
+// exportFile 需要与主文件一同导出的文件
+type exportFile struct {
+	remote string // 设备上的路径
+	name   string // 导出后的文件名
+	local  string // 下载到临时目录后的路径
+}
+
+// importState 一台设备的导入记录
+type importState struct {
+	UDID   string                   `json:"udid"`
+	Assets map[string]importedAsset `json:"assets"` // 键为 Asset.ID
+}
+
+// importedAsset 已导入资源的记录
+type importedAsset struct {
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+	Hash       string    `json:"hash"` // 主文件的 SHA-256
+	Path       string    `json:"path"` // 相对导出目录的路径
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// Export 将资源导出到本地目录，按拍摄日期分目录存放
+// 与已导出文件内容相同的资源不会重复导出，导入记录按设备保存在导出目录的 .myitools 中。
+func Export(ctx context.Context, client *filesystem.Client, assets []Asset, dest string, options ExportOptions, onProgress func(ExportProgress)) (ExportResult, error) {
+	result := ExportResult{Dest: dest, Errors: []string{}}
+	if err := os.MkdirAll(filepath.Join(dest, stateDir), 0755); err != nil {
+		return result, fmt.Errorf("创建导出目录失败: %v", err)
+	}
+
+	udid := client.UDID()
+	state, err := loadImportState(dest, udid)
+	if err != nil {
+		return result, err
+	}
+	hashes, err := loadHashIndex(dest)
+	if err != nil {
+		return result, err
+	}
+
+	tmpRoot, err := os.MkdirTemp(filepath.Join(dest, stateDir), "tmp-*")
+	if err != nil {
+		return result, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmpRoot)
+
+	report := func(done int, current string) {
+		if onProgress != nil {
+			progress := ExportProgress{UDID: udid, CurrentFile: current, Done: done, Total: len(assets), Percent: 100}
+			if len(assets) > 0 {
+				progress.Percent = done * 100 / len(assets)
+			}
+			onProgress(progress)
+		}
+	}
+
+	defer saveImportState(dest, state)
+	for i, asset := range assets {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("导出已取消")
+		}
+		report(i, asset.Primary.Path)
+
+		if previous, ok := state.Assets[asset.ID]; ok && options.Incremental &&
+			previous.Size == asset.Primary.Size && previous.Modified.Equal(asset.Primary.ModifiedTime) {
+			result.Skipped++
+			continue
+		}
+
+		record, duplicate, err := exportAsset(ctx, client, asset, dest, tmpRoot, hashes, options)
+		if err != nil {
+			// 设备断开或缺少转换工具时后续资源也会失败，直接结束
+			var toolErr *device.ErrToolMissing
+			if ctx.Err() != nil || errors.Is(err, device.ErrDeviceNotConnected) || errors.As(err, &toolErr) {
+				return result, err
+			}
+			result.Failed++
+			result.Errors = append(result.Errors, asset.ID+": "+err.Error())
+			continue
+		}
+
+		state.Assets[asset.ID] = record
+		hashes[record.Hash] = record.Path
+		if duplicate {
+			result.Duplicates++
+		} else {
+			result.Exported++
+		}
+		// 定期保存，中途取消时已导出的资源也会被记录
+		if (i+1)%20 == 0 {
+			saveImportState(dest, state)
+		}
+	}
+	report(len(assets), "")
+	fmt.Printf("照片导出完成: 导出 %d，跳过 %d，重复 %d，失败 %d\n", result.Exported, result.Skipped, result.Duplicates, result.Failed)
+	return result, nil
+}
+
+// exportAsset 导出一个资源及其实况视频和附属文件，返回导入记录和是否为重复资源
+func exportAsset(ctx context.Context, client *filesystem.Client, asset Asset, dest string, tmpRoot string, hashes map[string]string, options ExportOptions) (importedAsset, bool, error) {
+	tmp, err := os.MkdirTemp(tmpRoot, "asset-*")
+	if err != nil {
+		return importedAsset{}, false, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	primary, err := download(ctx, client, asset.Primary.Path, tmp)
+	if err != nil {
+		return importedAsset{}, false, err
+	}
+	hash, err := fileHash(primary)
+	if err != nil {
+		return importedAsset{}, false, err
+	}
+	record := importedAsset{
+		Size:       asset.Primary.Size,
+		Modified:   asset.Primary.ModifiedTime,
+		Hash:       hash,
+		ImportedAt: time.Now(),
+	}
+	if existing, ok := hashes[hash]; ok {
+		record.Path = existing
+		return record, true, nil
+	}
+
+	taken, ok := CaptureTime(primary)
+	if !ok {
+		taken = asset.CreatedAt
+	}
+	dir := filepath.Join(dest, layoutDir(taken, options.Layout))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return record, false, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	_, ext := splitName(asset.Primary.Name)
+	convert := options.ConvertHEIC && (ext == "HEIC" || ext == "HEIF")
+	primaryExt := path.Ext(asset.Primary.Name)
+	if convert {
+		primaryExt = ".JPG"
+	}
+
+	// 实况视频和附属文件使用与主文件相同的文件名，保持配对关系，
+	// 选择 base 时要求其中每个文件名都不与目录中已有的文件冲突。
+	companions := func(base string) []exportFile {
+		var extras []exportFile
+		if asset.LiveVideo != nil && !options.ExcludeLiveVideo {
+			extras = append(extras, exportFile{remote: asset.LiveVideo.Path, name: base + path.Ext(asset.LiveVideo.Name)})
+		}
+		if !options.ExcludeSidecars {
+			for _, sidecar := range asset.Sidecars {
+				extras = append(extras, exportFile{remote: sidecar.File.Path, name: sidecarName(base, sidecar)})
+			}
+		}
+		return extras
+	}
+	base := uniqueBase(dir, asset.Name, func(base string) []string {
+		names := []string{base + primaryExt}
+		for _, extra := range companions(base) {
+			names = append(names, extra.name)
+		}
+		return names
+	})
+	target := filepath.Join(dir, base+primaryExt)
+
+	// 全部下载到临时目录后才移入导出目录，任何一个失败时导出目录中不留下文件，
+	// 否则主文件已保存却没有导入记录，下次导出会生成 _1 副本。
+	extras := companions(base)
+	for i := range extras {
+		extraDir := filepath.Join(tmp, fmt.Sprintf("extra-%d", i))
+		if extras[i].local, err = download(ctx, client, extras[i].remote, extraDir); err != nil {
+			return record, false, err
+		}
+	}
+
+	// 转换也在临时目录中完成
+	if convert {
+		converted := filepath.Join(tmp, "converted"+primaryExt)
+		if err := ConvertHEIC(primary, converted); err != nil {
+			return record, false, err
+		}
+		primary = converted
+	}
+
+	moves := append([]exportFile{{local: primary, name: base + primaryExt}}, extras...)
+	var placed []string
+	for _, move := range moves {
+		moveTarget := filepath.Join(dir, move.name)
+		if err := os.Rename(move.local, moveTarget); err != nil {
+			for _, p := range placed {
+				os.Remove(p)
+			}
+			return record, false, fmt.Errorf("保存 %s 失败: %v", move.name, err)
+		}
+		placed = append(placed, moveTarget)
+		setFileTime(moveTarget, taken)
+	}
+	record.Path, _ = filepath.Rel(dest, target)
+	return record, false, nil
+}
+
+// download 下载一个文件到临时目录，返回本地路径
+func download(ctx context.Context, client *filesystem.Client, remote string, dir string) (string, error) {
+	if err := client.Download(ctx, []string{remote}, dir, nil); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path.Base(remote)), nil
+}
+
+// sidecarName 附属文件导出后的文件名
+func sidecarName(base string, sidecar Sidecar) string {
+	ext := path.Ext(sidecar.File.Name)
+	switch {
+	case sidecar.Role == SidecarAdjustments && strings.EqualFold(ext, ".aae"):
+		return base + ext
+	case sidecar.Role == SidecarAdjustments:
+		return base + "_adjustments" + ext
+	case sidecar.Role == SidecarOriginalAdjustments:
+		return base + "_original" + ext
+	}
+	return base + "_edited" + ext
+}
+
+// layoutDir 根据拍摄时间返回相对导出目录的子目录
+func layoutDir(taken time.Time, layout string) string {
+	if layout == LayoutFlat {
+		return ""
+	}
+	if taken.IsZero() {
+		return "unknown"
+	}
+	switch layout {
+	case LayoutDay:
+		return filepath.Join(taken.Format("2006"), taken.Format("2006-01-02"))
+	}
+	return filepath.Join(taken.Format("2006"), taken.Format("2006-01"))
+}
+
+// uniqueBase 返回目录中不冲突的文件名，已存在时添加 _1、_2 等后缀
+// names 返回以 base 为文件名时要写入的全部文件，其中任何一个已存在都视为冲突。
+func uniqueBase(dir string, name string, names func(base string) []string) string {
+	base := name
+	for i := 1; ; i++ {
+		if !anyExists(dir, names(base)) {
+			return base
+		}
+		base = fmt.Sprintf("%s_%d", name, i)
+	}
+}
+
+// anyExists 检查目录中是否已有其中任何一个文件
+func anyExists(dir string, names []string) bool {
+	for _, name := range names {
+		if _, err := os.Lstat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFileTime 将文件修改时间设为拍摄时间
+func setFileTime(p string, t time.Time) {
+	if !t.IsZero() {
+		os.Chtimes(p, t, t)
+	}
+}
+
+// fileHash 计算文件的 SHA-256
+func fileHash(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("读取 %s 失败: %v", filepath.Base(p), err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// importStatePath 设备导入记录文件的路径
+func importStatePath(dest string, udid string) string {
+	return filepath.Join(dest, stateDir, "import_"+udid+".json")
+}
+
+// loadImportState 读取设备的导入记录
+func loadImportState(dest string, udid string) (*importState, error) {
+	state := &importState{UDID: udid, Assets: map[string]importedAsset{}}
+	data, err := os.ReadFile(importStatePath(dest, udid))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取导入记录失败: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("导入记录损坏: %v", err)
+	}
+	if state.Assets == nil {
+		state.Assets = map[string]importedAsset{}
+	}
+	return state, nil
+}
+
+// saveImportState 保存设备的导入记录
+func saveImportState(dest string, state *importState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	p := importStatePath(dest, state.UDID)
+	if err := os.WriteFile(p+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("保存导入记录失败: %v", err)
+	}
+	return os.Rename(p+".tmp", p)
+}
+
+// loadHashIndex 汇总导出目录中所有设备已导入文件的哈希，用于跨设备去重
+// 记录中的文件已被删除时不计入，以便重新导出。
+func loadHashIndex(dest string) (map[string]string, error) {
+	hashes := map[string]string{}
+	matches, err := filepath.Glob(filepath.Join(dest, stateDir, "import_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			continue
+		}
+		var state importState
+		if json.Unmarshal(data, &state) != nil {
+			continue
+		}
+		for _, asset := range state.Assets {
+			if _, err := os.Stat(filepath.Join(dest, asset.Path)); err == nil {
+				hashes[asset.Hash] = asset.Path
+			}
+		}
+	}
+	return hashes, nil
+}
@@ -0,0 +1,118 @@
+package photos
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"myitools/filesystem"
+	"myitools/internal/testutil"
+)
+
+// exportedFiles 列出导出目录中的文件，不包括导入记录
+func exportedFiles(t *testing.T, dest string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dest, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == stateDir {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(dest, p)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestExportLeavesNothingWhenLiveVideoFails(t *testing.T) {
+	// 参数为 -u udid info PATH 或 -u udid get -f SRC DST，实况视频下载失败
+	testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in
+info) printf 'st_size: 5\nst_ifmt: S_IFREG\n' ;;
+get)
+	case "$5" in
+	*.MOV) echo "ERROR: read failed" >&2; exit 1 ;;
+	*) printf 'hello' > "$6" ;;
+	esac
+	;;
+esac
+`})
+
+	taken := time.Date(2024, time.March, 9, 8, 15, 0, 0, time.Local)
+	asset := Asset{
+		ID: "100APPLE/IMG_0001", Album: "100APPLE", Name: "IMG_0001", Kind: KindLivePhoto,
+		Primary:   filesystem.FileInfo{Name: "IMG_0001.JPG", Path: "/DCIM/100APPLE/IMG_0001.JPG", Size: 5},
+		LiveVideo: &filesystem.FileInfo{Name: "IMG_0001.MOV", Path: "/DCIM/100APPLE/IMG_0001.MOV", Size: 5},
+		Sidecars:  []Sidecar{},
+		CreatedAt: taken,
+	}
+
+	dest := t.TempDir()
+	result, err := Export(context.Background(), filesystem.NewClient("udid"), []Asset{asset}, dest, ExportOptions{Layout: LayoutFlat}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || result.Exported != 0 {
+		t.Errorf("结果 = %+v", result)
+	}
+	if files := exportedFiles(t, dest); len(files) != 0 {
+		t.Errorf("实况视频下载失败时不应保存主文件: %v", files)
+	}
+
+	// 视频恢复后重新导出，不应生成 _1 副本
+	testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in
+info) printf 'st_size: 5\nst_ifmt: S_IFREG\n' ;;
+get) printf 'hello' > "$6" ;;
+esac
+`})
+	if _, err := Export(context.Background(), filesystem.NewClient("udid"), []Asset{asset}, dest, ExportOptions{Layout: LayoutFlat}, nil); err != nil {
+		t.Fatal(err)
+	}
+	files := exportedFiles(t, dest)
+	if strings.Join(files, ",") != "IMG_0001.JPG,IMG_0001.MOV" {
+		t.Errorf("导出的文件 = %v", files)
+	}
+}
+
+func TestExportPicksBaseFreeForEveryFile(t *testing.T) {
+	testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in
+info) printf 'st_size: 5\nst_ifmt: S_IFREG\n' ;;
+get) printf 'hello' > "$6" ;;
+esac
+`})
+
+	// 目录中只有同名的视频，主文件名不冲突也不能覆盖它
+	dest := t.TempDir()
+	existing := filepath.Join(dest, "IMG_0001.MOV")
+	if err := os.WriteFile(existing, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	asset := Asset{
+		ID: "100APPLE/IMG_0001", Album: "100APPLE", Name: "IMG_0001", Kind: KindLivePhoto,
+		Primary:   filesystem.FileInfo{Name: "IMG_0001.JPG", Path: "/DCIM/100APPLE/IMG_0001.JPG", Size: 5},
+		LiveVideo: &filesystem.FileInfo{Name: "IMG_0001.MOV", Path: "/DCIM/100APPLE/IMG_0001.MOV", Size: 5},
+		Sidecars:  []Sidecar{},
+		CreatedAt: time.Date(2024, time.March, 9, 8, 15, 0, 0, time.Local),
+	}
+	if _, err := Export(context.Background(), filesystem.NewClient("udid"), []Asset{asset}, dest, ExportOptions{Layout: LayoutFlat}, nil); err != nil {
+		t.Fatal(err)
+	}
+	files := exportedFiles(t, dest)
+	if strings.Join(files, ",") != "IMG_0001.MOV,IMG_0001_1.JPG,IMG_0001_1.MOV" {
+		t.Errorf("导出的文件 = %v", files)
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "other" {
+		t.Errorf("已有的视频被覆盖: %q, %v", data, err)
+	}
+}
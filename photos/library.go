@@ -0,0 +1,224 @@
+package photos
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"myitools/filesystem"
+)
+
+// 设备上的照片目录
+const (
+	dcimDir      = "/DCIM"
+	mutationsDir = "/PhotoData/Mutations/DCIM"
+	thumbnailDir = "/PhotoData/Thumbnails/V2"
+)
+
+// 资源类型
+const (
+	KindPhoto     = "photo"
+	KindVideo     = "video"
+	KindLivePhoto = "live_photo"
+)
+
+// 附属文件类型
+const (
+	SidecarEdited      = "edited"       // 编辑后的照片，IMG_E0001.JPG 或 FullSizeRender.jpg
+	SidecarEditedVideo = "edited_video" // 编辑后的实况视频
+	SidecarAdjustments = "adjustments"  // 编辑记录，.AAE 或 Adjustments.plist
+	// SidecarOriginalAdjustments 原始编辑记录，IMG_O0001.AAE，照片再次编辑时保留的第一次编辑
+	SidecarOriginalAdjustments = "original_adjustments"
+)
+
+var (
+	imageExts = map[string]bool{"HEIC": true, "HEIF": true, "JPG": true, "JPEG": true, "PNG": true, "GIF": true, "DNG": true, "TIF": true, "TIFF": true}
+	videoExts = map[string]bool{"MOV": true, "MP4": true, "M4V": true}
+)
+
+// Asset 相机胶卷中的一个资源，实况照片的图片和视频合为一个资源
+type Asset struct {
+	ID        string               `json:"id"`    // 相册目录/文件名，例如 100APPLE/IMG_0001
+	Album     string               `json:"album"` // DCIM 下的目录，例如 100APPLE
+	Name      string               `json:"name"`  // 不含扩展名的文件名
+	Kind      string               `json:"kind"`  // photo、video 或 live_photo
+	Primary   filesystem.FileInfo  `json:"primary"`
+	LiveVideo *filesystem.FileInfo `json:"live_video,omitempty"` // 实况照片的视频部分
+	Sidecars  []Sidecar            `json:"sidecars"`
+	CreatedAt time.Time            `json:"created_at"` // 设备上的文件创建时间，导出时以 EXIF 为准
+	Size      int64                `json:"size"`       // 全部文件的大小
+}
+
+// Sidecar 资源的附属文件
+type Sidecar struct {
+	Role string              `json:"role"` // edited、edited_video、adjustments 或 original_adjustments
+	File filesystem.FileInfo `json:"file"`
+}
+
+// assetGroup 扫描时按文件名归组的文件
+type assetGroup struct {
+	album    string
+	name     string
+	image    *filesystem.FileInfo
+	video    *filesystem.FileInfo
+	sidecars []Sidecar
+}
+
+// Scan 扫描设备的 DCIM 目录，并从 PhotoData 中补充编辑记录
+func Scan(ctx context.Context, client *filesystem.Client) ([]Asset, error) {
+	albums, err := client.ReadDir(ctx, dcimDir)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*assetGroup{}
+	var order []string
+	group := func(album string, name string) *assetGroup {
+		key := album + "/" + name
+		g, ok := groups[key]
+		if !ok {
+			g = &assetGroup{album: album, name: name}
+			groups[key] = g
+			order = append(order, key)
+		}
+		return g
+	}
+
+	for _, album := range albums {
+		if album.Type != filesystem.TypeFolder {
+			continue
+		}
+		files, err := client.ReadDir(ctx, album.Path)
+		if err != nil {
+			return nil, err
+		}
+		for i := range files {
+			file := files[i]
+			if file.Type != filesystem.TypeFile {
+				continue
+			}
+			name, ext := splitName(file.Name)
+			edited, original := false, false
+			// IMG_E0001.JPG 是 IMG_0001 编辑后的版本，IMG_O0001.AAE 是 IMG_0001 的原始编辑记录
+			if rest, ok := strings.CutPrefix(name, "IMG_E"); ok {
+				name, edited = "IMG_"+rest, true
+			} else if rest, ok := strings.CutPrefix(name, "IMG_O"); ok && ext == "AAE" {
+				name, original = "IMG_"+rest, true
+			}
+
+			g := group(album.Name, name)
+			switch {
+			case original:
+				g.sidecars = append(g.sidecars, Sidecar{Role: SidecarOriginalAdjustments, File: file})
+			case ext == "AAE":
+				g.sidecars = append(g.sidecars, Sidecar{Role: SidecarAdjustments, File: file})
+			case edited && videoExts[ext]:
+				g.sidecars = append(g.sidecars, Sidecar{Role: SidecarEditedVideo, File: file})
+			case edited && imageExts[ext]:
+				g.sidecars = append(g.sidecars, Sidecar{Role: SidecarEdited, File: file})
+			case imageExts[ext] && g.image == nil:
+				g.image = &file
+			case videoExts[ext] && g.video == nil:
+				g.video = &file
+			}
+		}
+	}
+
+	scanMutations(ctx, client, groups)
+
+	assets := make([]Asset, 0, len(order))
+	for _, key := range order {
+		if asset, ok := groups[key].asset(); ok {
+			assets = append(assets, asset)
+		}
+	}
+	sort.SliceStable(assets, func(i, j int) bool {
+		return assets[i].CreatedAt.Before(assets[j].CreatedAt)
+	})
+	return assets, nil
+}
+
+// scanMutations 读取 PhotoData/Mutations 中的编辑记录和编辑后的文件
+// 目录结构为 Mutations/DCIM/100APPLE/IMG_0001/Adjustments/，读取失败时忽略。
+func scanMutations(ctx context.Context, client *filesystem.Client, groups map[string]*assetGroup) {
+	albums, err := client.ReadDir(ctx, mutationsDir)
+	if err != nil {
+		return
+	}
+	for _, album := range albums {
+		if album.Type != filesystem.TypeFolder {
+			continue
+		}
+		entries, err := client.ReadDir(ctx, album.Path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			g, ok := groups[album.Name+"/"+entry.Name]
+			if !ok || entry.Type != filesystem.TypeFolder {
+				continue
+			}
+			files, err := client.ReadDir(ctx, path.Join(entry.Path, "Adjustments"))
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				lower := strings.ToLower(file.Name)
+				switch {
+				case lower == "adjustments.plist":
+					g.sidecars = append(g.sidecars, Sidecar{Role: SidecarAdjustments, File: file})
+				case strings.HasPrefix(lower, "fullsizerender.") && videoExts[strings.ToUpper(path.Ext(lower)[1:])]:
+					g.sidecars = append(g.sidecars, Sidecar{Role: SidecarEditedVideo, File: file})
+				case strings.HasPrefix(lower, "fullsizerender."):
+					g.sidecars = append(g.sidecars, Sidecar{Role: SidecarEdited, File: file})
+				}
+			}
+		}
+	}
+}
+
+// asset 将归组的文件转换为资源，只有附属文件的组返回 false
+func (g *assetGroup) asset() (Asset, bool) {
+	asset := Asset{ID: g.album + "/" + g.name, Album: g.album, Name: g.name, Sidecars: g.sidecars}
+	switch {
+	case g.image != nil && g.video != nil:
+		asset.Kind = KindLivePhoto
+		asset.Primary = *g.image
+		asset.LiveVideo = g.video
+	case g.image != nil:
+		asset.Kind = KindPhoto
+		asset.Primary = *g.image
+	case g.video != nil:
+		asset.Kind = KindVideo
+		asset.Primary = *g.video
+	default:
+		return asset, false
+	}
+	if asset.Sidecars == nil {
+		asset.Sidecars = []Sidecar{}
+	}
+
+	asset.CreatedAt = asset.Primary.CreatedTime
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = asset.Primary.ModifiedTime
+	}
+	asset.Size = asset.Primary.Size
+	if asset.LiveVideo != nil {
+		asset.Size += asset.LiveVideo.Size
+	}
+	for _, sidecar := range asset.Sidecars {
+		asset.Size += sidecar.File.Size
+	}
+	return asset, true
+}
+
+// splitName 拆分文件名和大写的扩展名
+func splitName(name string) (string, string) {
+	ext := path.Ext(name)
+	if ext == "" {
+		return name, ""
+	}
+	return strings.TrimSuffix(name, ext), strings.ToUpper(ext[1:])
+}
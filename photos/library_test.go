@@ -0,0 +1,56 @@
+package photos
+
+import (
+	"context"
+	"testing"
+
+	"myitools/filesystem"
+	"myitools/internal/testutil"
+)
+
+func TestScanGroupsOriginalAdjustments(t *testing.T) {
+	// 参数为 -u udid ls -l PATH
+	testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$5" in
+/DCIM) echo "drwxr-xr-x    5        160 Mar  9 08:15 100APPLE" ;;
+/DCIM/100APPLE)
+	echo "-rw-r--r--    1    2000000 Mar  9 08:15 IMG_0001.HEIC"
+	echo "-rw-r--r--    1    2100000 Mar  9 08:20 IMG_E0001.HEIC"
+	echo "-rw-r--r--    1       1200 Mar  9 08:20 IMG_0001.AAE"
+	echo "-rw-r--r--    1       1100 Mar  9 08:16 IMG_O0001.AAE"
+	;;
+*) echo "ERROR: No such file or directory" >&2; exit 1 ;;
+esac
+`})
+
+	assets, err := Scan(context.Background(), filesystem.NewClient("udid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 {
+		t.Fatalf("资源数 = %d, want 1: %+v", len(assets), assets)
+	}
+	asset := assets[0]
+	if asset.ID != "100APPLE/IMG_0001" || asset.Primary.Name != "IMG_0001.HEIC" {
+		t.Errorf("资源 = %s, 主文件 %s", asset.ID, asset.Primary.Name)
+	}
+
+	want := map[string]string{
+		"IMG_E0001.HEIC": SidecarEdited,
+		"IMG_0001.AAE":   SidecarAdjustments,
+		"IMG_O0001.AAE":  SidecarOriginalAdjustments,
+	}
+	if len(asset.Sidecars) != len(want) {
+		t.Fatalf("附属文件 = %+v", asset.Sidecars)
+	}
+	names := map[string]bool{}
+	for _, sidecar := range asset.Sidecars {
+		if want[sidecar.File.Name] != sidecar.Role {
+			t.Errorf("%s 的类型 = %q, want %q", sidecar.File.Name, sidecar.Role, want[sidecar.File.Name])
+		}
+		name := sidecarName("IMG_0001", sidecar)
+		if names[name] {
+			t.Errorf("附属文件导出后重名: %s", name)
+		}
+		names[name] = true
+	}
+}
@@ -0,0 +1,230 @@
+package photos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxExifScan 在 HEIC 等文件中查找 EXIF 时读取的最大字节数
+const maxExifScan = 8 << 20
+
+// EXIF 标签
+const (
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	// quickTimeEpoch QuickTime 时间戳的起点
+	quickTimeEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// CaptureTime 读取本地照片或视频的拍摄时间
+// 照片读取 EXIF 的 DateTimeOriginal，视频读取 mvhd 中的创建时间。
+func CaptureTime(localPath string) (time.Time, bool) {
+	_, ext := splitName(filepath.Base(localPath))
+	if videoExts[ext] {
+		return quickTimeCreation(localPath)
+	}
+	if !imageExts[ext] {
+		return time.Time{}, false
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxExifScan))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// DNG 等格式本身就是 TIFF
+	if isTIFFHeader(data) {
+		return tiffCaptureTime(data)
+	}
+	// JPEG 的 APP1 段和 HEIC 的 Exif 项都以 Exif\0\0 开头
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], exifHeader)
+		if i < 0 {
+			return time.Time{}, false
+		}
+		start := offset + i + len(exifHeader)
+		if isTIFFHeader(data[start:]) {
+			if t, ok := tiffCaptureTime(data[start:]); ok {
+				return t, true
+			}
+		}
+		offset = start
+	}
+}
+
+// isTIFFHeader 是否以 TIFF 文件头开始
+func isTIFFHeader(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+}
+
+// tiffCaptureTime 从 TIFF 结构中读取拍摄时间，优先使用 DateTimeOriginal
+func tiffCaptureTime(data []byte) (time.Time, bool) {
+	if len(data) < 8 {
+		return time.Time{}, false
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	ifd0 := readIFD(data, order, order.Uint32(data[4:]))
+	dateTime := ifd0.str(data, order, tagDateTime)
+	if offset, ok := ifd0[tagExifIFD]; ok {
+		exif := readIFD(data, order, offset.value)
+		if original := exif.str(data, order, tagDateTimeOriginal); original != "" {
+			return parseExifTime(original, exif.str(data, order, tagOffsetTimeOriginal))
+		}
+	}
+	if dateTime != "" {
+		return parseExifTime(dateTime, "")
+	}
+	return time.Time{}, false
+}
+
+// ifdEntry IFD 中的一项，value 为值或值所在的偏移
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value uint32
+}
+
+type ifd map[uint16]ifdEntry
+
+// readIFD 读取一个 IFD，数据不完整时返回已读取的部分
+func readIFD(data []byte, order binary.ByteOrder, offset uint32) ifd {
+	entries := ifd{}
+	if uint64(offset)+2 > uint64(len(data)) {
+		return entries
+	}
+	count := int(order.Uint16(data[offset:]))
+	for i := 0; i < count; i++ {
+		pos := int(offset) + 2 + i*12
+		if pos+12 > len(data) {
+			break
+		}
+		entries[order.Uint16(data[pos:])] = ifdEntry{
+			typ:   order.Uint16(data[pos+2:]),
+			count: order.Uint32(data[pos+4:]),
+			value: order.Uint32(data[pos+8:]),
+		}
+	}
+	return entries
+}
+
+// str 读取 ASCII 类型的标签值
+func (d ifd) str(data []byte, order binary.ByteOrder, tag uint16) string {
+	entry, ok := d[tag]
+	if !ok || entry.typ != 2 || entry.count == 0 {
+		return ""
+	}
+	var raw []byte
+	if entry.count <= 4 {
+		raw = make([]byte, 4)
+		order.PutUint32(raw, entry.value)
+		raw = raw[:entry.count]
+	} else {
+		end := uint64(entry.value) + uint64(entry.count)
+		if end > uint64(len(data)) {
+			return ""
+		}
+		raw = data[entry.value:end]
+	}
+	return strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+}
+
+// parseExifTime 解析 EXIF 时间，有时区偏移时使用偏移，否则视为本地时间
+func parseExifTime(value string, offset string) (time.Time, bool) {
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t, true
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", value, time.Local)
+	if err != nil || t.Year() < 1970 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// quickTimeCreation 读取 MOV/MP4 的 moov/mvhd 中的创建时间
+func quickTimeCreation(localPath string) (time.Time, bool) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	moov, moovSize, ok := findBox(f, 0, stat.Size(), "moov")
+	if !ok {
+		return time.Time{}, false
+	}
+	mvhd, _, ok := findBox(f, moov, moov+moovSize, "mvhd")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	header := make([]byte, 12)
+	if _, err := f.ReadAt(header, mvhd); err != nil {
+		return time.Time{}, false
+	}
+	var seconds uint64
+	if header[0] == 1 {
+		seconds = binary.BigEndian.Uint64(header[4:])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(header[4:]))
+	}
+	if seconds == 0 {
+		return time.Time{}, false
+	}
+	return quickTimeEpoch.Add(time.Duration(seconds) * time.Second), true
+}
+
+// findBox 在 [start, end) 范围内查找指定类型的 box，返回内容的起始位置和长度
+func findBox(r io.ReaderAt, start int64, end int64, boxType string) (int64, int64, bool) {
+	header := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := r.ReadAt(header[:8], pos); err != nil {
+			return 0, 0, false
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - pos
+		case 1:
+			if _, err := r.ReadAt(header[8:16], pos+8); err != nil {
+				return 0, 0, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if size < headerSize || pos+size > end {
+			return 0, 0, false
+		}
+		if string(header[4:8]) == boxType {
+			return pos + headerSize, size - headerSize, true
+		}
+		pos += size
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,189 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"myitools/device"
+	"myitools/filesystem"
+
+	_ "golang.org/x/image/tiff"
+)
+
+// DefaultThumbnailSize 缩略图默认的最长边
+const DefaultThumbnailSize = 256
+
+// maxThumbnailSource 设备上没有缩略图时，允许下载原文件生成缩略图的最大文件大小
+// 视频只截取第一帧也要下载整个文件，较大的文件不在本地生成缩略图。
+const maxThumbnailSource = 50 << 20
+
+// Thumbnail 生成设备上照片或视频的 JPEG 缩略图
+// 优先使用设备在 PhotoData/Thumbnails 中生成的缩略图，没有时下载原文件在本地生成，
+// 原文件超过 maxThumbnailSource 或为 DNG 时不下载，直接返回错误。
+func Thumbnail(ctx context.Context, client *filesystem.Client, photoPath string, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultThumbnailSize
+	}
+	tmp, err := os.MkdirTemp("", "myitools-thumb-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// 设备缩略图位于 Thumbnails/V2/DCIM/100APPLE/IMG_0001.HEIC/ 目录中，取最大的一张
+	if thumbs, err := client.ReadDir(ctx, path.Join(thumbnailDir, photoPath)); err == nil {
+		var best *filesystem.FileInfo
+		for i := range thumbs {
+			if strings.EqualFold(path.Ext(thumbs[i].Name), ".jpg") && (best == nil || thumbs[i].Size > best.Size) {
+				best = &thumbs[i]
+			}
+		}
+		if best != nil {
+			if err := client.Download(ctx, []string{best.Path}, tmp, nil); err == nil {
+				if data, err := MakeThumbnail(filepath.Join(tmp, best.Name), maxSize); err == nil {
+					return data, nil
+				}
+			}
+		}
+	}
+
+	name := path.Base(photoPath)
+	if _, ext := splitName(name); ext == "DNG" {
+		return nil, fmt.Errorf("%s 没有可用的缩略图，不支持解码 DNG", name)
+	}
+	info, err := client.Stat(ctx, photoPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size > maxThumbnailSource {
+		return nil, fmt.Errorf("%s 没有可用的缩略图，文件过大 (%d MB)", name, info.Size>>20)
+	}
+	if err := client.Download(ctx, []string{photoPath}, tmp, nil); err != nil {
+		return nil, err
+	}
+	return MakeThumbnail(filepath.Join(tmp, name), maxSize)
+}
+
+// MakeThumbnail 为本地照片或视频生成 JPEG 缩略图
+// HEIC 需要系统中有转换工具，视频需要 ffmpeg，不支持 DNG。
+func MakeThumbnail(localPath string, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultThumbnailSize
+	}
+	_, ext := splitName(filepath.Base(localPath))
+	if ext == "DNG" {
+		return nil, fmt.Errorf("不支持为 DNG 生成缩略图")
+	}
+	source := localPath
+
+	if ext == "HEIC" || ext == "HEIF" || videoExts[ext] {
+		tmp, err := os.CreateTemp("", "myitools-thumb-*.jpg")
+		if err != nil {
+			return nil, fmt.Errorf("创建临时文件失败: %v", err)
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+
+		if videoExts[ext] {
+			err = extractVideoFrame(localPath, tmp.Name())
+		} else {
+			err = ConvertHEIC(localPath, tmp.Name())
+		}
+		if err != nil {
+			return nil, err
+		}
+		source = tmp.Name()
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("打开图片失败: %v", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleImage(img, maxSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("生成缩略图失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ConvertHEIC 将 HEIC 转换为 JPEG
+// 依次尝试 macOS 的 sips、libheif 的 heif-convert 和 ImageMagick。
+func ConvertHEIC(src string, dst string) error {
+	converters := [][]string{
+		{"sips", "-s", "format", "jpeg", src, "--out", dst},
+		{"heif-convert", "-q", "90", src, dst},
+		{"magick", src, dst},
+	}
+	for _, args := range converters {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			return device.CommandError(args[0], "转换 HEIC 失败", err, string(output))
+		}
+		return nil
+	}
+	return &device.ErrToolMissing{Tool: "heif-convert"}
+}
+
+// extractVideoFrame 使用 ffmpeg 截取视频的第一帧
+func extractVideoFrame(src string, dst string) error {
+	output, err := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-i", src, "-frames:v", "1", dst).CombinedOutput()
+	if err != nil {
+		return device.CommandError("ffmpeg", "截取视频画面失败", err, string(output))
+	}
+	return nil
+}
+
+// scaleImage 按比例缩小图片，使最长边不超过 maxSize，每个像素取源区域的平均值
+func scaleImage(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSize && h <= maxSize {
+		return src
+	}
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
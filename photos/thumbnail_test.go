@@ -0,0 +1,93 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"myitools/filesystem"
+	"myitools/internal/testutil"
+
+	"golang.org/x/image/tiff"
+)
+
+func TestMakeThumbnailDecodesTIFF(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.Black)
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(t.TempDir(), "IMG_0001.TIF")
+	if err := os.WriteFile(local, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MakeThumbnail(local, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := thumb.Bounds().Size(); size.X != 100 || size.Y != 50 {
+		t.Errorf("缩略图大小 = %v", size)
+	}
+}
+
+// fakeOriginal 假的 afcclient，设备上没有缩略图，原文件大小为 size
+func fakeOriginal(t *testing.T, size int64) *testutil.FakeTools {
+	t.Helper()
+	return testutil.NewFakeTools(t, map[string]string{"afcclient": `case "$3" in
+ls) echo "ERROR: No such file or directory" >&2; exit 1 ;;
+info) printf 'st_size: ` + strconv.FormatInt(size, 10) + `\nst_ifmt: S_IFREG\n' ;;
+get) printf 'not an image' > "$6" ;;
+esac
+`})
+}
+
+func TestThumbnailSkipsFullDownload(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		size int64
+	}{
+		{"大视频", "/DCIM/100APPLE/IMG_0001.MOV", maxThumbnailSource + 1},
+		{"DNG", "/DCIM/100APPLE/IMG_0002.DNG", 1 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := fakeOriginal(t, tt.size)
+			if _, err := Thumbnail(context.Background(), filesystem.NewClient("udid"), tt.path, 0); err == nil {
+				t.Error("应返回错误")
+			}
+			for _, call := range tools.Calls(t, "afcclient") {
+				if strings.Contains(call, " get ") {
+					t.Errorf("不应下载原文件: %q", call)
+				}
+			}
+		})
+	}
+
+	// 较小的文件仍下载原文件生成
+	tools := fakeOriginal(t, 1<<20)
+	Thumbnail(context.Background(), filesystem.NewClient("udid"), "/DCIM/100APPLE/IMG_0003.JPG", 0)
+	downloaded := false
+	for _, call := range tools.Calls(t, "afcclient") {
+		downloaded = downloaded || strings.Contains(call, " get -f /DCIM/100APPLE/IMG_0003.JPG ")
+	}
+	if !downloaded {
+		t.Errorf("没有下载原文件: %q", tools.Calls(t, "afcclient"))
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package secret
+
+// newCredentialStore 只有 Windows 支持凭据管理器
+func newCredentialStore() (Store, error) {
+	return nil, ErrUnsupported
+}
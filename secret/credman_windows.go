@@ -0,0 +1,130 @@
+//go:build windows
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// 凭据管理器常量
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential 对应 Windows 的 CREDENTIALW 结构
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialStore 基于 Windows 凭据管理器的密码存储
+// 每个账户保存为一个普通凭据，目标名为 myitools:<账户>，密码以 UTF-8 保存。
+type credentialStore struct{}
+
+// newCredentialStore 创建凭据管理器存储
+func newCredentialStore() (Store, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return &credentialStore{}, nil
+}
+
+// Kind 存储类型
+func (s *credentialStore) Kind() string {
+	return "wincred"
+}
+
+// Get 读取密码
+func (s *credentialStore) Get(account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("读取凭据管理器失败: %v", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", ErrNotFound
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// Set 保存密码
+func (s *credentialStore) Set(account string, password string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(password)
+	defer wipeBytes(blob)
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("写入凭据管理器失败: %v", err)
+	}
+	return nil
+}
+
+// Delete 删除密码
+func (s *credentialStore) Delete(account string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		if errors.Is(err, errorNotFound) {
+			return nil
+		}
+		return fmt.Errorf("删除凭据管理器条目失败: %v", err)
+	}
+	return nil
+}
+
+// credentialTarget 返回凭据的目标名
+func credentialTarget(account string) string {
+	return serviceName + ":" + account
+}
@@ -0,0 +1,269 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// pbkdf2Iterations 由主密码派生密钥时的迭代次数，也是读取文件时允许的最小值
+const pbkdf2Iterations = 210000
+
+// fileVersion 当前的密码文件格式版本
+// 版本 2 起文件头作为 GCM 的附加数据参与认证，版本 1 的文件在下次保存时升级。
+const fileVersion = 2
+
+// fileFormat 加密密码文件的格式
+type fileFormat struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// additionalData 返回参与认证的文件头，修改版本、迭代次数或盐都会导致解密失败
+func (f *fileFormat) additionalData() []byte {
+	if f.Version < 2 {
+		return nil
+	}
+	header, _ := json.Marshal(struct {
+		Version    int    `json:"version"`
+		Iterations int    `json:"iterations"`
+		Salt       []byte `json:"salt"`
+	}{f.Version, f.Iterations, f.Salt})
+	return header
+}
+
+// validate 检查文件头，拒绝过低的迭代次数和空的盐，防止文件被改为弱参数
+func (f *fileFormat) validate() error {
+	switch {
+	case f.Version < 1 || f.Version > fileVersion:
+		return fmt.Errorf("密码文件格式错误: 不支持的版本 %d", f.Version)
+	case f.Iterations < pbkdf2Iterations:
+		return fmt.Errorf("密码文件格式错误: 迭代次数 %d 过低", f.Iterations)
+	case len(f.Salt) == 0:
+		return fmt.Errorf("密码文件格式错误: 缺少盐")
+	}
+	return nil
+}
+
+// FileStore 使用主密码加密的文件密码存储
+// 文件内容使用 AES-256-GCM 加密，密钥由主密码经 PBKDF2-SHA256 派生。
+type FileStore struct {
+	mu         sync.Mutex
+	path       string
+	key        []byte
+	salt       []byte
+	iterations int
+}
+
+// NewFileStore 打开或创建加密密码文件
+// 文件已存在时会用主密码解密一次以校验主密码是否正确。
+func NewFileStore(path string, masterPassword string) (*FileStore, error) {
+	store := &FileStore{path: path, iterations: pbkdf2Iterations}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		store.salt = make([]byte, 16)
+		if _, err := rand.Read(store.salt); err != nil {
+			return nil, fmt.Errorf("生成随机盐失败: %v", err)
+		}
+		store.key = deriveKey([]byte(masterPassword), store.salt, store.iterations)
+		if err := store.save(map[string]string{}); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密码文件失败: %v", err)
+	}
+
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("密码文件格式错误: %v", err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	store.salt = f.Salt
+	store.iterations = f.Iterations
+	store.key = deriveKey([]byte(masterPassword), f.Salt, f.Iterations)
+	if _, err := store.load(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Kind 存储类型
+func (s *FileStore) Kind() string {
+	return "file"
+}
+
+// Get 读取密码
+func (s *FileStore) Get(account string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	password, ok := entries[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return password, nil
+}
+
+// Set 保存密码
+func (s *FileStore) Set(account string, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries[account] = password
+	return s.save(entries)
+}
+
+// Delete 删除密码
+func (s *FileStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[account]; !ok {
+		return nil
+	}
+	delete(entries, account)
+	return s.save(entries)
+}
+
+// Close 清除内存中的密钥
+func (s *FileStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wipeBytes(s.key)
+	s.key = nil
+}
+
+// load 读取并解密密码文件
+func (s *FileStore) load() (map[string]string, error) {
+	if s.key == nil {
+		return nil, fmt.Errorf("密码存储已关闭")
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密码文件失败: %v", err)
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("密码文件格式错误: %v", err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("密码文件格式错误: 随机数长度无效")
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, f.additionalData())
+	if err != nil {
+		return nil, ErrWrongMasterPassword
+	}
+	defer wipeBytes(plain)
+
+	entries := map[string]string{}
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return nil, fmt.Errorf("密码文件内容错误: %v", err)
+	}
+	return entries, nil
+}
+
+// save 加密并写入密码文件
+func (s *FileStore) save(entries map[string]string) error {
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("序列化密码失败: %v", err)
+	}
+	defer wipeBytes(plain)
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %v", err)
+	}
+
+	f := fileFormat{
+		Version:    fileVersion,
+		Iterations: s.iterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+	}
+	f.Data = gcm.Seal(nil, nonce, plain, f.additionalData())
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化密码文件失败: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("创建密码文件目录失败: %v", err)
+	}
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("写入密码文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("写入密码文件失败: %v", err)
+	}
+	return nil
+}
+
+// newGCM 创建 AES-GCM 加密器
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %v", err)
+	}
+	return gcm, nil
+}
+
+// deriveKey 使用 PBKDF2-HMAC-SHA256 从主密码派生32字节密钥
+func deriveKey(password []byte, salt []byte, iterations int) []byte {
+	return pbkdf2.Key(password, salt, iterations, 32, sha256.New)
+}
+
+// wipeBytes 将字节切片清零
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
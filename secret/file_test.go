@@ -0,0 +1,174 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFileFormat(t *testing.T, path string) fileFormat {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func writeFileFormat(t *testing.T, path string, f fileFormat) {
+	t.Helper()
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "myitools", "secrets.json")
+	store, err := NewFileStore(path, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(BackupAccount("udid-1"), "p@ss 密码"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(BackupAccount("udid-2"), "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(BackupAccount("udid-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("删除不存在的条目: %v", err)
+	}
+	store.Close()
+	if _, err := store.Get(BackupAccount("udid-1")); err == nil {
+		t.Error("关闭后读取应返回错误")
+	}
+
+	if stat, err := os.Stat(path); err != nil || stat.Mode().Perm() != 0600 {
+		t.Errorf("密码文件权限 = %v, %v", stat.Mode().Perm(), err)
+	}
+	if f := readFileFormat(t, path); f.Version != fileVersion || f.Iterations != pbkdf2Iterations || len(f.Salt) != 16 {
+		t.Errorf("文件头 = version %d, iterations %d, salt %d 字节", f.Version, f.Iterations, len(f.Salt))
+	}
+
+	reopened, err := NewFileStore(path, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got, err := reopened.Get(BackupAccount("udid-1")); err != nil || got != "p@ss 密码" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if _, err := reopened.Get(BackupAccount("udid-2")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("已删除的条目: err = %v", err)
+	}
+}
+
+func TestFileStoreWrongMasterPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	store, err := NewFileStore(path, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if _, err := NewFileStore(path, "Master"); !errors.Is(err, ErrWrongMasterPassword) {
+		t.Errorf("主密码错误: err = %v", err)
+	}
+}
+
+func TestFileStoreRejectsTamperedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	store, err := NewFileStore(path, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("account", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	original := readFileFormat(t, path)
+
+	tests := []struct {
+		name   string
+		tamper func(f *fileFormat)
+	}{
+		{"修改密文", func(f *fileFormat) { f.Data[0] ^= 1 }},
+		{"修改随机数", func(f *fileFormat) { f.Nonce[0] ^= 1 }},
+		{"随机数长度无效", func(f *fileFormat) { f.Nonce = f.Nonce[:4] }},
+		{"提高迭代次数", func(f *fileFormat) { f.Iterations++ }},
+		{"降低迭代次数", func(f *fileFormat) { f.Iterations = 1 }},
+		{"空的盐", func(f *fileFormat) { f.Salt = nil }},
+		{"未知版本", func(f *fileFormat) { f.Version = fileVersion + 1 }},
+		// 派生密钥不变，只有文件头参与认证才能发现
+		{"降级为版本 1", func(f *fileFormat) { f.Version = 1 }},
+	}
+	for _, tt := range tests {
+		f := original
+		f.Data = append([]byte(nil), original.Data...)
+		f.Nonce = append([]byte(nil), original.Nonce...)
+		tt.tamper(&f)
+		writeFileFormat(t, path, f)
+		if store, err := NewFileStore(path, "master"); err == nil {
+			store.Close()
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(path, "master"); err == nil {
+		t.Error("损坏的文件应返回错误")
+	}
+}
+
+func TestFileStoreUpgradesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key := deriveKey([]byte("master"), salt, pbkdf2Iterations)
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	writeFileFormat(t, path, fileFormat{
+		Version:    1,
+		Iterations: pbkdf2Iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, []byte(`{"account":"old"}`), nil),
+	})
+
+	store, err := NewFileStore(path, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if got, err := store.Get("account"); err != nil || got != "old" {
+		t.Errorf("版本 1 的文件: Get = %q, %v", got, err)
+	}
+	if err := store.Set("account", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if f := readFileFormat(t, path); f.Version != fileVersion {
+		t.Errorf("保存后版本 = %d", f.Version)
+	}
+	if got, err := store.Get("account"); err != nil || got != "new" {
+		t.Errorf("升级后 Get = %q, %v", got, err)
+	}
+}
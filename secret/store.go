@@ -0,0 +1,62 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// serviceName 在系统密钥库中保存密码时使用的服务名
+const serviceName = "myitools"
+
+// ErrNotFound 没有找到保存的密码
+var ErrNotFound = errors.New("未找到保存的密码")
+
+// ErrUnsupported 当前系统不支持系统密钥库
+var ErrUnsupported = errors.New("当前系统不支持系统密钥库")
+
+// ErrWrongMasterPassword 主密码错误
+var ErrWrongMasterPassword = errors.New("主密码错误")
+
+// Store 密码存储接口
+type Store interface {
+	// Get 读取密码
+	Get(account string) (string, error)
+	// Set 保存密码，已存在时覆盖
+	Set(account string, password string) error
+	// Delete 删除密码，不存在时不返回错误
+	Delete(account string) error
+	// Kind 存储类型，例如 keychain、secret-service、wincred、file
+	Kind() string
+}
+
+// BackupAccount 返回保存设备备份密码时使用的账户名
+func BackupAccount(udid string) string {
+	return "backup:" + udid
+}
+
+// DefaultFilePath 返回加密密码文件的默认路径
+func DefaultFilePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		homeDir, _ := os.UserHomeDir()
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "myitools", "secrets.json")
+}
+
+// Open 打开密码存储
+// 优先使用系统密钥库；不可用时使用主密码加密的文件存储，此时 masterPassword 不能为空。
+func Open(masterPassword string) (Store, error) {
+	store, err := NewSystemStore()
+	if err == nil {
+		return store, nil
+	}
+	fmt.Printf("系统密钥库不可用，使用加密文件存储: %v\n", err)
+
+	if masterPassword == "" {
+		return nil, fmt.Errorf("系统密钥库不可用，需要设置主密码: %w", err)
+	}
+	return NewFileStore(DefaultFilePath(), masterPassword)
+}
@@ -0,0 +1,152 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// probeAccount 检查 Secret Service 是否可用时查询的账户名
+const probeAccount = "probe"
+
+// probeTimeout 检查 Secret Service 是否可用的超时时间，守护进程无响应时 secret-tool 可能一直等待
+const probeTimeout = 5 * time.Second
+
+// systemStore 基于系统密钥库的密码存储
+// macOS 使用 security 命令访问钥匙串，Linux 使用 secret-tool 访问 Secret Service。
+// 密码始终通过标准输入传递，不出现在命令行参数中。
+// Windows 使用凭据管理器，见 credman_windows.go。
+type systemStore struct {
+	tool string
+}
+
+// NewSystemStore 创建系统密钥库存储，当前系统不支持时返回 ErrUnsupported
+func NewSystemStore() (Store, error) {
+	var tool string
+	switch runtime.GOOS {
+	case "darwin":
+		tool = "security"
+	case "linux":
+		tool = "secret-tool"
+	case "windows":
+		return newCredentialStore()
+	default:
+		return nil, ErrUnsupported
+	}
+
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 找不到 %s", ErrUnsupported, tool)
+	}
+	store := &systemStore{tool: path}
+	// 安装了 secret-tool 不代表有 Secret Service 守护进程在运行，查询一次不存在的条目确认可用
+	if tool == "secret-tool" {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		if _, err := store.get(ctx, probeAccount); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+		}
+	}
+	return store, nil
+}
+
+// Kind 存储类型
+func (s *systemStore) Kind() string {
+	if runtime.GOOS == "darwin" {
+		return "keychain"
+	}
+	return "secret-service"
+}
+
+// Get 读取密码
+func (s *systemStore) Get(account string) (string, error) {
+	return s.get(context.Background(), account)
+}
+
+// get 读取密码，ctx 取消时结束查询命令
+func (s *systemStore) get(ctx context.Context, account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.CommandContext(ctx, s.tool, "find-generic-password", "-s", serviceName, "-a", account, "-w")
+	} else {
+		cmd = exec.CommandContext(ctx, s.tool, "lookup", "service", serviceName, "account", account)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("读取系统密钥库超时")
+		}
+		if runtime.GOOS == "darwin" && strings.Contains(stderr.String(), "could not be found") {
+			return "", ErrNotFound
+		}
+		// secret-tool 找不到条目时以非零状态退出且没有输出
+		if runtime.GOOS != "darwin" && stdout.Len() == 0 && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("读取系统密钥库失败: %v - %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	password := strings.TrimRight(stdout.String(), "\r\n")
+	if password == "" {
+		return "", ErrNotFound
+	}
+	return password, nil
+}
+
+// Set 保存密码
+func (s *systemStore) Set(account string, password string) error {
+	var cmd *exec.Cmd
+	var input []byte
+	if runtime.GOOS == "darwin" {
+		// security -i 从标准输入读取命令，密码不会出现在进程参数中
+		cmd = exec.Command(s.tool, "-i")
+		input = []byte(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			quoteSecurityArg(serviceName), quoteSecurityArg(account), quoteSecurityArg(password)))
+	} else {
+		cmd = exec.Command(s.tool, "store", "--label=MyiTools "+account, "service", serviceName, "account", account)
+		input = []byte(password)
+	}
+	defer wipeBytes(input)
+
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("写入系统密钥库失败: %v - %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Delete 删除密码
+func (s *systemStore) Delete(account string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command(s.tool, "delete-generic-password", "-s", serviceName, "-a", account)
+	} else {
+		cmd = exec.Command(s.tool, "clear", "service", serviceName, "account", account)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "could not be found") {
+			return nil
+		}
+		return fmt.Errorf("删除系统密钥库条目失败: %v - %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// quoteSecurityArg 为 security -i 的命令行参数加引号
+func quoteSecurityArg(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
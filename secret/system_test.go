@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"myitools/internal/testutil"
+)
+
+func TestNewSystemStoreProbesSecretService(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("只有 Linux 使用 secret-tool")
+	}
+
+	// 没有运行 Secret Service 时 secret-tool 报错退出
+	testutil.NewFakeTools(t, map[string]string{
+		"secret-tool": "echo 'secret-tool: Cannot autolaunch D-Bus without X11 $DISPLAY' >&2\nexit 1\n",
+	})
+	if _, err := NewSystemStore(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("守护进程不可用: err = %v", err)
+	}
+
+	// 条目不存在时 secret-tool 以非零状态退出且没有输出
+	tools := testutil.NewFakeTools(t, map[string]string{"secret-tool": "exit 1\n"})
+	store, err := NewSystemStore()
+	if err != nil {
+		t.Fatalf("守护进程可用: err = %v", err)
+	}
+	if store.Kind() != "secret-service" {
+		t.Errorf("Kind = %q", store.Kind())
+	}
+	if calls := tools.Calls(t, "secret-tool"); len(calls) != 1 || calls[0] != "lookup service myitools account probe" {
+		t.Errorf("探测调用 = %q", calls)
+	}
+}